@@ -20,16 +20,22 @@ import (
 	"context"
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
 	"github.com/95ulisse/dns-operator/pkg/controllers"
+	"github.com/95ulisse/dns-operator/pkg/metrics"
 	"github.com/95ulisse/dns-operator/pkg/types"
+	"github.com/95ulisse/dns-operator/pkg/webhooks"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -48,10 +54,36 @@ func init() {
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var driftCheckInterval time.Duration
+	var clusterID string
+	var enableWebhooks bool
+	var ingressClasses string
+	var ingressProvider string
+	var operatorNamespace string
+	var fieldRefKinds string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&driftCheckInterval, "drift-check-interval", 10*time.Minute,
+		"Interval between two checks of the published DNS records against the desired ones. "+
+			"Set to 0 to disable drift detection.")
+	flag.StringVar(&clusterID, "cluster-id", "default",
+		"Identifier of this cluster, recorded in the ownership records published alongside the DNS records. "+
+			"Must be unique among the clusters sharing the same DNS zones.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Enable the admission webhooks. "+
+			"The webhook server requires a TLS certificate in /tmp/k8s-webhook-server/serving-certs, "+
+			"and the webhook configurations in config/release to be installed.")
+	flag.StringVar(&ingressClasses, "ingress-classes", "",
+		"Comma separated list of ingress classes whose Ingresses get DNS records even without annotations.")
+	flag.StringVar(&ingressProvider, "ingress-provider", "",
+		"DNSProvider (namespace/name, or ClusterDNSProvider/name) used for the Ingresses which do not reference one with an annotation.")
+	flag.StringVar(&operatorNamespace, "operator-namespace", "dns-operator",
+		"Namespace from which ClusterDNSProviders read the secrets referenced without a namespace.")
+	flag.StringVar(&fieldRefKinds, "field-ref-kinds", "Service,ConfigMap",
+		"Comma separated list of kinds (Kind or Kind.group) which DNSRecords can reference with valueFrom.fieldRef. "+
+			"Secrets and cluster scoped kinds are not allowed. The operator needs the get, list and watch permissions on them.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
@@ -68,20 +100,37 @@ func main() {
 		os.Exit(1)
 	}
 
+	// The root context is cancelled when the manager is asked to stop,
+	// so that any pending operation against the DNS providers is aborted
+	stopCh := ctrl.SetupSignalHandler()
+	rootCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+
 	// Prepare a shared context for the controllers
 	ctx := &types.ControllerContext{
-		RootContext:   context.Background(),
+		RootContext:   rootCtx,
 		Client:        mgr.GetClient(),
 		Log:           ctrl.Log,
 		EventRecorder: mgr.GetEventRecorderFor("dns.k8s.marcocameriero.net"),
+		ClusterID:     clusterID,
 	}
 
+	// Expose the number of records by status alongside the metrics of the providers
+	ctrlmetrics.Registry.MustRegister(metrics.NewRecordsCollector(mgr.GetClient()))
+
 	// Register the controllers with the manager
 	if err = (&controllers.DNSRecordReconciler{
 		Client:  mgr.GetClient(),
 		Log:     ctrl.Log.WithName("controllers").WithName("DNSRecord"),
 		Scheme:  mgr.GetScheme(),
 		Context: ctx,
+
+		DriftCheckInterval: driftCheckInterval,
+		FieldRefKinds:      splitGroupKinds(fieldRefKinds),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "DNSRecord")
 		os.Exit(1)
@@ -95,11 +144,128 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "DNSProvider")
 		os.Exit(1)
 	}
+	if err = (&controllers.ClusterDNSProviderReconciler{
+		Client:  mgr.GetClient(),
+		Log:     ctrl.Log.WithName("controllers").WithName("ClusterDNSProvider"),
+		Scheme:  mgr.GetScheme(),
+		Context: ctx,
+
+		CredentialsNamespace: operatorNamespace,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterDNSProvider")
+		os.Exit(1)
+	}
+	if err = (&controllers.ServiceReconciler{
+		Client:  mgr.GetClient(),
+		Log:     ctrl.Log.WithName("controllers").WithName("Service"),
+		Scheme:  mgr.GetScheme(),
+		Context: ctx,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Service")
+		os.Exit(1)
+	}
+	if err = (&controllers.IngressReconciler{
+		Client:          mgr.GetClient(),
+		Log:             ctrl.Log.WithName("controllers").WithName("Ingress"),
+		Scheme:          mgr.GetScheme(),
+		Context:         ctx,
+		IngressClasses:  splitList(ingressClasses),
+		DefaultProvider: ingressProvider,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Ingress")
+		os.Exit(1)
+	}
+	if err = setupGatewayAPI(mgr, ctx); err != nil {
+		setupLog.Error(err, "unable to create Gateway API controllers")
+		os.Exit(1)
+	}
+	if enableWebhooks {
+		if err = (&webhooks.DNSRecordValidator{
+			Client:        mgr.GetClient(),
+			Context:       ctx,
+			FieldRefKinds: splitGroupKinds(fieldRefKinds),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "DNSRecord")
+			os.Exit(1)
+		}
+		if err = (&webhooks.DNSRecordDefaulter{}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "DNSRecord")
+			os.Exit(1)
+		}
+		if err = (&webhooks.DNSProviderValidator{}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "DNSProvider")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(stopCh); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// setupGatewayAPI registers the controllers for the Gateway API resources installed in the cluster.
+// The CRDs are looked up only once, so the kinds installed after startup are ignored until the operator is restarted.
+func setupGatewayAPI(mgr ctrl.Manager, ctx *types.ControllerContext) error {
+	installed, err := controllers.IsKindInstalled(mgr, controllers.GatewayKind)
+	if err != nil {
+		return err
+	}
+	if !installed {
+		setupLog.Info("Gateway API not installed, skipping Gateway and route controllers (restart after installing it)")
+		return nil
+	}
+
+	if err := (&controllers.GatewayReconciler{
+		Client:  mgr.GetClient(),
+		Log:     ctrl.Log.WithName("controllers").WithName("Gateway"),
+		Scheme:  mgr.GetScheme(),
+		Context: ctx,
+	}).SetupWithManager(mgr); err != nil {
+		return err
+	}
+
+	for _, kind := range controllers.RouteKinds {
+		installed, err := controllers.IsKindInstalled(mgr, kind)
+		if err != nil {
+			return err
+		}
+		if !installed {
+			setupLog.Info("Gateway API route kind not installed, skipping (restart after installing it)", "kind", kind.String())
+			continue
+		}
+		if err := (&controllers.RouteReconciler{
+			Client:  mgr.GetClient(),
+			Log:     ctrl.Log.WithName("controllers").WithName(kind.Kind),
+			Scheme:  mgr.GetScheme(),
+			Context: ctx,
+			Kind:    kind,
+		}).SetupWithManager(mgr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitList splits a comma separated list, ignoring empty items.
+func splitList(s string) []string {
+	var res []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+// splitGroupKinds parses a comma separated list of kinds in the form `Kind` or `Kind.group`.
+func splitGroupKinds(s string) []schema.GroupKind {
+	res := []schema.GroupKind{}
+	for _, item := range splitList(s) {
+		res = append(res, schema.ParseGroupKind(item))
+	}
+	return res
+}
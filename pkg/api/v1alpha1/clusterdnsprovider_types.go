@@ -0,0 +1,60 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+
+// ClusterDNSProvider is a DNSProvider which can be referenced by DNSRecords in any namespace.
+// Secrets referenced without a namespace are read from the namespace of the operator.
+type ClusterDNSProvider struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DNSProviderSpec   `json:"spec,omitempty"`
+	Status DNSProviderStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterDNSProviderList contains a list of ClusterDNSProvider
+type ClusterDNSProviderList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterDNSProvider `json:"items"`
+}
+
+// AsDNSProvider returns a DNSProvider with the same name and spec of this resource, living in the given namespace,
+// so that it can be built by the same constructors of the namespaced providers.
+func (resource *ClusterDNSProvider) AsDNSProvider(namespace string) *DNSProvider {
+	res := &DNSProvider{
+		ObjectMeta: *resource.ObjectMeta.DeepCopy(),
+		Spec:       *resource.Spec.DeepCopy(),
+		Status:     *resource.Status.DeepCopy(),
+	}
+	res.Namespace = namespace
+	return res
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterDNSProvider{}, &ClusterDNSProviderList{})
+}
@@ -18,9 +18,12 @@ package v1alpha1
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/95ulisse/dns-operator/pkg/dnsname"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // DNSProviderSpec defines the desired state of DNSProvider.
@@ -32,6 +35,16 @@ type DNSProviderSpec struct {
 	// +kubebuilder:validation:MinItems=1
 	Zones []dnsname.Name `json:"zones"`
 
+	// Maximum duration of each operation performed against the backend of the provider (e.g., `30s`, `2m`).
+	// Defaults to 30s.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// Restricts the DNSRecords which can use this provider.
+	// When not set, DNSRecords in any namespace can publish any record.
+	// +optional
+	AccessPolicy *DNSProviderAccessPolicy `json:"accessPolicy,omitempty"`
+
 	// Dummy provider used for debugging.
 	// +optional
 	Dummy *bool `json:"dummy,omitempty"`
@@ -43,6 +56,135 @@ type DNSProviderSpec struct {
 	// Use Cloudflare to manage records.
 	// +optional
 	Cloudflare *DNSProviderCloudflare `json:"cloudflare,omitempty"`
+
+	// Use Amazon Route53, or any service compatible with its API, to manage records.
+	// +optional
+	Route53 *DNSProviderRoute53 `json:"route53,omitempty"`
+
+	// Use Google Cloud DNS to manage records.
+	// +optional
+	GoogleCloudDNS *DNSProviderGoogleCloudDNS `json:"googleCloudDNS,omitempty"`
+
+	// Use Azure DNS to manage records.
+	// +optional
+	AzureDNS *DNSProviderAzureDNS `json:"azureDNS,omitempty"`
+
+	// Use the HTTP API of a PowerDNS Authoritative Server to manage records.
+	// +optional
+	PowerDNS *DNSProviderPowerDNS `json:"powerdns,omitempty"`
+}
+
+// DNSProviderAccessPolicy restricts the DNSRecords which can use a provider.
+type DNSProviderAccessPolicy struct {
+	// Selects the namespaces whose DNSRecords can use this provider.
+	// DNSRecords in the same namespace of a DNSProvider are always allowed.
+	// When not set, DNSRecords in any namespace are allowed.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Restricts the names and the types of the records which can be published.
+	// A record is allowed if at least one of the rules matching its namespace allows it.
+	// When empty, any record is allowed.
+	// +optional
+	Rules []DNSProviderAccessRule `json:"rules,omitempty"`
+}
+
+// DNSProviderAccessRule allows the DNSRecords of a set of namespaces to publish records with specific names and types.
+type DNSProviderAccessRule struct {
+	// Selects the namespaces this rule applies to.
+	// When not set, the rule applies to all the namespaces.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Fully qualified names which can be published (e.g., `api.example.com`).
+	// A leading `*.` matches any subdomain of the rest of the name (e.g., `*.team.example.com`).
+	// When empty, any name is allowed.
+	// +optional
+	Names []string `json:"names,omitempty"`
+
+	// Types of the records which can be published (e.g., `A`, `CNAME`).
+	// When empty, any type is allowed.
+	// +optional
+	Types []string `json:"types,omitempty"`
+}
+
+// Allows checks whether a record with the given name and type, in a namespace with the given labels,
+// can be published by a provider living in `providerNamespace` (empty for ClusterDNSProviders).
+// Returns an error describing the reason if the record is not allowed.
+func (policy *DNSProviderAccessPolicy) Allows(providerNamespace, namespace string, namespaceLabels labels.Set, name *dnsname.Name, rtype string) error {
+	if policy == nil {
+		return nil
+	}
+
+	if policy.NamespaceSelector != nil && namespace != providerNamespace {
+		ok, err := matchesSelector(policy.NamespaceSelector, namespaceLabels)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("Namespace %s is not allowed to use the provider", namespace)
+		}
+	}
+
+	if len(policy.Rules) == 0 {
+		return nil
+	}
+	for _, rule := range policy.Rules {
+		if rule.NamespaceSelector != nil {
+			ok, err := matchesSelector(rule.NamespaceSelector, namespaceLabels)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+		}
+		if rule.allowsName(name) && rule.allowsType(rtype) {
+			return nil
+		}
+	}
+	return fmt.Errorf("Record %s %s is not allowed by the access policy of the provider", rtype, name.ToFQDN().String())
+}
+
+func (rule *DNSProviderAccessRule) allowsName(name *dnsname.Name) bool {
+	if len(rule.Names) == 0 {
+		return true
+	}
+	for _, pattern := range rule.Names {
+		subdomains := strings.HasPrefix(pattern, "*.")
+		parent, err := dnsname.NewName(strings.TrimPrefix(pattern, "*."))
+		if err != nil {
+			continue
+		}
+		if subdomains {
+			if name.IsSubdomainOf(parent.ToFQDN()) && !name.Equal(parent.ToFQDN()) {
+				return true
+			}
+		} else if name.Equal(parent.ToFQDN()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule *DNSProviderAccessRule) allowsType(rtype string) bool {
+	if len(rule.Types) == 0 {
+		return true
+	}
+	for _, t := range rule.Types {
+		if strings.EqualFold(t, rtype) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesSelector(selector *metav1.LabelSelector, set labels.Set) (bool, error) {
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, fmt.Errorf("Invalid namespace selector: %s", err)
+	}
+	return s.Matches(set), nil
 }
 
 // DNSProviderRFC2136 is a structure containing the configuration for RFC2136 DNS provider.
@@ -96,6 +238,115 @@ type DNSProviderCloudflare struct {
 	ProxiedByDefault *bool `json:"proxiedByDefault,omitempty"`
 }
 
+// DNSProviderRoute53 is a structure containing the configuration of the Route53 provider.
+// Zones are looked up by name among the hosted zones of the account.
+type DNSProviderRoute53 struct {
+	// Reference to a secret containing the ID of the access key to use for authentication.
+	AccessKeyIDSecretRef SecretReference `json:"accessKeyIDSecretRef"`
+
+	// Reference to a secret containing the secret access key to use for authentication.
+	SecretAccessKeySecretRef SecretReference `json:"secretAccessKeySecretRef"`
+
+	// AWS region used to sign the requests.
+	// Defaults to `us-east-1`, which is the region of the global Route53 endpoint.
+	// +optional
+	Region *string `json:"region,omitempty"`
+
+	// URL of the Route53 API (e.g., `http://localhost:5000` for a local stand-in like moto).
+	// Defaults to `https://route53.amazonaws.com`.
+	// +optional
+	Endpoint *string `json:"endpoint,omitempty"`
+
+	// Selects the private (`true`) or the public (`false`) hosted zones, when a public and a private hosted zone
+	// have the same name. When unset, each zone of the provider must match exactly one hosted zone.
+	// +optional
+	PrivateZone *bool `json:"privateZone,omitempty"`
+}
+
+// DNSProviderGoogleCloudDNS is a structure containing the configuration of the Google Cloud DNS provider.
+type DNSProviderGoogleCloudDNS struct {
+	// Reference to a secret containing the JSON key of the service account to use for authentication.
+	ServiceAccountSecretRef SecretReference `json:"serviceAccountSecretRef"`
+
+	// ID of the project owning the managed zones.
+	// Defaults to the project of the service account.
+	// +optional
+	Project *string `json:"project,omitempty"`
+
+	// Names of the managed zones, keyed by the DNS zones of the provider (e.g., `example.com: example-com`).
+	// Zones not listed here are looked up by name among the managed zones of the project.
+	// +optional
+	ManagedZones map[string]string `json:"managedZones,omitempty"`
+
+	// Base URL of the Cloud DNS API.
+	// Defaults to `https://dns.googleapis.com/dns/v1`.
+	// +optional
+	Endpoint *string `json:"endpoint,omitempty"`
+}
+
+// DNSProviderAzureDNS is a structure containing the configuration of the Azure DNS provider.
+// The provider authenticates as a service principal using a client secret.
+type DNSProviderAzureDNS struct {
+	// ID of the Azure AD tenant of the service principal.
+	TenantID string `json:"tenantID"`
+
+	// Application (client) ID of the service principal.
+	ClientID string `json:"clientID"`
+
+	// Reference to a secret containing the client secret of the service principal.
+	ClientSecretSecretRef SecretReference `json:"clientSecretSecretRef"`
+
+	// ID of the subscription containing the zones.
+	SubscriptionID string `json:"subscriptionID"`
+
+	// Resource group containing the zones not listed in `zoneResourceGroups`.
+	// +optional
+	ResourceGroup *string `json:"resourceGroup,omitempty"`
+
+	// Resource groups containing the zones, keyed by the DNS zones of the provider (e.g., `example.com: my-group`).
+	// Every zone must be listed here, unless `resourceGroup` is set.
+	// +optional
+	ZoneResourceGroups map[string]string `json:"zoneResourceGroups,omitempty"`
+
+	// If true, the zones are Azure Private DNS zones instead of public ones.
+	// +optional
+	PrivateZones *bool `json:"privateZones,omitempty"`
+
+	// URL of the Azure Resource Manager API.
+	// Defaults to `https://management.azure.com`.
+	// +optional
+	Endpoint *string `json:"endpoint,omitempty"`
+
+	// URL of the Azure AD authority issuing the tokens.
+	// Defaults to `https://login.microsoftonline.com`.
+	// +optional
+	AuthorityHost *string `json:"authorityHost,omitempty"`
+}
+
+// DNSProviderPowerDNS is a structure containing the configuration of the PowerDNS provider.
+type DNSProviderPowerDNS struct {
+	// URL of the HTTP API of the PowerDNS Authoritative Server (e.g., `http://powerdns:8081`).
+	// +kubebuilder:validation:MinLength=1
+	URL string `json:"url"`
+
+	// ID of the server in the HTTP API.
+	// Defaults to `localhost`.
+	// +optional
+	ServerID *string `json:"serverID,omitempty"`
+
+	// Reference to a secret containing the API key to use for authentication.
+	APIKeySecretRef SecretReference `json:"apiKeySecretRef"`
+
+	// If true, the zones of the provider which do not exist on the server are created on the first use.
+	// Defaults to false.
+	// +optional
+	CreateZones *bool `json:"createZones,omitempty"`
+
+	// Nameservers of the zones created by the provider, when `createZones` is true.
+	// +optional
+	Nameservers []dnsname.Name `json:"nameservers,omitempty"`
+}
+
 // DNSProviderStatus defines the observed state of DNSProvider
 type DNSProviderStatus struct {
 	StatusWithConditions `json:",inline"`
@@ -122,17 +373,52 @@ type DNSProviderList struct {
 	Items           []DNSProvider `json:"items"`
 }
 
+// ProviderTypes returns the types of all the providers configured in this spec,
+// which are also the names of their fields. A valid spec configures exactly one provider.
+func (spec *DNSProviderSpec) ProviderTypes() []string {
+	var res []string
+	if spec.Dummy != nil {
+		res = append(res, "dummy")
+	}
+	if spec.RFC2136 != nil {
+		res = append(res, "rfc2136")
+	}
+	if spec.Cloudflare != nil {
+		res = append(res, "cloudflare")
+	}
+	if spec.Route53 != nil {
+		res = append(res, "route53")
+	}
+	if spec.GoogleCloudDNS != nil {
+		res = append(res, "googleCloudDNS")
+	}
+	if spec.AzureDNS != nil {
+		res = append(res, "azureDNS")
+	}
+	if spec.PowerDNS != nil {
+		res = append(res, "powerdns")
+	}
+	return res
+}
+
 // GetProviderType returns a string representing the type of the provider represented by this resource.
 func (resource *DNSProvider) GetProviderType() (string, error) {
-	if resource.Spec.Dummy != nil {
-		return "dummy", nil
-	} else if resource.Spec.RFC2136 != nil {
-		return "rfc2136", nil
-	} else if resource.Spec.Cloudflare != nil {
-		return "cloudflare", nil
-	} else {
+	types := resource.Spec.ProviderTypes()
+	if len(types) == 0 {
 		return "", fmt.Errorf("Unknown provider type")
 	}
+	return types[0], nil
+}
+
+// DefaultProviderTimeout is the timeout of the operations of a provider, when not specified otherwise.
+const DefaultProviderTimeout = 30 * time.Second
+
+// GetTimeout returns the maximum duration of each operation performed by this provider.
+func (resource *DNSProvider) GetTimeout() time.Duration {
+	if resource.Spec.Timeout != nil && resource.Spec.Timeout.Duration > 0 {
+		return resource.Spec.Timeout.Duration
+	}
+	return DefaultProviderTimeout
 }
 
 func init() {
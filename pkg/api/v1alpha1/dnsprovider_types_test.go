@@ -0,0 +1,78 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+)
+
+func TestAccessPolicy(t *testing.T) {
+	require := require.New(t)
+
+	policy := &DNSProviderAccessPolicy{
+		NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"dns": "allowed"}},
+		Rules: []DNSProviderAccessRule{
+			{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+				Names:             []string{"*.a.example.com"},
+				Types:             []string{"A", "AAAA"},
+			},
+			{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}},
+				Names:             []string{"b.example.com", "*.b.example.com"},
+			},
+		},
+	}
+	teamA := labels.Set{"dns": "allowed", "team": "a"}
+	teamB := labels.Set{"dns": "allowed", "team": "b"}
+
+	table := []struct {
+		namespace string
+		labels    labels.Set
+		name      string
+		rtype     string
+		allowed   bool
+	}{
+		// Namespace selector
+		{"ns-a", teamA, "www.a.example.com", "A", true},
+		{"ns-a", labels.Set{"team": "a"}, "www.a.example.com", "A", false},
+		{"ns-c", labels.Set{"dns": "allowed"}, "www.a.example.com", "A", false},
+
+		// Names
+		{"ns-a", teamA, "WWW.A.example.com.", "AAAA", true},
+		{"ns-a", teamA, "a.example.com", "A", false},
+		{"ns-a", teamA, "www.b.example.com", "A", false},
+		{"ns-b", teamB, "b.example.com", "TXT", true},
+		{"ns-b", teamB, "deep.www.b.example.com", "CNAME", true},
+		{"ns-b", teamB, "bb.example.com", "A", false},
+		{"ns-a", teamA, "*.a.example.com", "A", true},
+		{"ns-b", teamB, "*.b.example.com", "A", true},
+
+		// Types
+		{"ns-a", teamA, "www.a.example.com", "TXT", false},
+		{"ns-a", teamA, "www.a.example.com", "aaaa", true},
+
+		// The namespace of the provider is always allowed, but still subject to the rules
+		{"provider", labels.Set{"team": "a"}, "www.a.example.com", "A", true},
+		{"provider", labels.Set{"team": "a"}, "www.b.example.com", "A", false},
+	}
+
+	for _, entry := range table {
+		name, err := dnsname.NewName(entry.name)
+		require.Nil(err)
+		err = policy.Allows("provider", entry.namespace, entry.labels, name, entry.rtype)
+		if entry.allowed {
+			require.Nil(err, "Record %s %s in namespace %s was not allowed", entry.rtype, entry.name, entry.namespace)
+		} else {
+			require.NotNil(err, "Record %s %s in namespace %s was allowed", entry.rtype, entry.name, entry.namespace)
+		}
+	}
+
+	// Missing policies allow everything
+	var missing *DNSProviderAccessPolicy
+	require.Nil(missing.Allows("", "ns", nil, &dnsname.Name{}, "A"))
+}
@@ -17,19 +17,34 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
 	"github.com/95ulisse/dns-operator/pkg/dnsname"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // DNSRecordSpec defines the desired state of DNSRecord
 type DNSRecordSpec struct {
-	// Reference to the DNSProvider managing this DNSRecord.
-	ProviderRef ObjectReference `json:"providerRef"`
+	// Reference to the DNSProvider or ClusterDNSProvider managing this DNSRecord.
+	ProviderRef ProviderReference `json:"providerRef"`
 
 	// Name of the DNS record.
+	// It can be either a full name, or a name relative to the zone of the provider (e.g., `api`, or `@` for the zone apex).
+	// Names which end with a dot or which already belong to one of the zones of the provider are considered full names.
 	// This field is required.
 	Name dnsname.Name `json:"name"`
 
+	// Zone of the provider against which relative names are resolved.
+	// Required only when using a relative name with a provider managing more than one zone.
+	// +optional
+	Zone *dnsname.Name `json:"zone,omitempty"`
+
 	// RRSet contains the actual contents of the DNS record.
 	// The meaning of the rdata field depends on the type of record.
 	// This field is required.
@@ -47,6 +62,43 @@ type DNSRecordSpec struct {
 	// - "Retain": keep the published DNS record even after this resource is deleted.
 	// +optional
 	DeletionPolicy *DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Specifies what to do when the published DNS record is changed outside of the operator.
+	// Valid values are:
+	// - "Repair" (default): overwrite the published DNS record with the one described by this resource;
+	// - "Report": leave the published DNS record untouched and set the `Drifted` condition.
+	// +optional
+	DriftPolicy *DriftPolicy `json:"driftPolicy,omitempty"`
+
+	// Specifies what to do when the RRset already exists on the backend and is not owned by this resource.
+	// Valid values are:
+	// - "Refuse" (default): leave the existing RRset untouched and mark this resource as not ready;
+	// - "Adopt": take ownership of the existing RRset and overwrite it.
+	// +optional
+	AdoptionPolicy *AdoptionPolicy `json:"adoptionPolicy,omitempty"`
+}
+
+// UnmarshalJSON parses a DNSRecordSpec. Unlike the other names, the name of the record can be `@`
+// to refer to the apex of the zone it is resolved against.
+func (spec *DNSRecordSpec) UnmarshalJSON(data []byte) error {
+	type plainSpec DNSRecordSpec
+	raw := struct {
+		*plainSpec
+		Name *string `json:"name"`
+	}{
+		plainSpec: (*plainSpec)(spec),
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Name != nil {
+		name, err := dnsname.NewRecordName(*raw.Name)
+		if err != nil {
+			return err
+		}
+		spec.Name = *name
+	}
+	return nil
 }
 
 // DNSRecordSetData represents the actual contents of a DNS record. Only one of these can be set.
@@ -75,6 +127,21 @@ type DNSRecordSetData struct {
 	// +kubebuilder:validation:MinItems=1
 	// +optional
 	TXT []string `json:"txt,omitempty"`
+
+	// SRV record.
+	// The name of the record must be in the form `_service._proto.name`.
+	// +kubebuilder:validation:MinItems=1
+	// +optional
+	SRV []SRVRData `json:"srv,omitempty"`
+
+	// CAA record.
+	// +kubebuilder:validation:MinItems=1
+	// +optional
+	CAA []CAARData `json:"caa,omitempty"`
+
+	// Record whose contents are taken from another resource, and kept up to date when the resource changes.
+	// +optional
+	ValueFrom *RecordValueSource `json:"valueFrom,omitempty"`
 }
 
 // MXRData represents the contents of an MX DNS record.
@@ -83,14 +150,171 @@ type MXRData struct {
 	Host       dnsname.Name `json:"host"`
 }
 
+// SRVRData represents the contents of an SRV DNS record.
+type SRVRData struct {
+	Priority uint16       `json:"priority"`
+	Weight   uint16       `json:"weight"`
+	Port     uint16       `json:"port"`
+	Target   dnsname.Name `json:"target"`
+}
+
+// CAATag is the property tag of a CAA record.
+// +kubebuilder:validation:Enum=issue;issuewild;iodef
+type CAATag string
+
+const (
+	// CAAIssueTag authorizes a CA to issue certificates for the domain.
+	CAAIssueTag CAATag = "issue"
+
+	// CAAIssueWildTag authorizes a CA to issue wildcard certificates for the domain.
+	CAAIssueWildTag CAATag = "issuewild"
+
+	// CAAIodefTag specifies where CAs should report policy violations.
+	CAAIodefTag CAATag = "iodef"
+)
+
+// CAARData represents the contents of a CAA DNS record (https://tools.ietf.org/html/rfc8659).
+type CAARData struct {
+	// Flags of the record. The only flag defined is 128 (Issuer Critical).
+	// +optional
+	Flags uint8 `json:"flags,omitempty"`
+
+	// Property tag: one of `issue`, `issuewild` or `iodef`.
+	Tag CAATag `json:"tag"`
+
+	// Value of the property.
+	// For `issue` and `issuewild` this is the domain name of the CA, optionally followed by `;`-separated parameters,
+	// or just `;` to forbid issuance. For `iodef` this is a `mailto:`, `http:` or `https:` URL.
+	Value string `json:"value"`
+}
+
+// Validate checks that the value of the CAA record is consistent with its tag.
+func (data *CAARData) Validate() error {
+	switch data.Tag {
+	case CAAIssueTag, CAAIssueWildTag:
+		issuer := strings.TrimSpace(strings.SplitN(data.Value, ";", 2)[0])
+		if issuer == "" {
+			if !strings.Contains(data.Value, ";") {
+				return fmt.Errorf("Empty value for CAA %s property, use \";\" to forbid issuance", data.Tag)
+			}
+			return nil
+		}
+		if _, err := dnsname.NewName(issuer); err != nil {
+			return fmt.Errorf("Invalid issuer for CAA %s property: %s", data.Tag, err.Error())
+		}
+		return nil
+
+	case CAAIodefTag:
+		u, err := url.Parse(data.Value)
+		if err != nil {
+			return fmt.Errorf("Invalid URL for CAA iodef property: %s", err.Error())
+		}
+		if u.Scheme != "mailto" && u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("Unsupported URL scheme for CAA iodef property: %s", data.Value)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("Unsupported CAA property tag %s", data.Tag)
+	}
+}
+
+// RecordValueSource describes the resource from which the contents of a DNS record are taken.
+// Only one of the references can be set.
+type RecordValueSource struct {
+	// Type of the DNS record: one of `A`, `AAAA`, `CNAME` or `TXT`.
+	// Values of the wrong kind (e.g., IPv6 addresses for an `A` record) are ignored.
+	// +kubebuilder:validation:Enum=A;AAAA;CNAME;TXT
+	Type string `json:"type"`
+
+	// Selects the addresses of the load balancer of a Service in the same namespace of the DNSRecord.
+	// +optional
+	ServiceRef *LocalObjectReference `json:"serviceRef,omitempty"`
+
+	// Selects the external addresses of a Node.
+	// +optional
+	NodeRef *LocalObjectReference `json:"nodeRef,omitempty"`
+
+	// Selects the addresses of all the Nodes matching a label selector which are Ready and not cordoned.
+	// +optional
+	NodeSelector *NodeAddressSelector `json:"nodeSelector,omitempty"`
+
+	// Selects a key of a ConfigMap in the same namespace of the DNSRecord.
+	// Values are separated by whitespace or commas, except for TXT records.
+	// +optional
+	ConfigMapKeyRef *ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// Selects a field of a resource in the same namespace of the DNSRecord with a JSONPath expression.
+	// Only the kinds allowed by the `--field-ref-kinds` flag of the operator can be referenced,
+	// and never Secrets or cluster scoped resources.
+	// +optional
+	FieldRef *ObjectFieldSelector `json:"fieldRef,omitempty"`
+}
+
+// LocalObjectReference is a reference to an object in the same namespace.
+type LocalObjectReference struct {
+	// Name of the resource being referred.
+	Name string `json:"name"`
+}
+
+// NodeAddressSelector selects the addresses of a set of Nodes.
+type NodeAddressSelector struct {
+	// Label selector of the Nodes. An empty selector matches all the Nodes.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Type of the addresses to select: `ExternalIP` (default) or `InternalIP`.
+	// +kubebuilder:validation:Enum=ExternalIP;InternalIP
+	// +optional
+	AddressType *string `json:"addressType,omitempty"`
+}
+
+// ConfigMapKeySelector selects a key of a ConfigMap.
+type ConfigMapKeySelector struct {
+	// Name of the ConfigMap.
+	Name string `json:"name"`
+
+	// Key of the ConfigMap to select.
+	Key string `json:"key"`
+}
+
+// ObjectFieldSelector selects a field of an arbitrary resource.
+type ObjectFieldSelector struct {
+	// API version of the referenced resource, e.g. `v1` or `apps/v1`.
+	// Only the group is relevant: resources are read at the version preferred by the API server.
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the referenced resource.
+	Kind string `json:"kind"`
+
+	// Name of the referenced resource.
+	Name string `json:"name"`
+
+	// JSONPath expression selecting the values, e.g. `{.status.podIP}`.
+	JSONPath string `json:"jsonPath"`
+}
+
 // DNSRecordStatus defines the observed state of DNSRecord
 type DNSRecordStatus struct {
 	StatusWithConditions `json:",inline"`
+
+	// Fully qualified name of the published DNS record.
+	// +optional
+	FQDN *dnsname.Name `json:"fqdn,omitempty"`
+
+	// The generation of the resource which has been last published.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Contents of the published DNS record, when taken from another resource with `valueFrom`.
+	// +optional
+	PublishedRRSet *DNSRecordSetData `json:"publishedRRSet,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="RR Name",type="string",JSONPath=`.spec.name`
+// +kubebuilder:printcolumn:name="FQDN",type="string",JSONPath=`.status.fqdn`
 // +kubebuilder:printcolumn:name="RR Data",type="string",JSONPath=`.spec.content`
 
 // DNSRecord is the Schema for the dnsrecords API
@@ -114,10 +338,111 @@ func (resource *DNSRecord) RType() string {
 		return "CNAME"
 	} else if resource.Spec.RRSet.TXT != nil {
 		return "TXT"
+	} else if resource.Spec.RRSet.SRV != nil {
+		return "SRV"
+	} else if resource.Spec.RRSet.CAA != nil {
+		return "CAA"
+	} else if resource.Spec.RRSet.ValueFrom != nil {
+		return resource.Spec.RRSet.ValueFrom.Type
 	}
 	return ""
 }
 
+// RData returns a normalized textual representation of each record of the RRset, in a stable order.
+// It is meant to compare RRsets coming from different sources, e.g. a resource and the records read back from a provider.
+func (resource *DNSRecord) RData() []string {
+	spec := &resource.Spec.RRSet
+	var rdata []string
+
+	switch resource.RType() {
+	case "A":
+		for _, value := range spec.A {
+			rdata = append(rdata, normalizeIP(string(value)))
+		}
+	case "AAAA":
+		for _, value := range spec.AAAA {
+			rdata = append(rdata, normalizeIP(string(value)))
+		}
+	case "MX":
+		for _, value := range spec.MX {
+			rdata = append(rdata, fmt.Sprintf("%d %s", value.Preference, normalizeName(&value.Host)))
+		}
+	case "CNAME":
+		for _, value := range spec.CNAME {
+			rdata = append(rdata, normalizeName(&value))
+		}
+	case "TXT":
+		for _, value := range spec.TXT {
+			rdata = append(rdata, strconv.Quote(value))
+		}
+	case "SRV":
+		for _, value := range spec.SRV {
+			rdata = append(rdata, fmt.Sprintf("%d %d %d %s", value.Priority, value.Weight, value.Port, normalizeName(&value.Target)))
+		}
+	case "CAA":
+		for _, value := range spec.CAA {
+			rdata = append(rdata, fmt.Sprintf("%d %s %s", value.Flags, strings.ToLower(string(value.Tag)), strconv.Quote(value.Value)))
+		}
+	}
+
+	sort.Strings(rdata)
+	return rdata
+}
+
+// DefaultTTLSeconds is the TTL of the records which do not specify one.
+const DefaultTTLSeconds uint32 = 3600
+
+// Default fills in the optional fields of the resource with their default values:
+// the namespace of the provider reference, the TTL and the deletion policy.
+func (resource *DNSRecord) Default() {
+	if resource.Spec.ProviderRef.Namespace == nil && !resource.Spec.ProviderRef.IsClusterScoped() {
+		namespace := resource.Namespace
+		resource.Spec.ProviderRef.Namespace = &namespace
+	}
+	if resource.Spec.TTLSeconds == nil {
+		ttl := DefaultTTLSeconds
+		resource.Spec.TTLSeconds = &ttl
+	}
+	if resource.Spec.DeletionPolicy == nil {
+		policy := DeletePolicy
+		resource.Spec.DeletionPolicy = &policy
+	}
+}
+
+// RRSetEquals returns `true` if the two resources describe the same RRset, i.e., same name, type and records.
+// TTLs are compared only when specified by both the resources.
+func (resource *DNSRecord) RRSetEquals(other *DNSRecord) bool {
+	if !resource.Spec.Name.Equal(&other.Spec.Name) || resource.RType() != other.RType() {
+		return false
+	}
+	if resource.Spec.TTLSeconds != nil && other.Spec.TTLSeconds != nil && *resource.Spec.TTLSeconds != *other.Spec.TTLSeconds {
+		return false
+	}
+
+	rdata := resource.RData()
+	otherRData := other.RData()
+	if len(rdata) != len(otherRData) {
+		return false
+	}
+	for i := range rdata {
+		if rdata[i] != otherRData[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeIP(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil {
+		return parsed.String()
+	}
+	return ip
+}
+
+func normalizeName(name *dnsname.Name) string {
+	return strings.ToLower(name.ToFQDN().String())
+}
+
 // +kubebuilder:object:root=true
 
 // DNSRecordList contains a list of DNSRecord
@@ -0,0 +1,158 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+)
+
+func TestCAAValidate(t *testing.T) {
+	require := require.New(t)
+
+	table := []struct {
+		tag     CAATag
+		value   string
+		success bool
+	}{
+		// Issuers
+		{CAAIssueTag, "letsencrypt.org", true},
+		{CAAIssueTag, "letsencrypt.org; validationmethods=dns-01", true},
+		{CAAIssueWildTag, "pki.goog", true},
+		{CAAIssueTag, ";", true},
+		{CAAIssueWildTag, ";", true},
+		{CAAIssueTag, "", false},
+		{CAAIssueTag, "letsencrypt..org", false},
+
+		// Incident reports
+		{CAAIodefTag, "mailto:security@example.com", true},
+		{CAAIodefTag, "https://example.com/caa-report", true},
+		{CAAIodefTag, "ftp://example.com", false},
+		{CAAIodefTag, "example.com", false},
+
+		// Unknown tags
+		{CAATag("tbs"), "anything", false},
+	}
+
+	for _, entry := range table {
+		data := CAARData{Tag: entry.tag, Value: entry.value}
+		err := data.Validate()
+		if entry.success {
+			require.Nil(err, "Valid CAA %s %q failed validation", entry.tag, entry.value)
+		} else {
+			require.NotNil(err, "Invalid CAA %s %q passed validation", entry.tag, entry.value)
+		}
+	}
+}
+
+func TestRRSetEquals(t *testing.T) {
+	require := require.New(t)
+
+	name := func(s string) dnsname.Name {
+		n, err := dnsname.NewName(s)
+		require.Nil(err)
+		return *n
+	}
+	ttl := func(v uint32) *uint32 { return &v }
+
+	base := DNSRecord{Spec: DNSRecordSpec{
+		Name:       name("www.example.com"),
+		TTLSeconds: ttl(300),
+		RRSet:      DNSRecordSetData{A: []Ipv4String{"1.1.1.1", "8.8.8.8"}},
+	}}
+
+	// Order of the records, case and final dot of the name do not matter
+	other := DNSRecord{Spec: DNSRecordSpec{
+		Name:       name("WWW.example.com."),
+		TTLSeconds: ttl(300),
+		RRSet:      DNSRecordSetData{A: []Ipv4String{"8.8.8.8", "1.1.1.1"}},
+	}}
+	require.True(base.RRSetEquals(&other))
+
+	// Missing TTLs are not compared
+	other.Spec.TTLSeconds = nil
+	require.True(base.RRSetEquals(&other))
+	other.Spec.TTLSeconds = ttl(60)
+	require.False(base.RRSetEquals(&other))
+	other.Spec.TTLSeconds = ttl(300)
+
+	// Different records
+	other.Spec.RRSet.A = []Ipv4String{"1.1.1.1"}
+	require.False(base.RRSetEquals(&other))
+
+	// Different types
+	other.Spec.RRSet = DNSRecordSetData{AAAA: []Ipv6String{"::1"}}
+	require.False(base.RRSetEquals(&other))
+
+	// Different names
+	other.Spec.Name = name("example.com")
+	other.Spec.RRSet = base.Spec.RRSet
+	require.False(base.RRSetEquals(&other))
+
+	// Names in the record data are normalized too
+	mx := DNSRecord{Spec: DNSRecordSpec{Name: name("example.com"), RRSet: DNSRecordSetData{MX: []MXRData{{10, name("Mail.example.com")}}}}}
+	mx2 := DNSRecord{Spec: DNSRecordSpec{Name: name("example.com"), RRSet: DNSRecordSetData{MX: []MXRData{{10, name("mail.example.com.")}}}}}
+	require.True(mx.RRSetEquals(&mx2))
+}
+
+func TestDNSRecordDefault(t *testing.T) {
+	require := require.New(t)
+
+	// Missing fields are filled in
+	var record DNSRecord
+	record.Namespace = "default"
+	record.Spec.ProviderRef.Name = "provider"
+	record.Default()
+	require.Equal("default", *record.Spec.ProviderRef.Namespace)
+	require.Equal(DefaultTTLSeconds, *record.Spec.TTLSeconds)
+	require.Equal(DeletePolicy, *record.Spec.DeletionPolicy)
+
+	// Existing values are preserved
+	other := "other"
+	ttl := uint32(60)
+	retain := RetainPolicy
+	record.Spec.ProviderRef.Namespace = &other
+	record.Spec.TTLSeconds = &ttl
+	record.Spec.DeletionPolicy = &retain
+	record.Default()
+	require.Equal("other", *record.Spec.ProviderRef.Namespace)
+	require.Equal(uint32(60), *record.Spec.TTLSeconds)
+	require.Equal(RetainPolicy, *record.Spec.DeletionPolicy)
+
+	// References to cluster scoped providers have no namespace
+	var clusterRecord DNSRecord
+	clusterRecord.Namespace = "default"
+	clusterRecord.Spec.ProviderRef = ProviderReference{Kind: ClusterDNSProviderKind, Name: "provider"}
+	clusterRecord.Default()
+	require.Nil(clusterRecord.Spec.ProviderRef.Namespace)
+}
+
+func TestDNSRecordSpecUnmarshal(t *testing.T) {
+	require := require.New(t)
+
+	table := []struct {
+		json    string
+		name    string
+		success bool
+	}{
+		{`{"name": "www", "rrset": {"cname": ["example.org."]}}`, "www", true},
+		{`{"name": "@", "rrset": {"a": ["1.1.1.1"]}}`, "@", true},
+		{`{"name": "@", "rrset": {"cname": ["@"]}}`, "", false},
+		{`{"name": "@.example.com", "rrset": {"a": ["1.1.1.1"]}}`, "", false},
+	}
+
+	for _, entry := range table {
+		var spec DNSRecordSpec
+		err := json.Unmarshal([]byte(entry.json), &spec)
+		if entry.success {
+			require.Nil(err, "Spec: %s", entry.json)
+			require.Equal(entry.name, spec.Name.String())
+			record := DNSRecord{Spec: spec}
+			require.NotEmpty(record.RData(), "Spec: %s", entry.json)
+		} else {
+			require.NotNil(err, "Spec: %s", entry.json)
+		}
+	}
+}
@@ -35,6 +35,32 @@ const (
 	RetainPolicy DeletionPolicy = "Retain"
 )
 
+// DriftPolicy describes what to do when the published DNS record differs from the one described by the DNSRecord resource,
+// for example because of a manual change made on the backend.
+// +kubebuilder:validation:Enum=Repair;Report
+type DriftPolicy string
+
+const (
+	// RepairPolicy overwrites the published DNS record with the one described by the resource.
+	RepairPolicy DriftPolicy = "Repair"
+
+	// ReportPolicy leaves the published DNS record untouched, and only reports the drift with the `Drifted` condition.
+	ReportPolicy DriftPolicy = "Report"
+)
+
+// AdoptionPolicy describes what to do when a DNSRecord resource targets an RRset
+// which already exists on the backend and is not owned by the resource.
+// +kubebuilder:validation:Enum=Refuse;Adopt
+type AdoptionPolicy string
+
+const (
+	// RefusePolicy leaves the existing RRset untouched, and marks the resource as not ready.
+	RefusePolicy AdoptionPolicy = "Refuse"
+
+	// AdoptPolicy takes ownership of the existing RRset, overwriting it.
+	AdoptPolicy AdoptionPolicy = "Adopt"
+)
+
 // ObjectReference is a reference to an object in a (possibly another) namespace.
 type ObjectReference struct {
 	// Name of the resource being referred.
@@ -45,6 +71,55 @@ type ObjectReference struct {
 	Namespace *string `json:"namespace,omitempty"`
 }
 
+// Kinds of the resources which can be referenced as providers by a DNSRecord.
+const (
+	DNSProviderKind        = "DNSProvider"
+	ClusterDNSProviderKind = "ClusterDNSProvider"
+)
+
+// ProviderReference is a reference to a DNSProvider or to a ClusterDNSProvider.
+type ProviderReference struct {
+	// Kind of the provider being referred: `DNSProvider` (default) or `ClusterDNSProvider`.
+	// +kubebuilder:validation:Enum=DNSProvider;ClusterDNSProvider
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the provider being referred.
+	Name string `json:"name"`
+
+	// Name of the namespace of the DNSProvider being referred.
+	// Ignored for ClusterDNSProviders.
+	// +optional
+	Namespace *string `json:"namespace,omitempty"`
+}
+
+// IsClusterScoped tells whether the reference points to a ClusterDNSProvider.
+func (ref *ProviderReference) IsClusterScoped() bool {
+	return ref.Kind == ClusterDNSProviderKind
+}
+
+// Key returns the key identifying the referenced provider,
+// interpreting references without a namespace relative to the given one.
+func (ref *ProviderReference) Key(namespace string) string {
+	if ref.IsClusterScoped() {
+		return ProviderKey(ClusterDNSProviderKind, "", ref.Name)
+	}
+	if ref.Namespace != nil {
+		namespace = *ref.Namespace
+	}
+	return ProviderKey(DNSProviderKind, namespace, ref.Name)
+}
+
+// ProviderKey returns the key identifying a provider of the given kind:
+// `namespace/name` for DNSProviders, and `ClusterDNSProvider/name` for ClusterDNSProviders.
+// Keys cannot collide, since names of namespaces cannot contain uppercase letters.
+func ProviderKey(kind, namespace, name string) string {
+	if kind == ClusterDNSProviderKind {
+		return ClusterDNSProviderKind + "/" + name
+	}
+	return namespace + "/" + name
+}
+
 // SecretReference is a reference to a specific secret.
 type SecretReference struct {
 	// The name of the Secret resource being referred to.
@@ -60,6 +135,22 @@ type ConditionType string
 const (
 	// ReadyCondition represents the `Ready` condition.
 	ReadyCondition ConditionType = "Ready"
+
+	// DriftedCondition represents the `Drifted` condition,
+	// which signals that the published DNS record differs from the desired one.
+	DriftedCondition ConditionType = "Drifted"
+
+	// ConflictCondition represents the `Conflict` condition,
+	// which signals that another DNSRecord claims the same RRset on the same provider.
+	ConflictCondition ConditionType = "Conflict"
+
+	// ResolvedCondition represents the `Resolved` condition,
+	// which signals whether the contents of a record taken from another resource with `valueFrom` could be resolved.
+	ResolvedCondition ConditionType = "Resolved"
+
+	// ForbiddenCondition represents the `Forbidden` condition,
+	// which signals that the access policy of the provider does not allow the DNSRecord.
+	ForbiddenCondition ConditionType = "Forbidden"
 )
 
 // ConditionStatus represents the possible values of a condition: True, False or Unknown.
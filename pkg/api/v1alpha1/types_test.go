@@ -0,0 +1,27 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderReferenceKey(t *testing.T) {
+	require := require.New(t)
+
+	other := "other"
+	table := []struct {
+		ref ProviderReference
+		key string
+	}{
+		{ProviderReference{Name: "provider"}, "default/provider"},
+		{ProviderReference{Kind: DNSProviderKind, Name: "provider"}, "default/provider"},
+		{ProviderReference{Name: "provider", Namespace: &other}, "other/provider"},
+		{ProviderReference{Kind: ClusterDNSProviderKind, Name: "provider"}, "ClusterDNSProvider/provider"},
+		{ProviderReference{Kind: ClusterDNSProviderKind, Name: "provider", Namespace: &other}, "ClusterDNSProvider/provider"},
+	}
+
+	for _, entry := range table {
+		require.Equal(entry.key, entry.ref.Key("default"), "%+v", entry.ref)
+	}
+}
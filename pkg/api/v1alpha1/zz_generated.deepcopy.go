@@ -22,9 +22,84 @@ package v1alpha1
 
 import (
 	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CAARData) DeepCopyInto(out *CAARData) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CAARData.
+func (in *CAARData) DeepCopy() *CAARData {
+	if in == nil {
+		return nil
+	}
+	out := new(CAARData)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterDNSProvider) DeepCopyInto(out *ClusterDNSProvider) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDNSProvider.
+func (in *ClusterDNSProvider) DeepCopy() *ClusterDNSProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterDNSProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterDNSProvider) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterDNSProviderList) DeepCopyInto(out *ClusterDNSProviderList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterDNSProvider, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDNSProviderList.
+func (in *ClusterDNSProviderList) DeepCopy() *ClusterDNSProviderList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterDNSProviderList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterDNSProviderList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Condition) DeepCopyInto(out *Condition) {
 	*out = *in
@@ -42,6 +117,21 @@ func (in *Condition) DeepCopy() *Condition {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapKeySelector) DeepCopyInto(out *ConfigMapKeySelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapKeySelector.
+func (in *ConfigMapKeySelector) DeepCopy() *ConfigMapKeySelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapKeySelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DNSProvider) DeepCopyInto(out *DNSProvider) {
 	*out = *in
@@ -69,6 +159,106 @@ func (in *DNSProvider) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSProviderAccessPolicy) DeepCopyInto(out *DNSProviderAccessPolicy) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]DNSProviderAccessRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSProviderAccessPolicy.
+func (in *DNSProviderAccessPolicy) DeepCopy() *DNSProviderAccessPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSProviderAccessPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSProviderAccessRule) DeepCopyInto(out *DNSProviderAccessRule) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Types != nil {
+		in, out := &in.Types, &out.Types
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSProviderAccessRule.
+func (in *DNSProviderAccessRule) DeepCopy() *DNSProviderAccessRule {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSProviderAccessRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSProviderAzureDNS) DeepCopyInto(out *DNSProviderAzureDNS) {
+	*out = *in
+	in.ClientSecretSecretRef.DeepCopyInto(&out.ClientSecretSecretRef)
+	if in.ResourceGroup != nil {
+		in, out := &in.ResourceGroup, &out.ResourceGroup
+		*out = new(string)
+		**out = **in
+	}
+	if in.ZoneResourceGroups != nil {
+		in, out := &in.ZoneResourceGroups, &out.ZoneResourceGroups
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PrivateZones != nil {
+		in, out := &in.PrivateZones, &out.PrivateZones
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Endpoint != nil {
+		in, out := &in.Endpoint, &out.Endpoint
+		*out = new(string)
+		**out = **in
+	}
+	if in.AuthorityHost != nil {
+		in, out := &in.AuthorityHost, &out.AuthorityHost
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSProviderAzureDNS.
+func (in *DNSProviderAzureDNS) DeepCopy() *DNSProviderAzureDNS {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSProviderAzureDNS)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DNSProviderCloudflare) DeepCopyInto(out *DNSProviderCloudflare) {
 	*out = *in
@@ -104,6 +294,39 @@ func (in *DNSProviderCloudflare) DeepCopy() *DNSProviderCloudflare {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSProviderGoogleCloudDNS) DeepCopyInto(out *DNSProviderGoogleCloudDNS) {
+	*out = *in
+	in.ServiceAccountSecretRef.DeepCopyInto(&out.ServiceAccountSecretRef)
+	if in.Project != nil {
+		in, out := &in.Project, &out.Project
+		*out = new(string)
+		**out = **in
+	}
+	if in.ManagedZones != nil {
+		in, out := &in.ManagedZones, &out.ManagedZones
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Endpoint != nil {
+		in, out := &in.Endpoint, &out.Endpoint
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSProviderGoogleCloudDNS.
+func (in *DNSProviderGoogleCloudDNS) DeepCopy() *DNSProviderGoogleCloudDNS {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSProviderGoogleCloudDNS)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DNSProviderList) DeepCopyInto(out *DNSProviderList) {
 	*out = *in
@@ -136,6 +359,37 @@ func (in *DNSProviderList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSProviderPowerDNS) DeepCopyInto(out *DNSProviderPowerDNS) {
+	*out = *in
+	if in.ServerID != nil {
+		in, out := &in.ServerID, &out.ServerID
+		*out = new(string)
+		**out = **in
+	}
+	in.APIKeySecretRef.DeepCopyInto(&out.APIKeySecretRef)
+	if in.CreateZones != nil {
+		in, out := &in.CreateZones, &out.CreateZones
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Nameservers != nil {
+		in, out := &in.Nameservers, &out.Nameservers
+		*out = make([]dnsname.Name, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSProviderPowerDNS.
+func (in *DNSProviderPowerDNS) DeepCopy() *DNSProviderPowerDNS {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSProviderPowerDNS)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DNSProviderRFC2136) DeepCopyInto(out *DNSProviderRFC2136) {
 	*out = *in
@@ -166,6 +420,38 @@ func (in *DNSProviderRFC2136) DeepCopy() *DNSProviderRFC2136 {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSProviderRoute53) DeepCopyInto(out *DNSProviderRoute53) {
+	*out = *in
+	in.AccessKeyIDSecretRef.DeepCopyInto(&out.AccessKeyIDSecretRef)
+	in.SecretAccessKeySecretRef.DeepCopyInto(&out.SecretAccessKeySecretRef)
+	if in.Region != nil {
+		in, out := &in.Region, &out.Region
+		*out = new(string)
+		**out = **in
+	}
+	if in.Endpoint != nil {
+		in, out := &in.Endpoint, &out.Endpoint
+		*out = new(string)
+		**out = **in
+	}
+	if in.PrivateZone != nil {
+		in, out := &in.PrivateZone, &out.PrivateZone
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSProviderRoute53.
+func (in *DNSProviderRoute53) DeepCopy() *DNSProviderRoute53 {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSProviderRoute53)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DNSProviderSpec) DeepCopyInto(out *DNSProviderSpec) {
 	*out = *in
@@ -174,6 +460,16 @@ func (in *DNSProviderSpec) DeepCopyInto(out *DNSProviderSpec) {
 		*out = make([]dnsname.Name, len(*in))
 		copy(*out, *in)
 	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.AccessPolicy != nil {
+		in, out := &in.AccessPolicy, &out.AccessPolicy
+		*out = new(DNSProviderAccessPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Dummy != nil {
 		in, out := &in.Dummy, &out.Dummy
 		*out = new(bool)
@@ -189,6 +485,26 @@ func (in *DNSProviderSpec) DeepCopyInto(out *DNSProviderSpec) {
 		*out = new(DNSProviderCloudflare)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Route53 != nil {
+		in, out := &in.Route53, &out.Route53
+		*out = new(DNSProviderRoute53)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GoogleCloudDNS != nil {
+		in, out := &in.GoogleCloudDNS, &out.GoogleCloudDNS
+		*out = new(DNSProviderGoogleCloudDNS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AzureDNS != nil {
+		in, out := &in.AzureDNS, &out.AzureDNS
+		*out = new(DNSProviderAzureDNS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PowerDNS != nil {
+		in, out := &in.PowerDNS, &out.PowerDNS
+		*out = new(DNSProviderPowerDNS)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSProviderSpec.
@@ -304,6 +620,21 @@ func (in *DNSRecordSetData) DeepCopyInto(out *DNSRecordSetData) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.SRV != nil {
+		in, out := &in.SRV, &out.SRV
+		*out = make([]SRVRData, len(*in))
+		copy(*out, *in)
+	}
+	if in.CAA != nil {
+		in, out := &in.CAA, &out.CAA
+		*out = make([]CAARData, len(*in))
+		copy(*out, *in)
+	}
+	if in.ValueFrom != nil {
+		in, out := &in.ValueFrom, &out.ValueFrom
+		*out = new(RecordValueSource)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSRecordSetData.
@@ -321,6 +652,11 @@ func (in *DNSRecordSpec) DeepCopyInto(out *DNSRecordSpec) {
 	*out = *in
 	in.ProviderRef.DeepCopyInto(&out.ProviderRef)
 	out.Name = in.Name
+	if in.Zone != nil {
+		in, out := &in.Zone, &out.Zone
+		*out = new(dnsname.Name)
+		**out = **in
+	}
 	in.RRSet.DeepCopyInto(&out.RRSet)
 	if in.TTLSeconds != nil {
 		in, out := &in.TTLSeconds, &out.TTLSeconds
@@ -332,6 +668,16 @@ func (in *DNSRecordSpec) DeepCopyInto(out *DNSRecordSpec) {
 		*out = new(DeletionPolicy)
 		**out = **in
 	}
+	if in.DriftPolicy != nil {
+		in, out := &in.DriftPolicy, &out.DriftPolicy
+		*out = new(DriftPolicy)
+		**out = **in
+	}
+	if in.AdoptionPolicy != nil {
+		in, out := &in.AdoptionPolicy, &out.AdoptionPolicy
+		*out = new(AdoptionPolicy)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSRecordSpec.
@@ -348,6 +694,16 @@ func (in *DNSRecordSpec) DeepCopy() *DNSRecordSpec {
 func (in *DNSRecordStatus) DeepCopyInto(out *DNSRecordStatus) {
 	*out = *in
 	in.StatusWithConditions.DeepCopyInto(&out.StatusWithConditions)
+	if in.FQDN != nil {
+		in, out := &in.FQDN, &out.FQDN
+		*out = new(dnsname.Name)
+		**out = **in
+	}
+	if in.PublishedRRSet != nil {
+		in, out := &in.PublishedRRSet, &out.PublishedRRSet
+		*out = new(DNSRecordSetData)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSRecordStatus.
@@ -360,6 +716,21 @@ func (in *DNSRecordStatus) DeepCopy() *DNSRecordStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalObjectReference) DeepCopyInto(out *LocalObjectReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalObjectReference.
+func (in *LocalObjectReference) DeepCopy() *LocalObjectReference {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalObjectReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MXRData) DeepCopyInto(out *MXRData) {
 	*out = *in
@@ -376,6 +747,46 @@ func (in *MXRData) DeepCopy() *MXRData {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeAddressSelector) DeepCopyInto(out *NodeAddressSelector) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AddressType != nil {
+		in, out := &in.AddressType, &out.AddressType
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeAddressSelector.
+func (in *NodeAddressSelector) DeepCopy() *NodeAddressSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeAddressSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectFieldSelector) DeepCopyInto(out *ObjectFieldSelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectFieldSelector.
+func (in *ObjectFieldSelector) DeepCopy() *ObjectFieldSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectFieldSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectReference) DeepCopyInto(out *ObjectReference) {
 	*out = *in
@@ -396,6 +807,82 @@ func (in *ObjectReference) DeepCopy() *ObjectReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderReference) DeepCopyInto(out *ProviderReference) {
+	*out = *in
+	if in.Namespace != nil {
+		in, out := &in.Namespace, &out.Namespace
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderReference.
+func (in *ProviderReference) DeepCopy() *ProviderReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecordValueSource) DeepCopyInto(out *RecordValueSource) {
+	*out = *in
+	if in.ServiceRef != nil {
+		in, out := &in.ServiceRef, &out.ServiceRef
+		*out = new(LocalObjectReference)
+		**out = **in
+	}
+	if in.NodeRef != nil {
+		in, out := &in.NodeRef, &out.NodeRef
+		*out = new(LocalObjectReference)
+		**out = **in
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = new(NodeAddressSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(ConfigMapKeySelector)
+		**out = **in
+	}
+	if in.FieldRef != nil {
+		in, out := &in.FieldRef, &out.FieldRef
+		*out = new(ObjectFieldSelector)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecordValueSource.
+func (in *RecordValueSource) DeepCopy() *RecordValueSource {
+	if in == nil {
+		return nil
+	}
+	out := new(RecordValueSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SRVRData) DeepCopyInto(out *SRVRData) {
+	*out = *in
+	out.Target = in.Target
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SRVRData.
+func (in *SRVRData) DeepCopy() *SRVRData {
+	if in == nil {
+		return nil
+	}
+	out := new(SRVRData)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretReference) DeepCopyInto(out *SecretReference) {
 	*out = *in
@@ -0,0 +1,78 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+// ClusterDNSProviderReconciler reconciles a ClusterDNSProvider object
+type ClusterDNSProviderReconciler struct {
+	client.Client
+	Log     logr.Logger
+	Scheme  *runtime.Scheme
+	Context *types.ControllerContext
+
+	// Namespace from which the secrets referenced without a namespace are read.
+	CredentialsNamespace string
+}
+
+// +kubebuilder:rbac:groups=dns.k8s.marcocameriero.net,resources=clusterdnsproviders,verbs=get;list;watch
+// +kubebuilder:rbac:groups=dns.k8s.marcocameriero.net,resources=clusterdnsproviders/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+
+// Reconcile performs an iteration of the reconcile loop for a ClusterDNSProvider.
+func (r *ClusterDNSProviderReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := r.Context.RootContext
+	log := r.Log.WithValues("clusterdnsprovider", req.Name)
+	key := dnsv1alpha1.ProviderKey(dnsv1alpha1.ClusterDNSProviderKind, "", req.Name)
+
+	log.V(1).Info("Starting reconcile loop")
+	defer log.V(1).Info("Finish reconcile loop")
+
+	// Retrieve the provider by name
+	var resource dnsv1alpha1.ClusterDNSProvider
+	if err := r.Get(ctx, req.NamespacedName, &resource); err != nil {
+		// Remove the provider from the global context after deletion
+		if apierrors.IsNotFound(err) {
+			r.Context.RemoveProvider(key)
+			log.V(1).Info("Removed provider")
+		} else {
+			log.Error(err, "Unable to fetch ClusterDNSProvider")
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// The provider is built as if it was a DNSProvider living in the namespace of the credentials
+	return reconcileProvider(ctx, r.Client, r.Context, log, key, &resource, &resource.Status, resource.AsDNSProvider(r.CredentialsNamespace))
+}
+
+// SetupWithManager registers the ClusterDNSProvider controller with the given Manager.
+func (r *ClusterDNSProviderReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&dnsv1alpha1.ClusterDNSProvider{}).
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		Complete(r)
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+// RRSetIndex is the name of the field index of DNSRecords by the provider and the type of the RRset they claim.
+// The name of the RRset is not part of the index, since resolving it depends on the zones of the provider,
+// which can change without any event for the records: it is resolved when looking for conflicts instead.
+const RRSetIndex = ".rrset"
+
+// RRSetIndexKey returns the value of the RRSetIndex for the given record.
+func RRSetIndexKey(record *dnsv1alpha1.DNSRecord) string {
+	return fmt.Sprintf("%s/%s", record.Spec.ProviderRef.Key(record.Namespace), record.RType())
+}
+
+// RRSetName returns the fully qualified name of the RRset claimed by the given record.
+// Returns false if the name cannot be determined yet, because it is relative and the provider has not been loaded.
+func RRSetName(controllerCtx *types.ControllerContext, record *dnsv1alpha1.DNSRecord) (*dnsname.Name, bool) {
+	var provider types.Provider
+	var zone, resolved dnsname.Name
+	switch {
+	case controllerCtx.GetProvider(record.Spec.ProviderRef.Key(record.Namespace), &provider) &&
+		ResolveRecordName(provider.Zones(), record, &zone, &resolved) == nil:
+		return &resolved, true
+	case record.Spec.Name.IsFQDN():
+		return &record.Spec.Name, true
+	case record.Status.FQDN != nil && record.Status.ObservedGeneration == record.Generation:
+		return record.Status.FQDN, true
+	default:
+		return nil, false
+	}
+}
+
+// RRSetKey returns the key identifying the RRset claimed by the given record,
+// made of the provider, the fully qualified name and the type of the record.
+// Returns false if the fully qualified name of the record cannot be determined yet.
+func RRSetKey(controllerCtx *types.ControllerContext, record *dnsv1alpha1.DNSRecord) (string, bool) {
+	fqdn, ok := RRSetName(controllerCtx, record)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%s/%s/%s", record.Spec.ProviderRef.Key(record.Namespace), strings.ToLower(fqdn.ToFQDN().String()), record.RType()), true
+}
+
+// listClaiming returns the other records claiming the same RRset of the given one.
+func listClaiming(ctx context.Context, c client.Client, controllerCtx *types.ControllerContext, record *dnsv1alpha1.DNSRecord) ([]dnsv1alpha1.DNSRecord, error) {
+	key, ok := RRSetKey(controllerCtx, record)
+	if !ok {
+		return nil, nil
+	}
+
+	var list dnsv1alpha1.DNSRecordList
+	if err := c.List(ctx, &list, client.MatchingField(RRSetIndex, RRSetIndexKey(record))); err != nil {
+		return nil, err
+	}
+
+	var res []dnsv1alpha1.DNSRecord
+	for i := range list.Items {
+		other := &list.Items[i]
+		if other.Namespace == record.Namespace && other.Name == record.Name {
+			continue
+		}
+		if otherKey, ok := RRSetKey(controllerCtx, other); ok && otherKey == key {
+			res = append(res, *other)
+		}
+	}
+	return res, nil
+}
+
+// FindConflict looks for other records claiming the same RRset of the given one,
+// and returns the one winning the claim, or nil if the given record wins.
+//
+// Conflicts are resolved deterministically in favor of the oldest record,
+// or of the first one by namespace and name if they have been created at the same time.
+// Records which have not been created yet are always the newest.
+func FindConflict(ctx context.Context, c client.Client, controllerCtx *types.ControllerContext, record *dnsv1alpha1.DNSRecord) (*dnsv1alpha1.DNSRecord, error) {
+	others, err := listClaiming(ctx, c, controllerCtx, record)
+	if err != nil {
+		return nil, err
+	}
+
+	var winner *dnsv1alpha1.DNSRecord
+	for i := range others {
+		other := &others[i]
+		if isOlder(other, record) && (winner == nil || isOlder(other, winner)) {
+			winner = other
+		}
+	}
+	return winner, nil
+}
+
+// isOlder tells whether `a` wins a conflict against `b`.
+func isOlder(a, b *dnsv1alpha1.DNSRecord) bool {
+	ta, tb := a.CreationTimestamp, b.CreationTimestamp
+	switch {
+	case ta.IsZero() != tb.IsZero():
+		return tb.IsZero()
+	case !ta.Equal(&tb):
+		return ta.Before(&tb)
+	case a.Namespace != b.Namespace:
+		return a.Namespace < b.Namespace
+	default:
+		return a.Name < b.Name
+	}
+}
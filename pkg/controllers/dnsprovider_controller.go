@@ -17,6 +17,8 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
+
 	"github.com/go-logr/logr"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -63,57 +65,73 @@ func (r *DNSProviderReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	return reconcileProvider(ctx, r.Client, r.Context, log, req.NamespacedName.String(), &resource, &resource.Status, &resource)
+}
+
+// reconcileProvider builds the provider described by `resource` and stores it in the shared context with the given key,
+// reporting the outcome in the status of `obj`, which is either the DNSProvider itself or a ClusterDNSProvider.
+func reconcileProvider(
+	ctx context.Context,
+	c client.Client,
+	controllerCtx *types.ControllerContext,
+	log logr.Logger,
+	key string,
+	obj runtime.Object,
+	status *dnsv1alpha1.DNSProviderStatus,
+	resource *dnsv1alpha1.DNSProvider,
+) (ctrl.Result, error) {
+
 	// Mark the provider as non ready
-	resource.Status.SetCondition(&dnsv1alpha1.Condition{
+	status.SetCondition(&dnsv1alpha1.Condition{
 		Type:    dnsv1alpha1.ReadyCondition,
 		Status:  dnsv1alpha1.FalseStatus,
 		Reason:  "Configuring",
 		Message: "Configuring the provider",
 	})
-	if err := r.Status().Update(ctx, &resource); err != nil {
+	if err := c.Status().Update(ctx, obj); err != nil {
 		log.Error(err, "Cannot update resource status")
 		return ctrl.Result{}, err
 	}
 
 	// Build the actual provider and store it in the shared context
-	provider, err := providers.ProviderFor(r.Context, &resource)
+	provider, err := providers.ProviderFor(ctx, controllerCtx, resource)
 	if err != nil {
 		log.Error(err, "Cannot build provider")
 
 		// Mark the provider as non ready
-		resource.Status.SetCondition(&dnsv1alpha1.Condition{
+		status.SetCondition(&dnsv1alpha1.Condition{
 			Type:    dnsv1alpha1.ReadyCondition,
 			Status:  dnsv1alpha1.FalseStatus,
 			Reason:  "Error",
 			Message: err.Error(),
 		})
-		if err := r.Status().Update(ctx, &resource); err != nil {
+		if err := c.Status().Update(ctx, obj); err != nil {
 			log.Error(err, "Cannot update resource status")
 			return ctrl.Result{}, err
 		}
 
 		// Record an event
-		r.Context.EventRecorder.Event(&resource, "Warning", "Error", err.Error())
+		controllerCtx.EventRecorder.Event(obj, "Warning", "Error", err.Error())
 
 		return ctrl.Result{}, err
 	}
-	r.Context.SetProvider(req.NamespacedName.String(), provider)
+	controllerCtx.SetProvider(key, provider)
 	log.Info("Provider updated")
 
 	// Mark the provider as ready
-	resource.Status.SetCondition(&dnsv1alpha1.Condition{
+	status.SetCondition(&dnsv1alpha1.Condition{
 		Type:    dnsv1alpha1.ReadyCondition,
 		Status:  dnsv1alpha1.TrueStatus,
 		Reason:  "Ready",
 		Message: "Ready to register DNS records",
 	})
-	if err := r.Status().Update(ctx, &resource); err != nil {
+	if err := c.Status().Update(ctx, obj); err != nil {
 		log.Error(err, "Cannot update resource status")
 		return ctrl.Result{}, err
 	}
 
 	// Record an event
-	r.Context.EventRecorder.Event(&resource, "Normal", "Ready", "Ready to register DNS records")
+	controllerCtx.EventRecorder.Event(obj, "Normal", "Ready", "Ready to register DNS records")
 
 	return ctrl.Result{}, nil
 }
@@ -17,15 +17,20 @@ limitations under the License.
 package controllers
 
 import (
-	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/source"
@@ -38,17 +43,32 @@ import (
 
 const finalizerName = "dns.k8s.marcocameriero.net/finalizer"
 
+// ProviderIndex is the name of the field index of DNSRecords by the key of the provider they reference.
+const ProviderIndex = ".spec.providerRef"
+
 // DNSRecordReconciler reconciles a DNSRecord object
 type DNSRecordReconciler struct {
 	client.Client
 	Log     logr.Logger
 	Scheme  *runtime.Scheme
 	Context *types.ControllerContext
+
+	// Interval between two consecutive checks of the published records against the desired ones.
+	// Zero disables drift detection.
+	DriftCheckInterval time.Duration
+
+	// Kinds which can be referenced by `valueFrom.fieldRef`. Secrets and cluster scoped kinds are not allowed.
+	// Defaults to DefaultFieldRefKinds.
+	FieldRefKinds []schema.GroupKind
+
+	fieldRefPolicy *FieldRefPolicy
 }
 
 // +kubebuilder:rbac:groups=dns.k8s.marcocameriero.net,resources=dnsrecords,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=dns.k8s.marcocameriero.net,resources=dnsrecords/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch;update
+// +kubebuilder:rbac:groups="",resources=services;nodes;configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 
 // Reconcile performs an iteration of the reconcile loop for a DNSRecord.
 func (r *DNSRecordReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -73,36 +93,93 @@ func (r *DNSRecordReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	// Mark the record as not ready
-	record.Status.SetCondition(&dnsv1alpha1.Condition{
-		Type:    dnsv1alpha1.ReadyCondition,
-		Status:  dnsv1alpha1.FalseStatus,
-		Reason:  "NotReady",
-		Message: "",
-	})
-	if err := r.Status().Update(ctx, &record); err != nil {
-		log.Error(err, "Cannot update resource status")
-		return ctrl.Result{}, err
+	// Resolve the contents of the record taken from other resources.
+	// Records being deleted use the last published contents instead, since the resources might be gone.
+	rrset := record.Spec.RRSet.DeepCopy()
+	if record.Spec.RRSet.ValueFrom != nil {
+		if !record.ObjectMeta.DeletionTimestamp.IsZero() {
+			rrset = record.Status.PublishedRRSet
+		} else if resolved, err := r.resolveValueFrom(ctx, &record); err != nil {
+			log.Info("Cannot resolve valueFrom", "reason", err.Error())
+			r.Context.EventRecorder.Event(&record, "Warning", "ResolutionFailed", err.Error())
+			record.Status.SetCondition(&dnsv1alpha1.Condition{
+				Type:    dnsv1alpha1.ResolvedCondition,
+				Status:  dnsv1alpha1.FalseStatus,
+				Reason:  "ResolutionFailed",
+				Message: err.Error(),
+			})
+			record.Status.SetCondition(&dnsv1alpha1.Condition{
+				Type:    dnsv1alpha1.ReadyCondition,
+				Status:  dnsv1alpha1.FalseStatus,
+				Reason:  "ResolutionFailed",
+				Message: err.Error(),
+			})
+			if err := r.Status().Update(ctx, &record); err != nil {
+				log.Error(err, "Cannot update resource status")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: r.DriftCheckInterval}, nil
+		} else {
+			rrset = resolved
+			record.Status.SetCondition(&dnsv1alpha1.Condition{
+				Type:    dnsv1alpha1.ResolvedCondition,
+				Status:  dnsv1alpha1.TrueStatus,
+				Reason:  "Resolved",
+				Message: "Record contents resolved from the referenced resource",
+			})
+		}
+	}
+
+	// A record whose current generation has already been published only needs to be checked for drift
+	published := record.Status.ObservedGeneration == record.Generation
+	if i := record.Status.GetCondition(dnsv1alpha1.ReadyCondition); i < 0 || record.Status.Conditions[i].Status != dnsv1alpha1.TrueStatus {
+		published = false
+	}
+	if record.Spec.RRSet.ValueFrom != nil && (record.Status.PublishedRRSet == nil || rrset == nil || !equality.Semantic.DeepEqual(*record.Status.PublishedRRSet, *rrset)) {
+		published = false
+	}
+
+	// Records published by the versions of the operator predating the ownership records are ready but have no FQDN
+	// in their status. Fill it in before the condition is reset, so that their RRsets can be adopted even across retries.
+	// These versions did not support relative names, so the name of the record is already the full one.
+	if i := record.Status.GetCondition(dnsv1alpha1.ReadyCondition); i >= 0 && record.Status.Conditions[i].Status == dnsv1alpha1.TrueStatus &&
+		record.Status.FQDN == nil {
+		record.Status.FQDN = record.Spec.Name.ToFQDN()
+	}
+
+	// Mark the record as not ready, unless we are just checking an already published record
+	if !published {
+		record.Status.SetCondition(&dnsv1alpha1.Condition{
+			Type:    dnsv1alpha1.ReadyCondition,
+			Status:  dnsv1alpha1.FalseStatus,
+			Reason:  "NotReady",
+			Message: "",
+		})
+		if err := r.Status().Update(ctx, &record); err != nil {
+			log.Error(err, "Cannot update resource status")
+			return ctrl.Result{}, err
+		}
 	}
 
 	// Step 2: Retrieve the referenced DNSProvider
 	// ===========================================
 
 	// Retrieve the provider
-	refName := record.Spec.ProviderRef.Name
-	refNamespace := record.Spec.ProviderRef.Namespace
-	if refNamespace == nil {
-		refNamespace = &record.Namespace
-	}
-	providerNamespacedName := fmt.Sprintf("%s/%s", *refNamespace, refName)
+	providerKey := record.Spec.ProviderRef.Key(record.Namespace)
 	var provider types.Provider
-	providerFound := r.Context.GetProvider(providerNamespacedName, &provider)
+	providerFound := r.Context.GetProvider(providerKey, &provider)
 
-	// Check that the provider manages a zone containing this record
+	// Check that the provider manages a zone containing this record,
+	// and resolve the full name of the record in case it is relative to the zone.
+	// Providers always receive a copy of the record with the full name.
 	var zone dnsname.Name
+	resolved := record.DeepCopy()
+	if rrset != nil {
+		resolved.Spec.RRSet = *rrset
+	}
 	if providerFound {
-		if !getMatchingZone(provider.Zones(), record.Spec.Name, &zone) {
-			err := fmt.Errorf("Provider %s does not support a zone matching record %s", providerNamespacedName, record.Spec.Name.String())
+		if err := ResolveRecordName(provider.Zones(), &record, &zone, &resolved.Spec.Name); err != nil {
+			log.Error(err, "Cannot resolve record name", "dnsprovider", providerKey)
 			return ctrl.Result{}, err
 		}
 	}
@@ -126,18 +203,23 @@ func (r *DNSRecordReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		// The object is being deleted, so execute the finalizer
 		if helpers.ContainsString(record.ObjectMeta.Finalizers, finalizerName) {
 
-			// Actually delete the record from the provider only if the user does not want us to retain the actual record
-			if record.Spec.DeletionPolicy == nil || *record.Spec.DeletionPolicy == dnsv1alpha1.DeletePolicy {
+			// Actually delete the record from the provider only if the user does not want us to retain the actual record,
+			// and if the record has ever been published in case its contents are taken from other resources
+			if (record.Spec.DeletionPolicy == nil || *record.Spec.DeletionPolicy == dnsv1alpha1.DeletePolicy) && rrset != nil {
 
 				if !providerFound {
-					err := fmt.Errorf("Cannot find DNSProvider %s", providerNamespacedName)
+					err := fmt.Errorf("Cannot find DNSProvider %s", providerKey)
 					log.Error(err, "Cannot delete DNSRecord")
 					return ctrl.Result{}, err
 				}
 
 				log.V(1).Info("Deleting record")
 
-				if err := provider.DeleteRecord(zone, record); err != nil {
+				// Records owned by someone else are left untouched, but must not block the deletion of the resource
+				if err := provider.DeleteRecord(ctx, zone, *resolved); errors.Is(err, types.ErrNotOwned) {
+					log.Info("Not deleting DNS record owned by someone else", "reason", err.Error())
+					r.Context.EventRecorder.Event(&record, "Warning", "NotOwned", err.Error())
+				} else if err != nil {
 					log.Error(err, "Cannot delete DNSRecord")
 					return ctrl.Result{}, err
 				}
@@ -157,28 +239,212 @@ func (r *DNSRecordReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, nil
 	}
 
-	// Step 4: Update the DNS record
-	// =============================
-
 	if !providerFound {
-		err := fmt.Errorf("Cannot find DNSProvider %s", providerNamespacedName)
+		err := fmt.Errorf("Cannot find DNSProvider %s", providerKey)
 		log.Error(err, "Cannot update DNSRecord")
 		return ctrl.Result{}, err
 	}
 
+	// Step 4: Check for conflicts with other records
+	// ==============================================
+
+	// Only one record can claim an RRset, the others are marked as conflicting and left alone
+	winner, err := FindConflict(ctx, r.Client, r.Context, &record)
+	if err != nil {
+		log.Error(err, "Cannot check for conflicting DNSRecords")
+		return ctrl.Result{}, err
+	}
+	if winner != nil {
+		message := fmt.Sprintf(
+			"RRset %s %s is already claimed by DNSRecord %s/%s",
+			resolved.RType(), resolved.Spec.Name.String(), winner.Namespace, winner.Name,
+		)
+		log.Info("Conflicting DNSRecord", "reason", message)
+		r.Context.EventRecorder.Event(&record, "Warning", "Conflict", message)
+		record.Status.FQDN = &resolved.Spec.Name
+		record.Status.SetCondition(&dnsv1alpha1.Condition{
+			Type:    dnsv1alpha1.ConflictCondition,
+			Status:  dnsv1alpha1.TrueStatus,
+			Reason:  "Conflict",
+			Message: message,
+		})
+		record.Status.SetCondition(&dnsv1alpha1.Condition{
+			Type:    dnsv1alpha1.ReadyCondition,
+			Status:  dnsv1alpha1.FalseStatus,
+			Reason:  "Conflict",
+			Message: message,
+		})
+		if err := r.Status().Update(ctx, &record); err != nil {
+			log.Error(err, "Cannot update resource status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+	if record.Status.GetCondition(dnsv1alpha1.ConflictCondition) >= 0 {
+		record.Status.SetCondition(&dnsv1alpha1.Condition{
+			Type:    dnsv1alpha1.ConflictCondition,
+			Status:  dnsv1alpha1.FalseStatus,
+			Reason:  "NoConflict",
+			Message: "No other DNSRecord claims the same RRset",
+		})
+	}
+
+	// Step 5: Check the access policy of the provider
+	// ===============================================
+
+	// Shared providers can restrict the namespaces, the names and the types of the records they publish
+	forbidden, err := CheckAccessPolicy(ctx, r.Client, &record, &resolved.Spec.Name)
+	if err != nil {
+		log.Error(err, "Cannot check the access policy of the provider")
+		return ctrl.Result{}, err
+	}
+	if forbidden != nil {
+		log.Info("Forbidden DNSRecord", "reason", forbidden.Error())
+		r.Context.EventRecorder.Event(&record, "Warning", "Forbidden", forbidden.Error())
+		record.Status.FQDN = &resolved.Spec.Name
+		record.Status.SetCondition(&dnsv1alpha1.Condition{
+			Type:    dnsv1alpha1.ForbiddenCondition,
+			Status:  dnsv1alpha1.TrueStatus,
+			Reason:  "Forbidden",
+			Message: forbidden.Error(),
+		})
+		record.Status.SetCondition(&dnsv1alpha1.Condition{
+			Type:    dnsv1alpha1.ReadyCondition,
+			Status:  dnsv1alpha1.FalseStatus,
+			Reason:  "Forbidden",
+			Message: forbidden.Error(),
+		})
+		if err := r.Status().Update(ctx, &record); err != nil {
+			log.Error(err, "Cannot update resource status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+	if record.Status.GetCondition(dnsv1alpha1.ForbiddenCondition) >= 0 {
+		record.Status.SetCondition(&dnsv1alpha1.Condition{
+			Type:    dnsv1alpha1.ForbiddenCondition,
+			Status:  dnsv1alpha1.FalseStatus,
+			Reason:  "Allowed",
+			Message: "The access policy of the provider allows the DNSRecord",
+		})
+	}
+
+	// Step 6: Check the published record for drift
+	// ============================================
+
+	// Periodically check already published records, unless the provider cannot read them back
+	result := ctrl.Result{RequeueAfter: r.DriftCheckInterval}
+	repairing := false
+	if published {
+		actual, err := provider.GetRecord(ctx, zone, *resolved)
+		if errors.Is(err, types.ErrNotSupported) {
+			// Records taken from other resources are still refreshed periodically
+			if record.Spec.RRSet.ValueFrom == nil {
+				result = ctrl.Result{}
+			}
+		} else if err != nil {
+			log.Error(err, "Cannot read back published DNS record")
+			return ctrl.Result{}, err
+		} else if actual != nil && resolved.RRSetEquals(actual) {
+
+			// Nothing to do, the published record is the one we want
+			log.V(1).Info("Published record in sync")
+			record.Status.SetCondition(&dnsv1alpha1.Condition{
+				Type:    dnsv1alpha1.DriftedCondition,
+				Status:  dnsv1alpha1.FalseStatus,
+				Reason:  "InSync",
+				Message: "Published DNS record matches the desired one",
+			})
+			if err := r.Status().Update(ctx, &record); err != nil {
+				log.Error(err, "Cannot update resource status")
+				return ctrl.Result{}, err
+			}
+			return result, nil
+
+		} else {
+
+			// The published record has been changed outside of our control
+			message := "Published DNS record differs from the desired one"
+			if actual == nil {
+				message = "Published DNS record has been deleted"
+			}
+			log.Info("Detected drift of published record", "reason", message)
+			r.Context.EventRecorder.Event(&record, "Warning", "Drifted", message)
+
+			// Report the drift only, if the user asked so
+			if record.Spec.DriftPolicy != nil && *record.Spec.DriftPolicy == dnsv1alpha1.ReportPolicy {
+				record.Status.SetCondition(&dnsv1alpha1.Condition{
+					Type:    dnsv1alpha1.DriftedCondition,
+					Status:  dnsv1alpha1.TrueStatus,
+					Reason:  "Drifted",
+					Message: message,
+				})
+				if err := r.Status().Update(ctx, &record); err != nil {
+					log.Error(err, "Cannot update resource status")
+					return ctrl.Result{}, err
+				}
+				return result, nil
+			}
+
+			repairing = true
+
+		}
+	}
+
+	// Step 7: Update the DNS record
+	// =============================
+
 	// Let the magic happen
-	if err := provider.UpdateRecord(zone, record); err != nil {
+	if err := provider.UpdateRecord(ctx, zone, *resolved); errors.Is(err, types.ErrNotOwned) {
+
+		// Retrying will not help, the user has to fix the record or opt into adoption
+		log.Info("Refusing to update DNS record owned by someone else", "reason", err.Error())
+		r.Context.EventRecorder.Event(&record, "Warning", "NotOwned", err.Error())
+		record.Status.SetCondition(&dnsv1alpha1.Condition{
+			Type:    dnsv1alpha1.ReadyCondition,
+			Status:  dnsv1alpha1.FalseStatus,
+			Reason:  "NotOwned",
+			Message: err.Error(),
+		})
+		if err := r.Status().Update(ctx, &record); err != nil {
+			log.Error(err, "Cannot update resource status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+
+	} else if err != nil {
 		log.Error(err, "Cannot update update DNS record")
 		return ctrl.Result{}, err
 	}
 
 	// Mark the record as ready
+	record.Status.FQDN = &resolved.Spec.Name
+	record.Status.ObservedGeneration = record.Generation
+	record.Status.PublishedRRSet = nil
+	if record.Spec.RRSet.ValueFrom != nil {
+		record.Status.PublishedRRSet = &resolved.Spec.RRSet
+	}
 	record.Status.SetCondition(&dnsv1alpha1.Condition{
 		Type:    dnsv1alpha1.ReadyCondition,
 		Status:  dnsv1alpha1.TrueStatus,
 		Reason:  "Ready",
 		Message: "DNS record registered",
 	})
+	if repairing {
+		record.Status.SetCondition(&dnsv1alpha1.Condition{
+			Type:    dnsv1alpha1.DriftedCondition,
+			Status:  dnsv1alpha1.FalseStatus,
+			Reason:  "Repaired",
+			Message: "Published DNS record has been restored to the desired one",
+		})
+	} else if record.Status.GetCondition(dnsv1alpha1.DriftedCondition) >= 0 {
+		record.Status.SetCondition(&dnsv1alpha1.Condition{
+			Type:    dnsv1alpha1.DriftedCondition,
+			Status:  dnsv1alpha1.FalseStatus,
+			Reason:  "InSync",
+			Message: "Published DNS record matches the desired one",
+		})
+	}
 	if err := r.Status().Update(ctx, &record); err != nil {
 		log.Error(err, "Cannot update resource status")
 		return ctrl.Result{}, err
@@ -188,69 +454,145 @@ func (r *DNSRecordReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 	r.Context.EventRecorder.Event(&record, "Normal", "Registered", "DNS record correclty registered")
 
-	return ctrl.Result{}, nil
+	return result, nil
 }
 
-// listRecordsUsingProvider returns a list of the names of DNSRecords resources that reference the given DNSProvider.
+// listRecordsUsingProvider returns a list of the names of DNSRecords resources that reference the given
+// DNSProvider or ClusterDNSProvider.
 func (r *DNSRecordReconciler) listRecordsUsingProvider(provider handler.MapObject) []ctrl.Request {
+	kind := dnsv1alpha1.DNSProviderKind
+	if _, ok := provider.Object.(*dnsv1alpha1.ClusterDNSProvider); ok {
+		kind = dnsv1alpha1.ClusterDNSProviderKind
+	}
+	providerKey := dnsv1alpha1.ProviderKey(kind, provider.Meta.GetNamespace(), provider.Meta.GetName())
 
-	// Filter all the DNSRecords using the provider referencing the given provider by name.
-	// We do not check the namespace of the records because records can leave the namespace
-	// of the reference unspecified, and we cannot filter on un set fields.
-	//
-	// TODO: This can be solved using a defaulting webhook that automatically applies
-	// the correct DNSProvider ref namespace, so that we can index it.
+	// Records are indexed by the key of the provider they reference,
+	// which already takes into account references without a namespace.
 	listOptions := []client.ListOption{
-		client.MatchingField(".spec.providerRef.name", provider.Meta.GetName()),
+		client.MatchingField(ProviderIndex, providerKey),
 	}
 	var list dnsv1alpha1.DNSRecordList
-	if err := r.List(context.Background(), &list, listOptions...); err != nil {
+	if err := r.List(r.Context.RootContext, &list, listOptions...); err != nil {
 		r.Log.Error(
 			err,
 			"Cannot list DNSRecords impacted by a change to DNSProvider",
-			"dnsprovider", fmt.Sprintf("%s/%s", provider.Meta.GetNamespace(), provider.Meta.GetName()),
+			"dnsprovider", providerKey,
 		)
 		return nil
 	}
 
 	var res []ctrl.Request
 	for _, record := range list.Items {
+		res = append(res, ctrl.Request{
+			NamespacedName: k8stypes.NamespacedName{
+				Name:      record.Name,
+				Namespace: record.Namespace,
+			},
+		})
+	}
 
-		// By default, if the provider reference of this record does not include a namespace,
-		// use the same namespace of the record itself.
-		refName := record.Spec.ProviderRef.Name
-		refNamespace := record.Spec.ProviderRef.Namespace
-		if refNamespace == nil {
-			refNamespace = &record.Namespace
-		}
+	r.Log.V(1).Info(
+		"Enqueued reconciling of DNSRecord due to a change to DNSProvider",
+		"count", len(res),
+		"dnsprovider", providerKey,
+	)
+
+	return res
+}
+
+// listConflictingRecords returns the other DNSRecords claiming the same RRset of the given one,
+// so that they can take over the RRset when the given record changes or is deleted.
+// Records currently marked as conflicting are always included, since they might have been conflicting
+// with the previous RRset of the given record.
+func (r *DNSRecordReconciler) listConflictingRecords(obj handler.MapObject) []ctrl.Request {
+	record, ok := obj.Object.(*dnsv1alpha1.DNSRecord)
+	if !ok {
+		return nil
+	}
+
+	var list dnsv1alpha1.DNSRecordList
+	if err := r.List(r.Context.RootContext, &list, client.MatchingField(RRSetIndex, RRSetIndexKey(record))); err != nil {
+		r.Log.Error(
+			err,
+			"Cannot list DNSRecords conflicting with a changed DNSRecord",
+			"dnsrecord", fmt.Sprintf("%s/%s", record.Namespace, record.Name),
+		)
+		return nil
+	}
 
-		// Select this record for reconciling if the provider ref matches the changed provider
-		if refName == provider.Meta.GetName() && *refNamespace == provider.Meta.GetNamespace() {
+	key, _ := RRSetKey(r.Context, record)
+	var res []ctrl.Request
+	for _, other := range list.Items {
+		if other.Namespace == record.Namespace && other.Name == record.Name {
+			continue
+		}
+		otherKey, ok := RRSetKey(r.Context, &other)
+		i := other.Status.GetCondition(dnsv1alpha1.ConflictCondition)
+		if (ok && otherKey == key) || (i >= 0 && other.Status.Conditions[i].Status == dnsv1alpha1.TrueStatus) {
 			res = append(res, ctrl.Request{
 				NamespacedName: k8stypes.NamespacedName{
-					Name:      record.Name,
-					Namespace: record.Namespace,
+					Name:      other.Name,
+					Namespace: other.Namespace,
 				},
 			})
 		}
+	}
+	return res
+}
 
+// ResolveRecordName finds the zone of the provider the given record belongs to, and the fully qualified name of the record.
+//
+// Names which are fully qualified, or which already belong to one of the candidate zones, are considered absolute.
+// All the other names are relative, and are resolved against the zone selected with `.spec.zone`,
+// or against the only zone of the provider.
+func ResolveRecordName(zones []dnsname.Name, record *dnsv1alpha1.DNSRecord, zone *dnsname.Name, fqdn *dnsname.Name) error {
+	name := &record.Spec.Name
+
+	// Restrict the candidate zones to the selected one
+	candidates := zones
+	if record.Spec.Zone != nil {
+		candidates = nil
+		for _, z := range zones {
+			if z.Equal(record.Spec.Zone) {
+				candidates = append(candidates, z)
+			}
+		}
+		if len(candidates) == 0 {
+			return fmt.Errorf("Provider does not manage zone %s", record.Spec.Zone.String())
+		}
 	}
 
-	r.Log.V(1).Info(
-		"Enqueued reconciling of DNSRecord due to a change to DNSProvider",
-		"count", len(res),
-		"dnsprovider", fmt.Sprintf("%s/%s", provider.Meta.GetNamespace(), provider.Meta.GetName()),
-	)
+	// Absolute names
+	if !name.IsApex() && (name.IsFQDN() || getMatchingZone(candidates, *name, zone)) {
+		if !getMatchingZone(candidates, *name, zone) {
+			return fmt.Errorf("Provider does not support a zone matching record %s", name.String())
+		}
+		*fqdn = *name.ToFQDN()
+		return nil
+	}
+
+	// Relative names
+	if len(candidates) != 1 {
+		return fmt.Errorf("Relative record name %s is ambiguous since the provider manages more than one zone, use `zone` to select one", name.String())
+	}
+	*zone = candidates[0]
+	resolved, err := name.Resolve(zone)
+	if err != nil {
+		return err
+	}
+	*fqdn = *resolved
+	return nil
 
-	return res
 }
 
 func getMatchingZone(zones []dnsname.Name, record dnsname.Name, out *dnsname.Name) bool {
 
-	// Filter only the zones containing the target record
+	// Filter only the zones containing the target record.
+	// A wildcard cannot be the apex of a zone, so wildcard zones never match.
+	// Wildcard records instead belong to the zone containing the parent of their `*` label.
 	found := make([]dnsname.Name, 0, 1)
 	for _, z := range zones {
-		if record.IsChildOf(&z) {
+		if !z.IsWildcard() && record.IsSubdomainOf(&z) {
 			found = append(found, z)
 		}
 	}
@@ -259,10 +601,10 @@ func getMatchingZone(zones []dnsname.Name, record dnsname.Name, out *dnsname.Nam
 		return false
 	}
 
-	// Find the longest name
+	// Find the most specific zone, i.e. the one with more labels
 	longest := found[0]
 	for i, z := range found {
-		if i > 0 && len(longest.ToFQDN().String()) < len(z.ToFQDN().String()) {
+		if i > 0 && len(longest.Labels()) < len(z.Labels()) {
 			longest = z
 		}
 	}
@@ -275,19 +617,49 @@ func getMatchingZone(zones []dnsname.Name, record dnsname.Name, out *dnsname.Nam
 // SetupWithManager registers the DNSRecord controller with the given Manager.
 func (r *DNSRecordReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
-	// Index DNSRecords by the name of the provider they used
+	// Index DNSRecords by the provider they use
+	mgr.GetFieldIndexer().IndexField(
+		&dnsv1alpha1.DNSRecord{},
+		ProviderIndex,
+		func(obj runtime.Object) []string {
+			record := obj.(*dnsv1alpha1.DNSRecord)
+			if record.Spec.ProviderRef.Name == "" {
+				return nil
+			}
+			return []string{record.Spec.ProviderRef.Key(record.Namespace)}
+		})
+
+	// Restrict the resources which can be referenced by `fieldRef`
+	if r.Scheme == nil {
+		r.Scheme = mgr.GetScheme()
+	}
+	kinds := r.FieldRefKinds
+	if kinds == nil {
+		kinds = DefaultFieldRefKinds
+	}
+	r.fieldRefPolicy = &FieldRefPolicy{Kinds: kinds, Mapper: mgr.GetRESTMapper()}
+
+	// Index DNSRecords by the resource they take their contents from
 	mgr.GetFieldIndexer().IndexField(
 		&dnsv1alpha1.DNSRecord{},
-		".spec.providerRef.name",
+		valueFromIndex,
 		func(obj runtime.Object) []string {
-			providerName := obj.(*dnsv1alpha1.DNSRecord).Spec.ProviderRef.Name
-			if providerName == "" {
+			gvk, name, ok := r.valueFromReference(obj.(*dnsv1alpha1.DNSRecord))
+			if !ok {
 				return nil
 			}
-			return []string{providerName}
+			return []string{valueFromKey(gvk.GroupKind(), name.Namespace, name.Name)}
+		})
+
+	// Index DNSRecords by the provider and the type of the RRset they claim, to detect conflicts
+	mgr.GetFieldIndexer().IndexField(
+		&dnsv1alpha1.DNSRecord{},
+		RRSetIndex,
+		func(obj runtime.Object) []string {
+			return []string{RRSetIndexKey(obj.(*dnsv1alpha1.DNSRecord))}
 		})
 
-	return ctrl.NewControllerManagedBy(mgr).
+	c, err := ctrl.NewControllerManagedBy(mgr).
 		For(&dnsv1alpha1.DNSRecord{}).
 		Watches(
 			&source.Kind{Type: &dnsv1alpha1.DNSProvider{}},
@@ -295,6 +667,59 @@ func (r *DNSRecordReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				ToRequests: handler.ToRequestsFunc(r.listRecordsUsingProvider),
 			},
 		).
+		Watches(
+			&source.Kind{Type: &dnsv1alpha1.ClusterDNSProvider{}},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.listRecordsUsingProvider),
+			},
+		).
+		Watches(
+			&source.Kind{Type: &dnsv1alpha1.DNSRecord{}},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.listConflictingRecords),
+			},
+		).
 		WithEventFilter(predicate.GenerationChangedPredicate{}).
-		Complete(r)
+		Build(r)
+	if err != nil {
+		return err
+	}
+
+	// Watch the resources referenced by the records.
+	// These watches are not subject to the generation filter, since changes to the status are relevant.
+	watched := []runtime.Object{&corev1.Service{}, &corev1.Node{}, &corev1.ConfigMap{}}
+	for _, kind := range r.fieldRefPolicy.Kinds {
+		gvk, err := r.fieldRefPolicy.resolveKind(kind)
+		if err != nil {
+			return err
+		}
+		if kind != serviceKind && kind != configMapKind {
+			watched = append(watched, r.newObject(gvk))
+		}
+	}
+	for _, obj := range watched {
+		gvk, err := apiutil.GVKForObject(obj, mgr.GetScheme())
+		if err != nil {
+			return err
+		}
+		var predicates []predicate.Predicate
+		if gvk.GroupKind() == nodeKind {
+			predicates = append(predicates, predicate.Funcs{UpdateFunc: nodeChanged})
+		}
+		err = c.Watch(
+			&source.Kind{Type: obj},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: r.listRecordsReferencing(gvk.GroupKind())},
+			predicates...,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Changes to the labels of a namespace can change the outcome of the access policies of the providers
+	return c.Watch(
+		&source.Kind{Type: &corev1.Namespace{}},
+		&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.listRecordsInNamespace)},
+		predicate.Funcs{UpdateFunc: namespaceLabelsChanged},
+	)
 }
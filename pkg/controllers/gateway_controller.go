@@ -0,0 +1,561 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	helpers "github.com/95ulisse/dns-operator/pkg/helpers"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+// The Gateway API is not part of the Kubernetes API, so its resources are handled as unstructured objects,
+// and only the fields needed to generate the DNSRecords are decoded.
+var (
+	// GatewayKind is the kind of the Gateway API Gateways.
+	GatewayKind = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "Gateway"}
+
+	// RouteKinds are the kinds of the Gateway API routes whose hostnames are published.
+	RouteKinds = []schema.GroupVersionKind{
+		{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"},
+		{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "GRPCRoute"},
+		{Group: "gateway.networking.k8s.io", Version: "v1alpha2", Kind: "TLSRoute"},
+	}
+)
+
+// gateway contains the fields of a Gateway used to generate DNSRecords.
+type gateway struct {
+	Spec struct {
+		Listeners []struct {
+			Name     string  `json:"name"`
+			Hostname *string `json:"hostname,omitempty"`
+		} `json:"listeners"`
+	} `json:"spec"`
+	Status struct {
+		Addresses []struct {
+			Type  *string `json:"type,omitempty"`
+			Value string  `json:"value"`
+		} `json:"addresses,omitempty"`
+	} `json:"status,omitempty"`
+}
+
+// addresses returns the IPs and the hostnames the gateway is reachable at.
+func (gw *gateway) addresses() (ips []string, hostnames []string) {
+	for _, address := range gw.Status.Addresses {
+		if address.Type != nil && *address.Type == "Hostname" {
+			hostnames = append(hostnames, address.Value)
+		} else if address.Type == nil || *address.Type == "IPAddress" {
+			ips = append(ips, address.Value)
+		}
+	}
+	return
+}
+
+// publishedHostnames returns the hostnames published by the gateway itself:
+// the ones of its listeners, plus the given additional ones from its annotations.
+func (gw *gateway) publishedHostnames(additional []dnsname.Name) []dnsname.Name {
+	var res []dnsname.Name
+	for i := range additional {
+		res = append(res, *additional[i].ToFQDN())
+	}
+	for _, listener := range gw.Spec.Listeners {
+		if listener.Hostname == nil || *listener.Hostname == "" {
+			continue
+		}
+		name, err := dnsname.NewName(*listener.Hostname)
+		if err != nil {
+			continue
+		}
+		res = append(res, *name.ToFQDN())
+	}
+	return uniqueNames(res)
+}
+
+// parentReference is a reference from a route to the Gateway it attaches to.
+type parentReference struct {
+	Group       *string `json:"group,omitempty"`
+	Kind        *string `json:"kind,omitempty"`
+	Namespace   *string `json:"namespace,omitempty"`
+	Name        string  `json:"name"`
+	SectionName *string `json:"sectionName,omitempty"`
+}
+
+// gatewayName returns the name of the referenced Gateway, or false if the parent is not a Gateway.
+func (ref *parentReference) gatewayName(routeNamespace string) (k8stypes.NamespacedName, bool) {
+	if (ref.Group != nil && *ref.Group != GatewayKind.Group) || (ref.Kind != nil && *ref.Kind != GatewayKind.Kind) {
+		return k8stypes.NamespacedName{}, false
+	}
+	namespace := routeNamespace
+	if ref.Namespace != nil {
+		namespace = *ref.Namespace
+	}
+	return k8stypes.NamespacedName{Namespace: namespace, Name: ref.Name}, true
+}
+
+// route contains the fields shared by all the kinds of routes used to generate DNSRecords.
+type route struct {
+	Spec struct {
+		ParentRefs []parentReference `json:"parentRefs,omitempty"`
+		Hostnames  []string          `json:"hostnames,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		Parents []struct {
+			ParentRef  parentReference `json:"parentRef"`
+			Conditions []struct {
+				Type   string `json:"type"`
+				Status string `json:"status"`
+			} `json:"conditions,omitempty"`
+		} `json:"parents,omitempty"`
+	} `json:"status,omitempty"`
+}
+
+// gatewayParent is a Gateway which accepted a route, optionally restricted to a single listener.
+type gatewayParent struct {
+	Name        k8stypes.NamespacedName
+	SectionName *string
+}
+
+// acceptedGateways returns the Gateways, and their listeners, which accepted the route.
+func (r *route) acceptedGateways(routeNamespace string) []gatewayParent {
+	var res []gatewayParent
+	for _, parent := range r.Status.Parents {
+		name, ok := parent.ParentRef.gatewayName(routeNamespace)
+		if !ok {
+			continue
+		}
+		for _, condition := range parent.Conditions {
+			if condition.Type == "Accepted" && condition.Status == "True" {
+				res = append(res, gatewayParent{Name: name, SectionName: parent.ParentRef.SectionName})
+				break
+			}
+		}
+	}
+	return res
+}
+
+// routeHostnames returns the hostnames of a route served by the listeners of a gateway,
+// i.e. the intersection of the hostnames of the route with the ones of the listeners, following the Gateway API rules.
+// When a section name is given, only the listener with that name is considered.
+// Routes without hostnames inherit the ones of the listeners, which are already published by the gateway,
+// so nothing is returned for them.
+func (gw *gateway) routeHostnames(sectionName *string, hostnames []dnsname.Name) []dnsname.Name {
+	var res []dnsname.Name
+	for _, listener := range gw.Spec.Listeners {
+		if sectionName != nil && *sectionName != listener.Name {
+			continue
+		}
+		var listenerHostname *dnsname.Name
+		if listener.Hostname != nil && *listener.Hostname != "" {
+			name, err := dnsname.NewName(*listener.Hostname)
+			if err != nil {
+				continue
+			}
+			listenerHostname = name.ToFQDN()
+		}
+		for i := range hostnames {
+			if name := intersectHostnames(listenerHostname, &hostnames[i]); name != nil {
+				res = append(res, *name)
+			}
+		}
+	}
+	return uniqueNames(res)
+}
+
+// intersectHostnames returns the most specific hostname matched by both the hostname of a listener and the one of a route,
+// or nil if they do not match. A listener without hostname matches any hostname.
+func intersectHostnames(listener *dnsname.Name, route *dnsname.Name) *dnsname.Name {
+	switch {
+	case listener == nil || listener.Equal(route):
+		return route
+	case listener.IsWildcard() && wildcardMatches(listener, route):
+		return route
+	case route.IsWildcard() && wildcardMatches(route, listener):
+		return listener
+	default:
+		return nil
+	}
+}
+
+// wildcardMatches tells whether a wildcard hostname matches the given one.
+// As in the Gateway API, the wildcard label matches one or more labels,
+// so `*.example.com` matches `a.b.example.com`, but not `example.com`.
+func wildcardMatches(wildcard *dnsname.Name, name *dnsname.Name) bool {
+	suffix := wildcard.Parent()
+	return !name.Equal(suffix) && name.IsSubdomainOf(suffix)
+}
+
+// newUnstructured returns an empty unstructured object of the given kind.
+func newUnstructured(gvk schema.GroupVersionKind) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	return obj
+}
+
+// decodeUnstructured decodes the fields of an unstructured object into `out`.
+func decodeUnstructured(obj *unstructured.Unstructured, out interface{}) error {
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), out)
+}
+
+// IsKindInstalled tells whether the API server serves the given kind, e.g. because its CRD is installed.
+func IsKindInstalled(mgr ctrl.Manager, gvk schema.GroupVersionKind) (bool, error) {
+	_, err := mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GatewayReconciler generates DNSRecords for the listener hostnames of annotated Gateway API Gateways,
+// pointing to the addresses of the Gateway.
+type GatewayReconciler struct {
+	client.Client
+	Log     logr.Logger
+	Scheme  *runtime.Scheme
+	Context *types.ControllerContext
+}
+
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways;httproutes;grpcroutes;tlsroutes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=dns.k8s.marcocameriero.net,resources=dnsrecords,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile performs an iteration of the reconcile loop for a Gateway.
+func (r *GatewayReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := r.Context.RootContext
+	log := r.Log.WithValues("gateway", req.NamespacedName)
+
+	log.V(1).Info("Starting reconcile loop")
+	defer log.V(1).Info("Finish reconcile loop")
+
+	// Retrieve the gateway by name.
+	// Records of deleted gateways are garbage collected by Kubernetes, since they are owned by the gateway.
+	obj := newUnstructured(GatewayKind)
+	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "Unable to fetch Gateway")
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	var gw gateway
+	if err := decodeUnstructured(obj, &gw); err != nil {
+		log.Error(err, "Cannot decode Gateway")
+		return ctrl.Result{}, nil
+	}
+
+	// Only gateways referencing a provider get records
+	var desired []dnsv1alpha1.DNSRecord
+	if _, ok := obj.GetAnnotations()[providerAnnotation]; ok {
+
+		settings, err := parseRecordAnnotations(obj, nil)
+		if err != nil {
+			log.Info("Invalid annotations", "reason", err.Error())
+			r.Context.EventRecorder.Event(obj, "Warning", "InvalidAnnotations", err.Error())
+			return ctrl.Result{}, nil
+		}
+
+		// Publish the hostnames of the listeners, and the ones in the annotations,
+		// as long as they belong to the zones of the provider
+		providerKey := settings.providerRef.Key(obj.GetNamespace())
+		var provider types.Provider
+		if !r.Context.GetProvider(providerKey, &provider) {
+			err := fmt.Errorf("Cannot find DNSProvider %s", providerKey)
+			log.Error(err, "Cannot update DNSRecords for Gateway")
+			return ctrl.Result{}, err
+		}
+		hostnames, outside := zoneHostnames(provider.Zones(), gw.publishedHostnames(settings.hostnames))
+		for _, hostname := range outside {
+			log.V(1).Info("Skipping hostname outside of the zones of the provider", "hostname", hostname.String())
+		}
+
+		ips, lbHostnames := gw.addresses()
+		for _, hostname := range hostnames {
+			records, err := addressRecords(obj, settings, hostname, ips, lbHostnames)
+			if err != nil {
+				log.Error(err, "Cannot build DNSRecords for Gateway")
+				return ctrl.Result{}, nil
+			}
+			desired = append(desired, records...)
+		}
+
+	}
+
+	// Synchronize the records owned by the gateway
+	if err := syncOwnedRecords(ctx, r.Client, r.Scheme, r.Context.EventRecorder, GatewayKind.Kind, obj, desired); err != nil {
+		log.Error(err, "Cannot update DNSRecords for Gateway")
+		return ctrl.Result{}, err
+	}
+
+	log.V(1).Info("Updated DNSRecords for Gateway", "count", len(desired))
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager registers the Gateway controller with the given Manager.
+func (r *GatewayReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(newUnstructured(GatewayKind)).
+		Owns(&dnsv1alpha1.DNSRecord{}).
+		Complete(r)
+}
+
+// RouteReconciler generates DNSRecords for the hostnames of Gateway API routes of a specific kind,
+// pointing to the addresses of the annotated Gateways which accepted them.
+//
+// Records use the DNSProvider referenced by the route itself, or the one referenced by the Gateway.
+type RouteReconciler struct {
+	client.Client
+	Log     logr.Logger
+	Scheme  *runtime.Scheme
+	Context *types.ControllerContext
+
+	// Kind of the routes handled by this reconciler.
+	Kind schema.GroupVersionKind
+}
+
+// Reconcile performs an iteration of the reconcile loop for a route.
+func (r *RouteReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := r.Context.RootContext
+	log := r.Log.WithValues("route", req.NamespacedName)
+
+	log.V(1).Info("Starting reconcile loop")
+	defer log.V(1).Info("Finish reconcile loop")
+
+	// Retrieve the route by name.
+	// Records of deleted routes are garbage collected by Kubernetes, since they are owned by the route.
+	obj := newUnstructured(r.Kind)
+	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "Unable to fetch route")
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	var rt route
+	if err := decodeUnstructured(obj, &rt); err != nil {
+		log.Error(err, "Cannot decode route")
+		return ctrl.Result{}, nil
+	}
+
+	var routeHostnames []dnsname.Name
+	for _, h := range rt.Spec.Hostnames {
+		name, err := dnsname.NewName(h)
+		if err != nil {
+			log.Info("Invalid route hostname", "reason", err.Error())
+			continue
+		}
+		routeHostnames = append(routeHostnames, *name.ToFQDN())
+	}
+
+	// Collect the addresses of the annotated gateways which accepted the route,
+	// and the hostnames of the route served by each of them.
+	// The provider of the route, if any, takes precedence over the one of the gateways.
+	// Hostnames already published by one of the gateways are left to the gateway,
+	// since two records cannot claim the same RRset.
+	var providerRef *dnsv1alpha1.ProviderReference
+	var all targets
+	var hostnames []dnsname.Name
+	hostnameTargets := make(map[string]*targets)
+	published := make(map[string]bool)
+	for _, parent := range rt.acceptedGateways(obj.GetNamespace()) {
+		gwObj := newUnstructured(GatewayKind)
+		if err := r.Get(ctx, parent.Name, gwObj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			log.Error(err, "Unable to fetch Gateway", "gateway", parent.Name)
+			return ctrl.Result{}, err
+		}
+		provider, ok := gwObj.GetAnnotations()[providerAnnotation]
+		if !ok {
+			continue
+		}
+		ref, err := parseProviderRef(provider)
+		if err != nil {
+			continue
+		}
+		if ref.Namespace == nil {
+			ref.Namespace = &parent.Name.Namespace
+		}
+		if providerRef == nil {
+			providerRef = &ref
+		}
+
+		var gw gateway
+		if err := decodeUnstructured(gwObj, &gw); err != nil {
+			log.Error(err, "Cannot decode Gateway", "gateway", parent.Name)
+			continue
+		}
+		gwAnnotationHostnames, _ := parseHostnames(gwObj.GetAnnotations()[hostnameAnnotation])
+		for _, hostname := range gw.publishedHostnames(gwAnnotationHostnames) {
+			published[strings.ToLower(hostname.String())] = true
+		}
+		gwIPs, gwHostnames := gw.addresses()
+		all.add(gwIPs, gwHostnames)
+		for _, hostname := range gw.routeHostnames(parent.SectionName, routeHostnames) {
+			key := strings.ToLower(hostname.String())
+			if _, ok := hostnameTargets[key]; !ok {
+				hostnameTargets[key] = &targets{}
+				hostnames = append(hostnames, hostname)
+			}
+			hostnameTargets[key].add(gwIPs, gwHostnames)
+		}
+	}
+
+	var desired []dnsv1alpha1.DNSRecord
+	if providerRef != nil {
+
+		settings, err := parseRecordAnnotations(obj, providerRef)
+		if err != nil {
+			log.Info("Invalid annotations", "reason", err.Error())
+			r.Context.EventRecorder.Event(obj, "Warning", "InvalidAnnotations", err.Error())
+			return ctrl.Result{}, nil
+		}
+
+		// The hostnames in the annotations point to all the gateways
+		for _, hostname := range settings.hostnames {
+			key := strings.ToLower(hostname.ToFQDN().String())
+			if _, ok := hostnameTargets[key]; !ok {
+				hostnameTargets[key] = &targets{}
+				hostnames = append(hostnames, *hostname.ToFQDN())
+			}
+			hostnameTargets[key].add(all.ips, all.hostnames)
+		}
+
+		// Only the hostnames belonging to the zones of the provider can be published
+		providerKey := settings.providerRef.Key(obj.GetNamespace())
+		var provider types.Provider
+		if !r.Context.GetProvider(providerKey, &provider) {
+			err := fmt.Errorf("Cannot find DNSProvider %s", providerKey)
+			log.Error(err, "Cannot update DNSRecords for route")
+			return ctrl.Result{}, err
+		}
+		inside, outside := zoneHostnames(provider.Zones(), hostnames)
+		for _, hostname := range outside {
+			log.V(1).Info("Skipping hostname outside of the zones of the provider", "hostname", hostname.String())
+		}
+
+		for _, hostname := range inside {
+			key := strings.ToLower(hostname.String())
+			if published[key] {
+				log.V(1).Info("Skipping hostname already published by the Gateway", "hostname", hostname.String())
+				continue
+			}
+			t := hostnameTargets[key]
+			records, err := addressRecords(obj, settings, hostname, t.ips, t.hostnames)
+			if err != nil {
+				log.Error(err, "Cannot build DNSRecords for route")
+				return ctrl.Result{}, nil
+			}
+			desired = append(desired, records...)
+		}
+
+	}
+
+	// Synchronize the records owned by the route
+	if err := syncOwnedRecords(ctx, r.Client, r.Scheme, r.Context.EventRecorder, r.Kind.Kind, obj, desired); err != nil {
+		log.Error(err, "Cannot update DNSRecords for route")
+		return ctrl.Result{}, err
+	}
+
+	log.V(1).Info("Updated DNSRecords for route", "count", len(desired))
+
+	return ctrl.Result{}, nil
+}
+
+// listRoutesOfGateway returns the routes attached to the given Gateway,
+// so that their records follow the changes to the addresses of the gateway.
+func (r *RouteReconciler) listRoutesOfGateway(obj handler.MapObject) []ctrl.Request {
+	gatewayName := k8stypes.NamespacedName{Namespace: obj.Meta.GetNamespace(), Name: obj.Meta.GetName()}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(r.Kind.GroupVersion().WithKind(r.Kind.Kind + "List"))
+	if err := r.List(r.Context.RootContext, list); err != nil {
+		r.Log.Error(err, "Cannot list routes impacted by a change to Gateway", "gateway", gatewayName)
+		return nil
+	}
+
+	var res []ctrl.Request
+	for i := range list.Items {
+		var rt route
+		if err := decodeUnstructured(&list.Items[i], &rt); err != nil {
+			continue
+		}
+		for _, ref := range rt.Spec.ParentRefs {
+			if name, ok := ref.gatewayName(list.Items[i].GetNamespace()); ok && name == gatewayName {
+				res = append(res, ctrl.Request{
+					NamespacedName: k8stypes.NamespacedName{
+						Name:      list.Items[i].GetName(),
+						Namespace: list.Items[i].GetNamespace(),
+					},
+				})
+				break
+			}
+		}
+	}
+	return res
+}
+
+// SetupWithManager registers the route controller with the given Manager.
+func (r *RouteReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Kind.Empty() {
+		return fmt.Errorf("Kind of the routes is required")
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(newUnstructured(r.Kind)).
+		Owns(&dnsv1alpha1.DNSRecord{}).
+		Watches(
+			&source.Kind{Type: newUnstructured(GatewayKind)},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.listRoutesOfGateway),
+			},
+		).
+		Complete(r)
+}
+
+// targets are the addresses a hostname points to.
+type targets struct {
+	ips       []string
+	hostnames []string
+}
+
+// add adds the given addresses, skipping the ones already present.
+func (t *targets) add(ips []string, hostnames []string) {
+	for _, ip := range ips {
+		if !helpers.ContainsString(t.ips, ip) {
+			t.ips = append(t.ips, ip)
+		}
+	}
+	for _, hostname := range hostnames {
+		if !helpers.ContainsString(t.hostnames, hostname) {
+			t.hostnames = append(t.hostnames, hostname)
+		}
+	}
+}
+
+// uniqueNames removes the duplicates from a list of names.
+func uniqueNames(names []dnsname.Name) []dnsname.Name {
+	var res []dnsname.Name
+	for i := range names {
+		found := false
+		for j := range res {
+			if res[j].Equal(&names[i]) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			res = append(res, names[i])
+		}
+	}
+	return res
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+)
+
+func TestAcceptedGateways(t *testing.T) {
+	require := require.New(t)
+
+	parent := func(ref map[string]interface{}, accepted string) interface{} {
+		return map[string]interface{}{
+			"parentRef":  ref,
+			"conditions": []interface{}{map[string]interface{}{"type": "Accepted", "status": accepted}},
+		}
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{
+			"parents": []interface{}{
+				parent(map[string]interface{}{"name": "local"}, "True"),
+				parent(map[string]interface{}{"name": "remote", "namespace": "other", "sectionName": "https"}, "True"),
+				parent(map[string]interface{}{"name": "rejected"}, "False"),
+				parent(map[string]interface{}{"name": "service", "kind": "Service", "group": ""}, "True"),
+				parent(map[string]interface{}{"name": "explicit", "kind": "Gateway", "group": "gateway.networking.k8s.io"}, "True"),
+			},
+		},
+	}}
+	var rt route
+	require.Nil(decodeUnstructured(obj, &rt))
+
+	https := "https"
+	require.Equal([]gatewayParent{
+		{Name: k8stypes.NamespacedName{Namespace: "ns", Name: "local"}},
+		{Name: k8stypes.NamespacedName{Namespace: "other", Name: "remote"}, SectionName: &https},
+		{Name: k8stypes.NamespacedName{Namespace: "ns", Name: "explicit"}},
+	}, rt.acceptedGateways("ns"))
+}
+
+func TestIntersectHostnames(t *testing.T) {
+	require := require.New(t)
+
+	table := []struct {
+		listener string
+		route    string
+		expected string
+	}{
+		{"", "www.example.com", "www.example.com"},
+		{"www.example.com", "WWW.example.com", "WWW.example.com"},
+		{"www.example.com", "api.example.com", ""},
+		{"*.example.com", "www.example.com", "www.example.com"},
+		{"*.example.com", "a.b.example.com", "a.b.example.com"},
+		{"*.example.com", "example.com", ""},
+		{"*.example.com", "www.example.net", ""},
+		{"www.example.com", "*.example.com", "www.example.com"},
+		{"example.com", "*.example.com", ""},
+		{"*.example.com", "*.api.example.com", "*.api.example.com"},
+		{"*.api.example.com", "*.example.com", "*.api.example.com"},
+		{"*.example.com", "*.example.com", "*.example.com"},
+	}
+
+	for _, entry := range table {
+		var listener *dnsname.Name
+		if entry.listener != "" {
+			name := mustName(entry.listener)
+			listener = &name
+		}
+		route := mustName(entry.route)
+		actual := intersectHostnames(listener, &route)
+		if entry.expected == "" {
+			require.Nil(actual, "Listener: %s, Route: %s", entry.listener, entry.route)
+		} else {
+			require.NotNil(actual, "Listener: %s, Route: %s", entry.listener, entry.route)
+			require.Equal(entry.expected, actual.String(), "Listener: %s, Route: %s", entry.listener, entry.route)
+		}
+	}
+}
+
+func TestRouteHostnames(t *testing.T) {
+	require := require.New(t)
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"listeners": []interface{}{
+				map[string]interface{}{"name": "http", "hostname": "*.example.com"},
+				map[string]interface{}{"name": "https", "hostname": "secure.example.net"},
+			},
+		},
+	}}
+	var gw gateway
+	require.Nil(decodeUnstructured(obj, &gw))
+
+	https := "https"
+	other := "other"
+	hostnames := []dnsname.Name{mustName("www.example.com."), mustName("*.example.net."), mustName("www.example.org.")}
+	table := []struct {
+		sectionName *string
+		expected    []string
+	}{
+		{nil, []string{"www.example.com.", "secure.example.net."}},
+		{&https, []string{"secure.example.net."}},
+		{&other, nil},
+	}
+
+	for _, entry := range table {
+		var actual []string
+		for _, name := range gw.routeHostnames(entry.sectionName, hostnames) {
+			actual = append(actual, name.String())
+		}
+		require.Equal(entry.expected, actual, "Section: %v", entry.sectionName)
+	}
+
+	// Routes without hostnames inherit the ones of the listeners
+	require.Empty(gw.routeHostnames(nil, nil))
+}
+
+func TestGatewayPublishedHostnames(t *testing.T) {
+	require := require.New(t)
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"listeners": []interface{}{
+				map[string]interface{}{"name": "http", "hostname": "*.example.com"},
+				map[string]interface{}{"name": "https", "hostname": "secure.example.net"},
+				map[string]interface{}{"name": "other"},
+				map[string]interface{}{"name": "again", "hostname": "SECURE.example.net."},
+			},
+		},
+	}}
+	var gw gateway
+	require.Nil(decodeUnstructured(obj, &gw))
+
+	// Hostnames from the annotations and from the listeners are fully qualified and without duplicates
+	var actual []string
+	for _, name := range gw.publishedHostnames([]dnsname.Name{mustName("api.example.com"), mustName("secure.example.net.")}) {
+		actual = append(actual, name.String())
+	}
+	require.Equal([]string{"api.example.com.", "secure.example.net.", "*.example.com."}, actual)
+}
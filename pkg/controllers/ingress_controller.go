@@ -0,0 +1,294 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	helpers "github.com/95ulisse/dns-operator/pkg/helpers"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+const (
+	// ingressClassAnnotation is the deprecated annotation used to select the ingress controller implementing an Ingress,
+	// superseded by `spec.ingressClassName`.
+	ingressClassAnnotation = "kubernetes.io/ingress.class"
+
+	// defaultIngressClassAnnotation marks the IngressClass assigned to the Ingresses which do not specify one.
+	defaultIngressClassAnnotation = "ingressclass.kubernetes.io/is-default-class"
+)
+
+// The vendored Kubernetes API predates networking.k8s.io/v1, so Ingresses and IngressClasses are handled
+// as unstructured objects, and only the fields needed to generate the DNSRecords are decoded.
+var (
+	// IngressKinds are the supported versions of Ingress, in order of preference.
+	IngressKinds = []schema.GroupVersionKind{
+		{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"},
+		{Group: "networking.k8s.io", Version: "v1beta1", Kind: "Ingress"},
+	}
+
+	// IngressClassKinds are the supported versions of IngressClass, in order of preference.
+	IngressClassKinds = []schema.GroupVersionKind{
+		{Group: "networking.k8s.io", Version: "v1", Kind: "IngressClass"},
+		{Group: "networking.k8s.io", Version: "v1beta1", Kind: "IngressClass"},
+	}
+)
+
+// ingress contains the fields of an Ingress used to generate DNSRecords.
+// They are the same in networking.k8s.io/v1 and networking.k8s.io/v1beta1.
+type ingress struct {
+	Spec struct {
+		IngressClassName *string `json:"ingressClassName,omitempty"`
+		Rules            []struct {
+			Host string `json:"host,omitempty"`
+		} `json:"rules,omitempty"`
+		TLS []struct {
+			Hosts []string `json:"hosts,omitempty"`
+		} `json:"tls,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		LoadBalancer corev1.LoadBalancerStatus `json:"loadBalancer,omitempty"`
+	} `json:"status,omitempty"`
+}
+
+// IngressReconciler generates DNSRecords for the hosts of Ingresses, pointing to the load balancer of the ingress controller.
+//
+// Ingresses opt in either by referencing a DNSProvider with an annotation,
+// or by belonging to one of the configured ingress classes, in which case the default DNSProvider is used.
+type IngressReconciler struct {
+	client.Client
+	Log     logr.Logger
+	Scheme  *runtime.Scheme
+	Context *types.ControllerContext
+
+	// Ingress classes whose Ingresses get DNSRecords even without annotations.
+	IngressClasses []string
+
+	// DNSProvider used for the Ingresses which do not reference one with an annotation,
+	// in the form `namespace/name`.
+	DefaultProvider string
+
+	defaultProvider *dnsv1alpha1.ProviderReference
+	kind            schema.GroupVersionKind
+	classKind       *schema.GroupVersionKind
+}
+
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses;ingressclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=dns.k8s.marcocameriero.net,resources=dnsrecords,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile performs an iteration of the reconcile loop for an Ingress.
+func (r *IngressReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := r.Context.RootContext
+	log := r.Log.WithValues("ingress", req.NamespacedName)
+
+	log.V(1).Info("Starting reconcile loop")
+	defer log.V(1).Info("Finish reconcile loop")
+
+	// Retrieve the ingress by name.
+	// Records of deleted ingresses are garbage collected by Kubernetes, since they are owned by the ingress.
+	obj := newUnstructured(r.kind)
+	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "Unable to fetch Ingress")
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	var ing ingress
+	if err := decodeUnstructured(obj, &ing); err != nil {
+		log.Error(err, "Cannot decode Ingress")
+		return ctrl.Result{}, nil
+	}
+
+	defaultClass, err := r.defaultIngressClass()
+	if err != nil {
+		log.Error(err, "Cannot list IngressClasses")
+		return ctrl.Result{}, err
+	}
+
+	var desired []dnsv1alpha1.DNSRecord
+	if r.selected(obj.GetAnnotations(), &ing, defaultClass) {
+
+		settings, err := parseRecordAnnotations(obj, r.defaultProvider)
+		if err != nil {
+			log.Info("Invalid annotations", "reason", err.Error())
+			r.Context.EventRecorder.Event(obj, "Warning", "InvalidAnnotations", err.Error())
+			return ctrl.Result{}, nil
+		}
+
+		// Only the hosts belonging to the zones of the provider can be published
+		providerKey := settings.providerRef.Key(obj.GetNamespace())
+		var provider types.Provider
+		if !r.Context.GetProvider(providerKey, &provider) {
+			err := fmt.Errorf("Cannot find DNSProvider %s", providerKey)
+			log.Error(err, "Cannot update DNSRecords for Ingress")
+			return ctrl.Result{}, err
+		}
+
+		hosts, err := ingressHosts(&ing, settings.hostnames)
+		if err != nil {
+			log.Info("Invalid host", "reason", err.Error())
+			r.Context.EventRecorder.Event(obj, "Warning", "InvalidHost", err.Error())
+			return ctrl.Result{}, nil
+		}
+
+		// Point the hosts to the load balancer of the ingress controller
+		var ips, hostnames []string
+		for _, lb := range ing.Status.LoadBalancer.Ingress {
+			if lb.IP != "" {
+				ips = append(ips, lb.IP)
+			}
+			if lb.Hostname != "" {
+				hostnames = append(hostnames, lb.Hostname)
+			}
+		}
+		for _, host := range hosts {
+			var zone dnsname.Name
+			if !getMatchingZone(provider.Zones(), host, &zone) {
+				log.V(1).Info("Skipping host outside of the zones of the provider", "host", host.String())
+				continue
+			}
+			records, err := addressRecords(obj, settings, host, ips, hostnames)
+			if err != nil {
+				log.Error(err, "Cannot build DNSRecords for Ingress")
+				return ctrl.Result{}, nil
+			}
+			desired = append(desired, records...)
+		}
+
+	}
+
+	// Synchronize the records owned by the ingress
+	if err := syncOwnedRecords(ctx, r.Client, r.Scheme, r.Context.EventRecorder, r.kind.Kind, obj, desired); err != nil {
+		log.Error(err, "Cannot update DNSRecords for Ingress")
+		return ctrl.Result{}, err
+	}
+
+	log.V(1).Info("Updated DNSRecords for Ingress", "count", len(desired))
+
+	return ctrl.Result{}, nil
+}
+
+// selected tells whether DNSRecords should be generated for the given ingress.
+// Ingresses without a class belong to the default class, if any.
+func (r *IngressReconciler) selected(annotations map[string]string, ing *ingress, defaultClass string) bool {
+	if _, ok := annotations[providerAnnotation]; ok {
+		return true
+	}
+	class := annotations[ingressClassAnnotation]
+	if ing.Spec.IngressClassName != nil && *ing.Spec.IngressClassName != "" {
+		class = *ing.Spec.IngressClassName
+	}
+	if class == "" {
+		class = defaultClass
+	}
+	return r.defaultProvider != nil && class != "" && helpers.ContainsString(r.IngressClasses, class)
+}
+
+// defaultIngressClass returns the name of the IngressClass marked as default,
+// or an empty string if there is none or IngressClasses are not supported by the cluster.
+func (r *IngressReconciler) defaultIngressClass() (string, error) {
+	if r.classKind == nil || r.defaultProvider == nil {
+		return "", nil
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(r.classKind.GroupVersion().WithKind(r.classKind.Kind + "List"))
+	if err := r.List(r.Context.RootContext, list); err != nil {
+		return "", err
+	}
+	for _, class := range list.Items {
+		if class.GetAnnotations()[defaultIngressClassAnnotation] == "true" {
+			return class.GetName(), nil
+		}
+	}
+	return "", nil
+}
+
+// ingressHosts returns the fully qualified hosts of the rules and of the TLS configuration of an Ingress,
+// plus the given additional ones, without duplicates.
+func ingressHosts(ing *ingress, additional []dnsname.Name) ([]dnsname.Name, error) {
+	var hosts []string
+	for _, rule := range ing.Spec.Rules {
+		hosts = append(hosts, rule.Host)
+	}
+	for _, tls := range ing.Spec.TLS {
+		hosts = append(hosts, tls.Hosts...)
+	}
+
+	var res []dnsname.Name
+	seen := make(map[string]bool)
+	add := func(name *dnsname.Name) {
+		key := strings.ToLower(name.ToFQDN().String())
+		if !seen[key] {
+			seen[key] = true
+			res = append(res, *name.ToFQDN())
+		}
+	}
+	for _, host := range hosts {
+		if host == "" {
+			continue
+		}
+		name, err := dnsname.NewName(host)
+		if err != nil {
+			return nil, err
+		}
+		add(name)
+	}
+	for i := range additional {
+		add(&additional[i])
+	}
+	return res, nil
+}
+
+// firstInstalledKind returns the first of the given kinds served by the API server.
+func firstInstalledKind(mgr ctrl.Manager, kinds []schema.GroupVersionKind) (*schema.GroupVersionKind, error) {
+	for i := range kinds {
+		installed, err := IsKindInstalled(mgr, kinds[i])
+		if err != nil {
+			return nil, err
+		}
+		if installed {
+			return &kinds[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// SetupWithManager registers the Ingress controller with the given Manager,
+// watching the most recent version of Ingress served by the cluster.
+func (r *IngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.DefaultProvider != "" {
+		ref, err := parseProviderRef(r.DefaultProvider)
+		if err != nil {
+			return err
+		}
+		r.defaultProvider = &ref
+	}
+
+	kind, err := firstInstalledKind(mgr, IngressKinds)
+	if err != nil {
+		return err
+	}
+	if kind == nil {
+		return fmt.Errorf("No supported version of Ingress is served by the cluster")
+	}
+	r.kind = *kind
+	if r.classKind, err = firstInstalledKind(mgr, IngressClassKinds); err != nil {
+		return err
+	}
+	r.Log.Info("Watching Ingresses", "version", r.kind.GroupVersion().String())
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(newUnstructured(r.kind)).
+		Owns(&dnsv1alpha1.DNSRecord{}).
+		Complete(r)
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+)
+
+func newIngress(t *testing.T, obj map[string]interface{}) *ingress {
+	var ing ingress
+	require.Nil(t, decodeUnstructured(&unstructured.Unstructured{Object: obj}, &ing))
+	return &ing
+}
+
+func TestIngressHosts(t *testing.T) {
+	require := require.New(t)
+
+	table := []struct {
+		spec       map[string]interface{}
+		additional []dnsname.Name
+		expected   []string
+		success    bool
+	}{
+		{
+			map[string]interface{}{
+				"rules": []interface{}{
+					map[string]interface{}{"host": "www.example.com"},
+					map[string]interface{}{},
+					map[string]interface{}{"host": "API.example.com"},
+				},
+				"tls": []interface{}{
+					map[string]interface{}{"hosts": []interface{}{"api.example.com", "secure.example.com"}},
+				},
+			},
+			[]dnsname.Name{mustName("www.example.com."), mustName("extra.example.com")},
+			[]string{"www.example.com.", "API.example.com.", "secure.example.com.", "extra.example.com."},
+			true,
+		},
+		{map[string]interface{}{}, nil, nil, true},
+		{map[string]interface{}{"rules": []interface{}{map[string]interface{}{"host": "www..example.com"}}}, nil, nil, false},
+	}
+
+	for _, entry := range table {
+		ing := newIngress(t, map[string]interface{}{"spec": entry.spec})
+		hosts, err := ingressHosts(ing, entry.additional)
+		if !entry.success {
+			require.NotNil(err, "Spec: %v", entry.spec)
+			continue
+		}
+		require.Nil(err, "Spec: %v", entry.spec)
+		var actual []string
+		for _, host := range hosts {
+			actual = append(actual, host.String())
+		}
+		require.Equal(entry.expected, actual, "Spec: %v", entry.spec)
+	}
+}
+
+func TestIngressSelected(t *testing.T) {
+	require := require.New(t)
+
+	r := &IngressReconciler{
+		IngressClasses:  []string{"nginx"},
+		defaultProvider: &dnsv1alpha1.ProviderReference{Name: "default"},
+	}
+	table := []struct {
+		annotations  map[string]string
+		className    interface{}
+		defaultClass string
+		expected     bool
+	}{
+		{map[string]string{providerAnnotation: "provider"}, "other", "", true},
+		{nil, "nginx", "", true},
+		{nil, "other", "nginx", false},
+		{map[string]string{ingressClassAnnotation: "nginx"}, nil, "", true},
+		{map[string]string{ingressClassAnnotation: "nginx"}, "other", "", false},
+		{nil, nil, "nginx", true},
+		{nil, nil, "other", false},
+		{nil, nil, "", false},
+	}
+
+	for _, entry := range table {
+		spec := map[string]interface{}{}
+		if entry.className != nil {
+			spec["ingressClassName"] = entry.className
+		}
+		ing := newIngress(t, map[string]interface{}{"spec": spec})
+		require.Equal(entry.expected, r.selected(entry.annotations, ing, entry.defaultClass), "Entry: %v", entry)
+	}
+
+	// Without a default provider, only annotated ingresses are selected
+	r.defaultProvider = nil
+	require.False(r.selected(nil, newIngress(t, map[string]interface{}{"spec": map[string]interface{}{"ingressClassName": "nginx"}}), ""))
+}
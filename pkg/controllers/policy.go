@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/labels"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+)
+
+// CheckAccessPolicy checks whether the access policy of the provider referenced by a record
+// allows it to publish an RRset with the given fully qualified name.
+// Returns a nil error if the record is allowed, and a non nil reason otherwise.
+func CheckAccessPolicy(ctx context.Context, c client.Client, record *dnsv1alpha1.DNSRecord, fqdn *dnsname.Name) (reason error, err error) {
+	ref := record.Spec.ProviderRef
+
+	// Retrieve the policy of the provider
+	var policy *dnsv1alpha1.DNSProviderAccessPolicy
+	var providerNamespace string
+	if ref.IsClusterScoped() {
+		var provider dnsv1alpha1.ClusterDNSProvider
+		if err := c.Get(ctx, k8stypes.NamespacedName{Name: ref.Name}, &provider); err != nil {
+			return nil, err
+		}
+		policy = provider.Spec.AccessPolicy
+	} else {
+		providerNamespace = record.Namespace
+		if ref.Namespace != nil {
+			providerNamespace = *ref.Namespace
+		}
+		var provider dnsv1alpha1.DNSProvider
+		if err := c.Get(ctx, k8stypes.NamespacedName{Namespace: providerNamespace, Name: ref.Name}, &provider); err != nil {
+			return nil, err
+		}
+		policy = provider.Spec.AccessPolicy
+	}
+	if policy == nil {
+		return nil, nil
+	}
+
+	// Selectors match the labels of the namespace of the record
+	var namespace corev1.Namespace
+	if err := c.Get(ctx, k8stypes.NamespacedName{Name: record.Namespace}, &namespace); err != nil {
+		return nil, fmt.Errorf("Cannot get Namespace %s: %s", record.Namespace, err)
+	}
+
+	return policy.Allows(providerNamespace, record.Namespace, labels.Set(namespace.Labels), fqdn, record.RType()), nil
+}
+
+// namespaceLabelsChanged filters the updates to Namespaces which can change the outcome of the access policies.
+func namespaceLabelsChanged(e event.UpdateEvent) bool {
+	return !equality.Semantic.DeepEqual(e.MetaOld.GetLabels(), e.MetaNew.GetLabels())
+}
+
+// listRecordsInNamespace maps a Namespace to the DNSRecords it contains.
+func (r *DNSRecordReconciler) listRecordsInNamespace(obj handler.MapObject) []ctrl.Request {
+	var list dnsv1alpha1.DNSRecordList
+	if err := r.List(r.Context.RootContext, &list, client.InNamespace(obj.Meta.GetName())); err != nil {
+		r.Log.Error(err, "Cannot list DNSRecords impacted by a change to a Namespace", "namespace", obj.Meta.GetName())
+		return nil
+	}
+
+	var res []ctrl.Request
+	for _, record := range list.Items {
+		res = append(res, ctrl.Request{
+			NamespacedName: k8stypes.NamespacedName{
+				Name:      record.Name,
+				Namespace: record.Namespace,
+			},
+		})
+	}
+	return res
+}
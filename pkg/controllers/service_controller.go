@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	helpers "github.com/95ulisse/dns-operator/pkg/helpers"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+// ServiceReconciler generates DNSRecords for the load balancers of annotated LoadBalancer Services,
+// and for the endpoints of annotated headless Services.
+type ServiceReconciler struct {
+	client.Client
+	Log     logr.Logger
+	Scheme  *runtime.Scheme
+	Context *types.ControllerContext
+}
+
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+// +kubebuilder:rbac:groups=discovery.k8s.io,resources=endpointslices,verbs=get;list;watch
+// +kubebuilder:rbac:groups=dns.k8s.marcocameriero.net,resources=dnsrecords,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile performs an iteration of the reconcile loop for a Service.
+func (r *ServiceReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := r.Context.RootContext
+	log := r.Log.WithValues("service", req.NamespacedName)
+
+	log.V(1).Info("Starting reconcile loop")
+	defer log.V(1).Info("Finish reconcile loop")
+
+	// Retrieve the service by name.
+	// Records of deleted services are garbage collected by Kubernetes, since they are owned by the service.
+	var service corev1.Service
+	if err := r.Get(ctx, req.NamespacedName, &service); err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "Unable to fetch Service")
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// Build the records for the load balancer of the service, if the service asks for them
+	var desired []dnsv1alpha1.DNSRecord
+	settings, annotated, err := parseSourceAnnotations(&service)
+	if err != nil {
+		log.Info("Invalid annotations", "reason", err.Error())
+		r.Context.EventRecorder.Event(&service, "Warning", "InvalidAnnotations", err.Error())
+		return ctrl.Result{}, nil
+	}
+	loadBalancer := annotated && service.Spec.Type == corev1.ServiceTypeLoadBalancer
+	headless := annotated && service.Spec.ClusterIP == corev1.ClusterIPNone
+
+	// Only the hostnames belonging to the zones of the provider can be published
+	var hostnames []dnsname.Name
+	if loadBalancer || headless {
+		providerKey := settings.providerRef.Key(service.Namespace)
+		var provider types.Provider
+		if !r.Context.GetProvider(providerKey, &provider) {
+			err := fmt.Errorf("Cannot find DNSProvider %s", providerKey)
+			log.Error(err, "Cannot update DNSRecords for Service")
+			return ctrl.Result{}, err
+		}
+		var outside []dnsname.Name
+		hostnames, outside = zoneHostnames(provider.Zones(), settings.hostnames)
+		for _, hostname := range outside {
+			log.Info("Skipping hostname outside of the zones of the provider", "hostname", hostname.String())
+			r.Context.EventRecorder.Eventf(&service, "Warning", "InvalidHost", "Hostname %s does not belong to the zones of DNSProvider %s", hostname.String(), providerKey)
+		}
+	}
+
+	if loadBalancer {
+		var ips, lbHostnames []string
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			if ingress.IP != "" {
+				ips = append(ips, ingress.IP)
+			}
+			if ingress.Hostname != "" {
+				lbHostnames = append(lbHostnames, ingress.Hostname)
+			}
+		}
+		for _, hostname := range hostnames {
+			records, err := addressRecords(&service, settings, hostname, ips, lbHostnames)
+			if err != nil {
+				log.Error(err, "Cannot build DNSRecords for Service")
+				return ctrl.Result{}, nil
+			}
+			desired = append(desired, records...)
+		}
+	}
+	if headless {
+		var slices discoveryv1beta1.EndpointSliceList
+		if err := r.List(ctx, &slices, client.InNamespace(service.Namespace), client.MatchingLabels{discoveryv1beta1.LabelServiceName: service.Name}); err != nil {
+			log.Error(err, "Cannot list EndpointSlices for Service")
+			return ctrl.Result{}, err
+		}
+		srv := service.Annotations[srvAnnotation] == "true"
+		for _, hostname := range hostnames {
+			records, err := headlessRecords(&service, settings, hostname, slices.Items, srv)
+			if err != nil {
+				log.Error(err, "Cannot build DNSRecords for Service")
+				return ctrl.Result{}, nil
+			}
+			desired = append(desired, records...)
+		}
+	}
+
+	// Synchronize the records owned by the service
+	if err := syncOwnedRecords(ctx, r.Client, r.Scheme, r.Context.EventRecorder, "Service", &service, desired); err != nil {
+		log.Error(err, "Cannot update DNSRecords for Service")
+		return ctrl.Result{}, err
+	}
+
+	log.V(1).Info("Updated DNSRecords for Service", "count", len(desired))
+
+	return ctrl.Result{}, nil
+}
+
+// headlessRecords builds the DNSRecords for the ready endpoints of a headless Service:
+// an aggregate record with the addresses of all the endpoints, a record for each endpoint
+// named after its hostname (or its address, if it has no hostname) under the given one,
+// and optionally SRV records for the named ports pointing to the records of the endpoints.
+func headlessRecords(service *corev1.Service, settings *sourceAnnotations, hostname dnsname.Name, slices []discoveryv1beta1.EndpointSlice, srv bool) ([]dnsv1alpha1.DNSRecord, error) {
+	// The names of the endpoints and of the SRV records are built under the hostname,
+	// so it must be fully qualified or they would be resolved relative to the zone
+	hostname = *hostname.ToFQDN()
+
+	type srvKey struct{ name, protocol string }
+	var names []string
+	var aggregate []string
+	addresses := make(map[string][]string)
+	targets := make(map[srvKey][]dnsv1alpha1.SRVRData)
+
+	for _, slice := range slices {
+		if slice.AddressType == discoveryv1beta1.AddressTypeFQDN {
+			continue
+		}
+		for _, endpoint := range slice.Endpoints {
+			ready := endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready
+			if (!ready && !service.Spec.PublishNotReadyAddresses) || len(endpoint.Addresses) == 0 {
+				continue
+			}
+
+			label := endpointLabel(&endpoint)
+			name, err := dnsname.NewName(label + "." + hostname.String())
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := addresses[label]; !ok {
+				names = append(names, label)
+			}
+
+			// Dual-stack services have a slice per address family, and endpoints can appear in more than one slice
+			// while they are moved between slices, so the same address or target can be seen more than once
+			for _, address := range endpoint.Addresses {
+				if !helpers.ContainsString(addresses[label], address) {
+					addresses[label] = append(addresses[label], address)
+				}
+				if !helpers.ContainsString(aggregate, address) {
+					aggregate = append(aggregate, address)
+				}
+			}
+
+			if !srv {
+				continue
+			}
+			for _, port := range slice.Ports {
+				if port.Name == nil || *port.Name == "" || port.Port == nil {
+					continue
+				}
+				protocol := corev1.ProtocolTCP
+				if port.Protocol != nil {
+					protocol = *port.Protocol
+				}
+				key := srvKey{*port.Name, strings.ToLower(string(protocol))}
+				target := dnsv1alpha1.SRVRData{Port: uint16(*port.Port), Target: *name}
+				found := false
+				for _, existing := range targets[key] {
+					found = found || (existing.Port == target.Port && existing.Target.Equal(&target.Target))
+				}
+				if !found {
+					targets[key] = append(targets[key], target)
+				}
+			}
+		}
+	}
+
+	// Aggregate record
+	records, err := addressRecords(service, settings, hostname, aggregate, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Records of the single endpoints
+	sort.Strings(names)
+	for _, label := range names {
+		name, err := dnsname.NewName(label + "." + hostname.String())
+		if err != nil {
+			return nil, err
+		}
+		endpointRecords, err := addressRecords(service, settings, *name, addresses[label], nil)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, endpointRecords...)
+	}
+
+	// SRV records of the named ports, with the same weight for all the endpoints
+	for key, data := range targets {
+		name, err := dnsname.NewName("_" + key.name + "._" + key.protocol + "." + hostname.String())
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(data, func(i, j int) bool {
+			if c := data[i].Target.Compare(&data[j].Target); c != 0 {
+				return c < 0
+			}
+			return data[i].Port < data[j].Port
+		})
+		weight := uint16(100 / len(data))
+		if weight == 0 {
+			weight = 1
+		}
+		for i := range data {
+			data[i].Weight = weight
+		}
+
+		var record dnsv1alpha1.DNSRecord
+		record.Spec.Name = *name
+		record.Spec.ProviderRef = *settings.providerRef.DeepCopy()
+		record.Spec.TTLSeconds = settings.ttl
+		record.Spec.RRSet.SRV = data
+		record.Name = sourceRecordName(service, *name, "SRV")
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// endpointLabel returns the DNS label identifying an endpoint: its hostname if set, otherwise a label derived
+// from its first address. IPv4 addresses have their dots replaced by dashes (e.g., `10-0-0-1`), while IPv6 addresses
+// are fully expanded with dashes between the groups (e.g., `fd00-0000-0000-0000-0000-0000-0000-0001`),
+// so that the label never starts or ends with a dash.
+func endpointLabel(endpoint *discoveryv1beta1.Endpoint) string {
+	if endpoint.Hostname != nil && *endpoint.Hostname != "" {
+		return *endpoint.Hostname
+	}
+
+	ip := net.ParseIP(endpoint.Addresses[0])
+	switch {
+	case ip == nil:
+		h := fnv.New32a()
+		h.Write([]byte(endpoint.Addresses[0]))
+		return fmt.Sprintf("endpoint-%08x", h.Sum32())
+	case ip.To4() != nil:
+		return strings.Replace(ip.To4().String(), ".", "-", -1)
+	default:
+		groups := make([]string, net.IPv6len/2)
+		for i := range groups {
+			groups[i] = hex.EncodeToString(ip[2*i : 2*i+2])
+		}
+		return strings.Join(groups, "-")
+	}
+}
+
+// listServiceOfEndpointSlice maps an EndpointSlice to the Service it belongs to.
+func listServiceOfEndpointSlice(obj handler.MapObject) []ctrl.Request {
+	name, ok := obj.Meta.GetLabels()[discoveryv1beta1.LabelServiceName]
+	if !ok {
+		return nil
+	}
+	return []ctrl.Request{{
+		NamespacedName: k8stypes.NamespacedName{
+			Namespace: obj.Meta.GetNamespace(),
+			Name:      name,
+		},
+	}}
+}
+
+// SetupWithManager registers the Service controller with the given Manager.
+func (r *ServiceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Service{}).
+		Owns(&dnsv1alpha1.DNSRecord{}).
+		Watches(
+			&source.Kind{Type: &discoveryv1beta1.EndpointSlice{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(listServiceOfEndpointSlice)},
+		).
+		Complete(r)
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+)
+
+func TestEndpointLabel(t *testing.T) {
+	require := require.New(t)
+
+	hostname := "pod-0"
+	empty := ""
+	table := []struct {
+		hostname *string
+		address  string
+		expected string
+	}{
+		{&hostname, "10.0.0.1", "pod-0"},
+		{&empty, "10.0.0.1", "10-0-0-1"},
+		{nil, "10.0.0.1", "10-0-0-1"},
+		{nil, "::ffff:10.0.0.1", "10-0-0-1"},
+		{nil, "::1", "0000-0000-0000-0000-0000-0000-0000-0001"},
+		{nil, "FD00::1:2", "fd00-0000-0000-0000-0000-0000-0001-0002"},
+		{nil, "2001:db8::", "2001-0db8-0000-0000-0000-0000-0000-0000"},
+	}
+
+	for _, entry := range table {
+		endpoint := &discoveryv1beta1.Endpoint{Hostname: entry.hostname, Addresses: []string{entry.address}}
+		label := endpointLabel(endpoint)
+		require.Equal(entry.expected, label, "Address: %s", entry.address)
+		_, err := dnsname.NewName(label + ".example.com")
+		require.Nil(err, "Address: %s", entry.address)
+	}
+
+	// Labels are valid even for unexpected addresses
+	label := endpointLabel(&discoveryv1beta1.Endpoint{Addresses: []string{"not an address"}})
+	require.Regexp("^endpoint-[0-9a-f]{8}$", label)
+}
+
+func TestHeadlessRecords(t *testing.T) {
+	require := require.New(t)
+
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "db"}}
+	settings := &sourceAnnotations{providerRef: dnsv1alpha1.ProviderReference{Name: "provider"}}
+
+	ready, notReady := true, false
+	pod0, pod1 := "db-0", "db-1"
+	portName := "postgres"
+	port := int32(5432)
+	ports := []discoveryv1beta1.EndpointPort{{Name: &portName, Port: &port}}
+
+	// A dual-stack service, with an endpoint being moved between two IPv4 slices
+	slices := []discoveryv1beta1.EndpointSlice{
+		{
+			AddressType: discoveryv1beta1.AddressTypeIPv4,
+			Endpoints: []discoveryv1beta1.Endpoint{
+				{Hostname: &pod0, Addresses: []string{"10.0.0.1"}},
+				{Hostname: &pod1, Addresses: []string{"10.0.0.2"}, Conditions: discoveryv1beta1.EndpointConditions{Ready: &ready}},
+				{Addresses: []string{"10.0.0.3"}, Conditions: discoveryv1beta1.EndpointConditions{Ready: &notReady}},
+			},
+			Ports: ports,
+		},
+		{
+			AddressType: discoveryv1beta1.AddressTypeIPv4,
+			Endpoints:   []discoveryv1beta1.Endpoint{{Hostname: &pod1, Addresses: []string{"10.0.0.2"}}},
+			Ports:       ports,
+		},
+		{
+			AddressType: discoveryv1beta1.AddressTypeIPv6,
+			Endpoints: []discoveryv1beta1.Endpoint{
+				{Hostname: &pod0, Addresses: []string{"fd00::1"}},
+				{Hostname: &pod1, Addresses: []string{"fd00::2"}},
+			},
+			Ports: ports,
+		},
+		{
+			AddressType: discoveryv1beta1.AddressTypeFQDN,
+			Endpoints:   []discoveryv1beta1.Endpoint{{Addresses: []string{"external.example.net"}}},
+		},
+	}
+
+	summary := func(records []dnsv1alpha1.DNSRecord) map[string]dnsv1alpha1.DNSRecordSetData {
+		res := make(map[string]dnsv1alpha1.DNSRecordSetData)
+		for _, record := range records {
+			key := record.RType() + " " + record.Spec.Name.String()
+			require.NotContains(res, key)
+			require.Equal(sourceRecordName(service, record.Spec.Name, record.RType()), record.Name)
+			res[key] = record.Spec.RRSet
+		}
+		return res
+	}
+
+	table := []struct {
+		srv                      bool
+		publishNotReadyAddresses bool
+		expected                 map[string]dnsv1alpha1.DNSRecordSetData
+	}{
+		{
+			false, false,
+			map[string]dnsv1alpha1.DNSRecordSetData{
+				"A db.example.com.":         {A: []dnsv1alpha1.Ipv4String{"10.0.0.1", "10.0.0.2"}},
+				"AAAA db.example.com.":      {AAAA: []dnsv1alpha1.Ipv6String{"fd00::1", "fd00::2"}},
+				"A db-0.db.example.com.":    {A: []dnsv1alpha1.Ipv4String{"10.0.0.1"}},
+				"AAAA db-0.db.example.com.": {AAAA: []dnsv1alpha1.Ipv6String{"fd00::1"}},
+				"A db-1.db.example.com.":    {A: []dnsv1alpha1.Ipv4String{"10.0.0.2"}},
+				"AAAA db-1.db.example.com.": {AAAA: []dnsv1alpha1.Ipv6String{"fd00::2"}},
+			},
+		},
+		{
+			true, true,
+			map[string]dnsv1alpha1.DNSRecordSetData{
+				"A db.example.com.":          {A: []dnsv1alpha1.Ipv4String{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+				"AAAA db.example.com.":       {AAAA: []dnsv1alpha1.Ipv6String{"fd00::1", "fd00::2"}},
+				"A db-0.db.example.com.":     {A: []dnsv1alpha1.Ipv4String{"10.0.0.1"}},
+				"AAAA db-0.db.example.com.":  {AAAA: []dnsv1alpha1.Ipv6String{"fd00::1"}},
+				"A db-1.db.example.com.":     {A: []dnsv1alpha1.Ipv4String{"10.0.0.2"}},
+				"AAAA db-1.db.example.com.":  {AAAA: []dnsv1alpha1.Ipv6String{"fd00::2"}},
+				"A 10-0-0-3.db.example.com.": {A: []dnsv1alpha1.Ipv4String{"10.0.0.3"}},
+				"SRV _postgres._tcp.db.example.com.": {SRV: []dnsv1alpha1.SRVRData{
+					{Weight: 33, Port: 5432, Target: mustName("10-0-0-3.db.example.com.")},
+					{Weight: 33, Port: 5432, Target: mustName("db-0.db.example.com.")},
+					{Weight: 33, Port: 5432, Target: mustName("db-1.db.example.com.")},
+				}},
+			},
+		},
+	}
+
+	// Hostnames from the annotations may not be fully qualified, but the generated names always are
+	for _, entry := range table {
+		for _, hostname := range []string{"db.example.com.", "db.example.com"} {
+			service.Spec.PublishNotReadyAddresses = entry.publishNotReadyAddresses
+			records, err := headlessRecords(service, settings, mustName(hostname), slices, entry.srv)
+			require.Nil(err)
+			require.Equal(entry.expected, summary(records), "Hostname: %s, SRV: %v, PublishNotReadyAddresses: %v", hostname, entry.srv, entry.publishNotReadyAddresses)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+)
+
+// Annotations used by the source controllers, which generate DNSRecords from other resources.
+const (
+	// hostnameAnnotation contains a comma separated list of hostnames to publish for the annotated resource.
+	hostnameAnnotation = "dns.k8s.marcocameriero.net/hostname"
+
+	// providerAnnotation contains the reference to the DNSProvider to use for the generated records,
+	// in the form `name` or `namespace/name`, or `ClusterDNSProvider/name` for a ClusterDNSProvider.
+	providerAnnotation = "dns.k8s.marcocameriero.net/provider"
+
+	// ttlAnnotation contains the TTL in seconds of the generated records.
+	ttlAnnotation = "dns.k8s.marcocameriero.net/ttl"
+
+	// srvAnnotation enables the generation of SRV records for the named ports of headless Services.
+	srvAnnotation = "dns.k8s.marcocameriero.net/srv"
+)
+
+// sourceKindLabel is applied to the DNSRecords generated by the source controllers,
+// and contains the kind of the resource they have been generated from.
+const sourceKindLabel = "dns.k8s.marcocameriero.net/source-kind"
+
+// sourceOwner is a resource from which DNSRecords are generated.
+type sourceOwner interface {
+	metav1.Object
+	runtime.Object
+}
+
+// sourceRecordName returns the name of the DNSRecord generated from the given resource for a hostname and a type.
+func sourceRecordName(owner sourceOwner, hostname dnsname.Name, rtype string) string {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(hostname.ToFQDN().String())))
+	h.Write([]byte(rtype))
+
+	// Leave room for the suffix, since the name of a resource cannot exceed 253 characters
+	prefix := owner.GetName()
+	if len(prefix) > 200 {
+		prefix = prefix[:200]
+	}
+	return fmt.Sprintf("%s-%08x-%s", prefix, h.Sum32(), strings.ToLower(rtype))
+}
+
+// sourceAnnotations contains the settings of the records generated from an annotated resource.
+type sourceAnnotations struct {
+	hostnames   []dnsname.Name
+	providerRef dnsv1alpha1.ProviderReference
+	ttl         *uint32
+}
+
+// parseSourceAnnotations extracts the settings of the records to generate from the annotations of a resource.
+// Returns false if the resource does not ask for records to be generated.
+func parseSourceAnnotations(owner sourceOwner) (*sourceAnnotations, bool, error) {
+	hostnames, ok := owner.GetAnnotations()[hostnameAnnotation]
+	if !ok || strings.TrimSpace(hostnames) == "" {
+		return nil, false, nil
+	}
+
+	res, err := parseRecordAnnotations(owner, nil)
+	if err != nil {
+		return nil, true, err
+	}
+	return res, true, nil
+}
+
+// parseRecordAnnotations extracts the settings of the records to generate from the annotations of a resource,
+// using the given DNSProvider if the resource does not reference one.
+// Hostnames are optional.
+func parseRecordAnnotations(owner sourceOwner, defaultProvider *dnsv1alpha1.ProviderReference) (*sourceAnnotations, error) {
+	annotations := owner.GetAnnotations()
+	res := &sourceAnnotations{}
+	var err error
+
+	if hostnames, ok := annotations[hostnameAnnotation]; ok {
+		if res.hostnames, err = parseHostnames(hostnames); err != nil {
+			return nil, err
+		}
+	}
+
+	if provider, ok := annotations[providerAnnotation]; ok && provider != "" {
+		if res.providerRef, err = parseProviderRef(provider); err != nil {
+			return nil, err
+		}
+	} else if defaultProvider != nil {
+		res.providerRef = *defaultProvider.DeepCopy()
+	} else {
+		return nil, fmt.Errorf("Annotation %s is required", providerAnnotation)
+	}
+
+	if ttl, ok := annotations[ttlAnnotation]; ok {
+		value, err := strconv.ParseUint(ttl, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid TTL %s: %s", ttl, err)
+		}
+		v := uint32(value)
+		res.ttl = &v
+	}
+
+	return res, nil
+}
+
+// parseHostnames parses a comma separated list of hostnames.
+func parseHostnames(value string) ([]dnsname.Name, error) {
+	var res []dnsname.Name
+	for _, h := range strings.Split(value, ",") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		name, err := dnsname.NewName(h)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *name)
+	}
+	return res, nil
+}
+
+// parseProviderRef parses a reference to a DNSProvider in the form `name` or `namespace/name`,
+// or to a ClusterDNSProvider in the form `ClusterDNSProvider/name`.
+// The two forms cannot be confused, since names of namespaces cannot contain uppercase letters.
+func parseProviderRef(value string) (dnsv1alpha1.ProviderReference, error) {
+	parts := strings.Split(value, "/")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		return dnsv1alpha1.ProviderReference{Name: parts[0]}, nil
+	case len(parts) == 2 && parts[0] == dnsv1alpha1.ClusterDNSProviderKind && parts[1] != "":
+		return dnsv1alpha1.ProviderReference{Kind: dnsv1alpha1.ClusterDNSProviderKind, Name: parts[1]}, nil
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		return dnsv1alpha1.ProviderReference{Name: parts[1], Namespace: &parts[0]}, nil
+	default:
+		return dnsv1alpha1.ProviderReference{}, fmt.Errorf("Invalid DNSProvider reference %s", value)
+	}
+}
+
+// zoneHostnames splits the given hostnames between the ones belonging to one of the zones, which can be published,
+// and the ones outside of all of them. Hostnames are made fully qualified, since a relative name
+// would otherwise be resolved relative to the zone of the generated record.
+func zoneHostnames(zones []dnsname.Name, hostnames []dnsname.Name) (inside []dnsname.Name, outside []dnsname.Name) {
+	for i := range hostnames {
+		hostname := *hostnames[i].ToFQDN()
+		var zone dnsname.Name
+		if getMatchingZone(zones, hostname, &zone) {
+			inside = append(inside, hostname)
+		} else {
+			outside = append(outside, hostname)
+		}
+	}
+	return inside, outside
+}
+
+// addressRecords builds the DNSRecords publishing the given addresses for a hostname:
+// an A record for the IPv4 addresses, an AAAA record for the IPv6 ones,
+// or a CNAME record if there are no IPs but only hostnames (e.g., for cloud load balancers).
+func addressRecords(owner sourceOwner, settings *sourceAnnotations, hostname dnsname.Name, ips []string, hostnames []string) ([]dnsv1alpha1.DNSRecord, error) {
+	var rrsets []dnsv1alpha1.DNSRecordSetData
+	var ipv4 []dnsv1alpha1.Ipv4String
+	var ipv6 []dnsv1alpha1.Ipv6String
+	for _, s := range ips {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			continue
+		}
+		if ip.To4() != nil {
+			ipv4 = append(ipv4, dnsv1alpha1.Ipv4String(ip.String()))
+		} else {
+			ipv6 = append(ipv6, dnsv1alpha1.Ipv6String(ip.String()))
+		}
+	}
+	if len(ipv4) > 0 {
+		rrsets = append(rrsets, dnsv1alpha1.DNSRecordSetData{A: ipv4})
+	}
+	if len(ipv6) > 0 {
+		rrsets = append(rrsets, dnsv1alpha1.DNSRecordSetData{AAAA: ipv6})
+	}
+
+	// A CNAME cannot coexist with other records, so it is used only when there are no IPs.
+	// It is also limited to a single target.
+	if len(rrsets) == 0 && len(hostnames) > 0 {
+		target, err := dnsname.NewName(hostnames[0])
+		if err != nil {
+			return nil, err
+		}
+		rrsets = append(rrsets, dnsv1alpha1.DNSRecordSetData{CNAME: []dnsname.Name{*target.ToFQDN()}})
+	}
+
+	records := make([]dnsv1alpha1.DNSRecord, 0, len(rrsets))
+	for _, rrset := range rrsets {
+		var record dnsv1alpha1.DNSRecord
+		record.Spec.Name = *hostname.ToFQDN()
+		record.Spec.ProviderRef = *settings.providerRef.DeepCopy()
+		record.Spec.TTLSeconds = settings.ttl
+		record.Spec.RRSet = rrset
+		record.Name = sourceRecordName(owner, hostname, record.RType())
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// syncOwnedRecords makes the DNSRecords generated from `owner` match the desired ones:
+// missing records are created, changed ones are updated, and the ones no longer desired are deleted.
+// Records which are not controlled by `owner` are never touched.
+//
+// A record rejected by the API server (e.g., by the validating webhook, because its RRset is already claimed
+// by another record) does not stop the synchronization of the other ones: the rejection is reported
+// with a `Conflict` event on the owner, and all the errors are returned together at the end,
+// so that the owner is reconciled again later.
+func syncOwnedRecords(ctx context.Context, c client.Client, scheme *runtime.Scheme, recorder record.EventRecorder, kind string, owner sourceOwner, desired []dnsv1alpha1.DNSRecord) error {
+	labels := map[string]string{sourceKindLabel: kind}
+
+	// Retrieve the records generated so far.
+	// Names of resources can be longer than label values, so the owner is identified by the owner reference.
+	var list dnsv1alpha1.DNSRecordList
+	if err := c.List(ctx, &list, client.InNamespace(owner.GetNamespace()), client.MatchingLabels(labels)); err != nil {
+		return err
+	}
+	existing := make(map[string]*dnsv1alpha1.DNSRecord)
+	for i := range list.Items {
+		if metav1.IsControlledBy(&list.Items[i], owner) {
+			existing[list.Items[i].Name] = &list.Items[i]
+		}
+	}
+
+	// Create or update the desired records
+	var errs []error
+	for i := range desired {
+		dnsRecord := &desired[i]
+		dnsRecord.Namespace = owner.GetNamespace()
+		dnsRecord.Labels = labels
+
+		// Apply the same defaults of the defaulting webhook, or the records would be updated on every iteration
+		dnsRecord.Default()
+		if err := controllerutil.SetControllerReference(owner, dnsRecord, scheme); err != nil {
+			return err
+		}
+
+		var err error
+		if current, ok := existing[dnsRecord.Name]; ok {
+			delete(existing, dnsRecord.Name)
+			if equality.Semantic.DeepEqual(current.Spec, dnsRecord.Spec) {
+				continue
+			}
+			current.Spec = dnsRecord.Spec
+			err = c.Update(ctx, current)
+		} else {
+			err = c.Create(ctx, dnsRecord)
+		}
+		if apierrors.IsForbidden(err) {
+			recorder.Eventf(owner, "Warning", "Conflict", "Cannot publish %s %s: %s", dnsRecord.RType(), dnsRecord.Spec.Name.String(), err)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Delete the records no longer needed
+	for _, dnsRecord := range existing {
+		if err := c.Delete(ctx, dnsRecord); client.IgnoreNotFound(err) != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
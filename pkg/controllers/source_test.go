@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+)
+
+func mustName(name string) dnsname.Name {
+	n, err := dnsname.NewName(name)
+	if err != nil {
+		panic(err)
+	}
+	return *n
+}
+
+func TestParseProviderRef(t *testing.T) {
+	require := require.New(t)
+
+	ns := "ns"
+	table := []struct {
+		value    string
+		expected dnsv1alpha1.ProviderReference
+		success  bool
+	}{
+		{"provider", dnsv1alpha1.ProviderReference{Name: "provider"}, true},
+		{"ns/provider", dnsv1alpha1.ProviderReference{Name: "provider", Namespace: &ns}, true},
+		{"ClusterDNSProvider/provider", dnsv1alpha1.ProviderReference{Kind: dnsv1alpha1.ClusterDNSProviderKind, Name: "provider"}, true},
+		{"", dnsv1alpha1.ProviderReference{}, false},
+		{"ns/", dnsv1alpha1.ProviderReference{}, false},
+		{"/provider", dnsv1alpha1.ProviderReference{}, false},
+		{"ClusterDNSProvider/", dnsv1alpha1.ProviderReference{}, false},
+		{"a/b/c", dnsv1alpha1.ProviderReference{}, false},
+	}
+
+	for _, entry := range table {
+		ref, err := parseProviderRef(entry.value)
+		if entry.success {
+			require.Nil(err, "Value: %s", entry.value)
+			require.Equal(entry.expected, ref, "Value: %s", entry.value)
+		} else {
+			require.NotNil(err, "Value: %s", entry.value)
+		}
+	}
+}
+
+func TestParseHostnames(t *testing.T) {
+	require := require.New(t)
+
+	table := []struct {
+		value    string
+		expected []string
+		success  bool
+	}{
+		{"www.example.com", []string{"www.example.com"}, true},
+		{" www.example.com , api.example.com.,", []string{"www.example.com", "api.example.com."}, true},
+		{"", nil, true},
+		{"www..example.com", nil, false},
+	}
+
+	for _, entry := range table {
+		names, err := parseHostnames(entry.value)
+		if !entry.success {
+			require.NotNil(err, "Value: %s", entry.value)
+			continue
+		}
+		require.Nil(err, "Value: %s", entry.value)
+		var actual []string
+		for _, name := range names {
+			actual = append(actual, name.String())
+		}
+		require.Equal(entry.expected, actual, "Value: %s", entry.value)
+	}
+}
+
+func TestParseRecordAnnotations(t *testing.T) {
+	require := require.New(t)
+
+	defaultProvider := &dnsv1alpha1.ProviderReference{Name: "default"}
+	table := []struct {
+		annotations map[string]string
+		provider    string
+		ttl         *uint32
+		success     bool
+	}{
+		{map[string]string{providerAnnotation: "provider"}, "provider", nil, true},
+		{map[string]string{ttlAnnotation: "60"}, "default", func() *uint32 { v := uint32(60); return &v }(), true},
+		{map[string]string{ttlAnnotation: "-1"}, "", nil, false},
+		{map[string]string{providerAnnotation: "a/b/c"}, "", nil, false},
+	}
+
+	for _, entry := range table {
+		service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Annotations: entry.annotations}}
+		settings, err := parseRecordAnnotations(service, defaultProvider)
+		if !entry.success {
+			require.NotNil(err, "Annotations: %v", entry.annotations)
+			continue
+		}
+		require.Nil(err, "Annotations: %v", entry.annotations)
+		require.Equal(entry.provider, settings.providerRef.Name)
+		require.Equal(entry.ttl, settings.ttl)
+	}
+
+	// Without a default, the provider is required
+	_, err := parseRecordAnnotations(&corev1.Service{}, nil)
+	require.NotNil(err)
+	_, annotated, err := parseSourceAnnotations(&corev1.Service{})
+	require.Nil(err)
+	require.False(annotated)
+}
+
+func TestAddressRecords(t *testing.T) {
+	require := require.New(t)
+
+	owner := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "service"}}
+	settings := &sourceAnnotations{providerRef: dnsv1alpha1.ProviderReference{Name: "provider"}}
+	hostname := mustName("www.example.com")
+
+	table := []struct {
+		ips       []string
+		hostnames []string
+		expected  []dnsv1alpha1.DNSRecordSetData
+	}{
+		{
+			[]string{"1.1.1.1", "2001:db8::1", "invalid", "2.2.2.2"}, []string{"lb.example.net"},
+			[]dnsv1alpha1.DNSRecordSetData{
+				{A: []dnsv1alpha1.Ipv4String{"1.1.1.1", "2.2.2.2"}},
+				{AAAA: []dnsv1alpha1.Ipv6String{"2001:db8::1"}},
+			},
+		},
+		{
+			[]string{"::ffff:1.1.1.1"}, nil,
+			[]dnsv1alpha1.DNSRecordSetData{{A: []dnsv1alpha1.Ipv4String{"1.1.1.1"}}},
+		},
+		{
+			nil, []string{"a.lb.example.net", "b.lb.example.net"},
+			[]dnsv1alpha1.DNSRecordSetData{{CNAME: []dnsname.Name{mustName("a.lb.example.net.")}}},
+		},
+		{nil, nil, []dnsv1alpha1.DNSRecordSetData{}},
+	}
+
+	for _, entry := range table {
+		records, err := addressRecords(owner, settings, hostname, entry.ips, entry.hostnames)
+		require.Nil(err)
+		actual := []dnsv1alpha1.DNSRecordSetData{}
+		for _, record := range records {
+			require.Equal("www.example.com.", record.Spec.Name.String())
+			require.Equal("provider", record.Spec.ProviderRef.Name)
+			require.Equal(sourceRecordName(owner, hostname, record.RType()), record.Name)
+			actual = append(actual, record.Spec.RRSet)
+		}
+		require.Equal(entry.expected, actual, "IPs: %v, Hostnames: %v", entry.ips, entry.hostnames)
+	}
+}
+
+func TestZoneHostnames(t *testing.T) {
+	require := require.New(t)
+
+	zones := []dnsname.Name{mustName("example.com"), mustName("example.net.")}
+	hostnames := []dnsname.Name{mustName("www.example.com"), mustName("app.other.org"), mustName("example.net"), mustName("api.example.com.")}
+	inside, outside := zoneHostnames(zones, hostnames)
+
+	// Names are made fully qualified instead of being resolved relative to the zones
+	var actual []string
+	for _, name := range inside {
+		actual = append(actual, name.String())
+	}
+	require.Equal([]string{"www.example.com.", "example.net.", "api.example.com."}, actual)
+	require.Len(outside, 1)
+	require.Equal("app.other.org.", outside[0].String())
+}
+
+func TestSourceRecordName(t *testing.T) {
+	require := require.New(t)
+
+	owner := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "service"}}
+	a := sourceRecordName(owner, mustName("www.example.com"), "A")
+	require.Equal(a, sourceRecordName(owner, mustName("WWW.example.com."), "A"))
+	require.NotEqual(a, sourceRecordName(owner, mustName("www.example.com"), "AAAA"))
+	require.NotEqual(a, sourceRecordName(owner, mustName("api.example.com"), "A"))
+	require.Regexp("^service-[0-9a-f]{8}-a$", a)
+}
+
+// rejectingClient rejects the creation of the DNSRecords with the given name, like the validating webhook
+// does for the records claiming an RRset already claimed by another record.
+type rejectingClient struct {
+	client.Client
+	rejected string
+}
+
+func (c *rejectingClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	if record, ok := obj.(*dnsv1alpha1.DNSRecord); ok && record.Name == c.rejected {
+		return apierrors.NewForbidden(schema.GroupResource{Group: dnsv1alpha1.GroupVersion.Group, Resource: "dnsrecords"}, record.Name,
+			errors.New("RRset already claimed"))
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func TestSyncOwnedRecords(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	require.Nil(clientgoscheme.AddToScheme(scheme))
+	require.Nil(dnsv1alpha1.AddToScheme(scheme))
+	owner := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "service", Namespace: "default", UID: "uid"}}
+	settings := &sourceAnnotations{providerRef: dnsv1alpha1.ProviderReference{Name: "provider"}}
+
+	// A record generated for a hostname no longer published
+	stale, err := addressRecords(owner, settings, mustName("old.example.com"), []string{"1.1.1.1"}, nil)
+	require.Nil(err)
+	stale[0].Namespace = owner.Namespace
+	stale[0].Labels = map[string]string{sourceKindLabel: "Service"}
+	require.Nil(controllerutil.SetControllerReference(owner, &stale[0], scheme))
+
+	var desired []dnsv1alpha1.DNSRecord
+	for _, hostname := range []string{"shared.example.com", "www.example.com"} {
+		records, err := addressRecords(owner, settings, mustName(hostname), []string{"1.1.1.1"}, nil)
+		require.Nil(err)
+		desired = append(desired, records...)
+	}
+
+	// The rejection of the first record does not prevent the creation of the second one, nor the deletion of the stale one
+	c := &rejectingClient{Client: fake.NewFakeClientWithScheme(scheme, &stale[0]), rejected: desired[0].Name}
+	recorder := record.NewFakeRecorder(10)
+	err = syncOwnedRecords(ctx, c, scheme, recorder, "Service", owner, desired)
+	require.NotNil(err)
+	require.Contains(err.Error(), "RRset already claimed")
+
+	var list dnsv1alpha1.DNSRecordList
+	require.Nil(c.List(ctx, &list))
+	require.Len(list.Items, 1)
+	require.Equal(desired[1].Name, list.Items[0].Name)
+	require.Len(recorder.Events, 1)
+	require.Contains(<-recorder.Events, "Warning Conflict Cannot publish A shared.example.com.")
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
 	"path/filepath"
 	"testing"
 
@@ -32,6 +33,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/types"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -79,6 +81,12 @@ var _ = BeforeSuite(func(done Done) {
 	err = (&DNSRecordReconciler{
 		Client: k8sManager.GetClient(),
 		Log:    ctrl.Log.WithName("controllers").WithName("CronJob"),
+		Context: &types.ControllerContext{
+			RootContext:   context.Background(),
+			Client:        k8sManager.GetClient(),
+			Log:           ctrl.Log,
+			EventRecorder: k8sManager.GetEventRecorderFor("dns.k8s.marcocameriero.net"),
+		},
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
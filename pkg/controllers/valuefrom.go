@@ -0,0 +1,384 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/jsonpath"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+)
+
+// valueFromIndex is the name of the field index of DNSRecords by the resource referenced by `valueFrom`.
+const valueFromIndex = ".spec.rrset.valueFrom"
+
+// Kinds of the resources which are always watched for changes, since they can be referenced with a dedicated field.
+var (
+	serviceKind   = schema.GroupKind{Kind: "Service"}
+	nodeKind      = schema.GroupKind{Kind: "Node"}
+	configMapKind = schema.GroupKind{Kind: "ConfigMap"}
+)
+
+// secretKind is the kind of Secrets, which can never be referenced by `valueFrom`,
+// since they would be published using the permissions of the operator.
+var secretKind = schema.GroupKind{Kind: "Secret"}
+
+// DefaultFieldRefKinds are the kinds which can be referenced by `valueFrom.fieldRef` by default.
+// The operator can read them without additional permissions.
+var DefaultFieldRefKinds = []schema.GroupKind{serviceKind, configMapKind}
+
+// FieldRefPolicy restricts the resources which can be referenced by `valueFrom.fieldRef`
+// to an allowlist of namespaced kinds, which never includes Secrets.
+type FieldRefPolicy struct {
+	// Kinds which can be referenced.
+	Kinds []schema.GroupKind
+
+	// Mapper used to find the version and the scope of the kinds.
+	// When nil, only the kind of the references is checked.
+	Mapper meta.RESTMapper
+}
+
+// Resolve checks that the resource referenced by a `fieldRef` can be read,
+// and returns the kind to read it with, at the version preferred by the API server.
+func (p *FieldRefPolicy) Resolve(ref *dnsv1alpha1.ObjectFieldSelector) (schema.GroupVersionKind, error) {
+	gvk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+	allowed := false
+	for _, kind := range p.Kinds {
+		allowed = allowed || kind == gvk.GroupKind()
+	}
+	if gvk.GroupKind() != secretKind && !allowed {
+		return schema.GroupVersionKind{}, fmt.Errorf("Kind %s cannot be referenced by fieldRef, allowed kinds are: %s", gvk.GroupKind(), groupKindNames(p.Kinds))
+	}
+	return p.resolveKind(gvk.GroupKind())
+}
+
+// resolveKind returns the preferred version of a kind, checking that it can be referenced by `fieldRef`.
+func (p *FieldRefPolicy) resolveKind(kind schema.GroupKind) (schema.GroupVersionKind, error) {
+	if kind == secretKind {
+		return schema.GroupVersionKind{}, fmt.Errorf("Secrets cannot be referenced by fieldRef")
+	}
+	if p.Mapper == nil {
+		return kind.WithVersion(""), nil
+	}
+	mapping, err := p.Mapper.RESTMapping(kind)
+	if err != nil {
+		return schema.GroupVersionKind{}, fmt.Errorf("Cannot find kind %s: %s", kind, err)
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return schema.GroupVersionKind{}, fmt.Errorf("Kind %s is cluster scoped, only namespaced resources can be referenced by fieldRef", kind)
+	}
+	return mapping.GroupVersionKind, nil
+}
+
+// groupKindNames returns the names of the given kinds, in the form `Kind.group`.
+func groupKindNames(kinds []schema.GroupKind) string {
+	var names []string
+	for _, kind := range kinds {
+		names = append(names, kind.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// valueFromKey returns the key identifying a resource referenced by `valueFrom`.
+func valueFromKey(kind schema.GroupKind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", kind.Group, kind.Kind, namespace, name)
+}
+
+// valueFromReference returns the kind, the namespace and the name of the resource referenced by the given record.
+// Returns false if the record does not reference any resource.
+func (r *DNSRecordReconciler) valueFromReference(record *dnsv1alpha1.DNSRecord) (schema.GroupVersionKind, k8stypes.NamespacedName, bool) {
+	valueFrom := record.Spec.RRSet.ValueFrom
+	if valueFrom == nil {
+		return schema.GroupVersionKind{}, k8stypes.NamespacedName{}, false
+	}
+
+	switch {
+	case valueFrom.ServiceRef != nil:
+		return serviceKind.WithVersion("v1"), k8stypes.NamespacedName{Namespace: record.Namespace, Name: valueFrom.ServiceRef.Name}, true
+	case valueFrom.NodeRef != nil:
+		return nodeKind.WithVersion("v1"), k8stypes.NamespacedName{Name: valueFrom.NodeRef.Name}, true
+	case valueFrom.NodeSelector != nil:
+		// Records selecting nodes by label are not bound to a specific node, so they are indexed without a name
+		return nodeKind.WithVersion("v1"), k8stypes.NamespacedName{}, true
+	case valueFrom.ConfigMapKeyRef != nil:
+		return configMapKind.WithVersion("v1"), k8stypes.NamespacedName{Namespace: record.Namespace, Name: valueFrom.ConfigMapKeyRef.Name}, true
+	case valueFrom.FieldRef != nil:
+		gvk := schema.FromAPIVersionAndKind(valueFrom.FieldRef.APIVersion, valueFrom.FieldRef.Kind)
+		return gvk, k8stypes.NamespacedName{Namespace: record.Namespace, Name: valueFrom.FieldRef.Name}, true
+	}
+	return schema.GroupVersionKind{}, k8stypes.NamespacedName{}, false
+}
+
+// resolveValueFrom returns the contents of a record taken from the resource referenced by `valueFrom`.
+func (r *DNSRecordReconciler) resolveValueFrom(ctx context.Context, record *dnsv1alpha1.DNSRecord) (*dnsv1alpha1.DNSRecordSetData, error) {
+	valueFrom := record.Spec.RRSet.ValueFrom
+	_, name, ok := r.valueFromReference(record)
+	if !ok {
+		return nil, fmt.Errorf("One of serviceRef, nodeRef, nodeSelector, configMapKeyRef or fieldRef is required in valueFrom")
+	}
+
+	var values []string
+	switch {
+
+	// Addresses of the load balancer of a service
+	case valueFrom.ServiceRef != nil:
+		var service corev1.Service
+		if err := r.Get(ctx, name, &service); err != nil {
+			return nil, fmt.Errorf("Cannot get Service %s: %s", name, err)
+		}
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			if valueFrom.Type == "CNAME" {
+				values = append(values, ingress.Hostname)
+			} else {
+				values = append(values, ingress.IP)
+			}
+		}
+
+	// External addresses of a node
+	case valueFrom.NodeRef != nil:
+		var node corev1.Node
+		if err := r.Get(ctx, name, &node); err != nil {
+			return nil, fmt.Errorf("Cannot get Node %s: %s", name.Name, err)
+		}
+		for _, address := range node.Status.Addresses {
+			if (valueFrom.Type == "CNAME" && address.Type == corev1.NodeExternalDNS) ||
+				(valueFrom.Type != "CNAME" && address.Type == corev1.NodeExternalIP) {
+				values = append(values, address.Address)
+			}
+		}
+
+	// Addresses of a set of nodes
+	case valueFrom.NodeSelector != nil:
+		selector := labels.Everything()
+		if valueFrom.NodeSelector.Selector != nil {
+			var err error
+			if selector, err = metav1.LabelSelectorAsSelector(valueFrom.NodeSelector.Selector); err != nil {
+				return nil, fmt.Errorf("Invalid node selector: %s", err)
+			}
+		}
+		addressType := corev1.NodeExternalIP
+		if valueFrom.NodeSelector.AddressType != nil {
+			addressType = corev1.NodeAddressType(*valueFrom.NodeSelector.AddressType)
+		}
+		var nodes corev1.NodeList
+		if err := r.List(ctx, &nodes, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, fmt.Errorf("Cannot list Nodes: %s", err)
+		}
+		for _, node := range nodes.Items {
+			if !isNodeAvailable(&node) {
+				continue
+			}
+			for _, address := range node.Status.Addresses {
+				if address.Type == addressType {
+					values = append(values, address.Address)
+				}
+			}
+		}
+
+	// Key of a config map
+	case valueFrom.ConfigMapKeyRef != nil:
+		var configMap corev1.ConfigMap
+		if err := r.Get(ctx, name, &configMap); err != nil {
+			return nil, fmt.Errorf("Cannot get ConfigMap %s: %s", name, err)
+		}
+		value, ok := configMap.Data[valueFrom.ConfigMapKeyRef.Key]
+		if !ok {
+			return nil, fmt.Errorf("Cannot find key %s in ConfigMap %s", valueFrom.ConfigMapKeyRef.Key, name)
+		}
+		values = splitValues(valueFrom.Type, value)
+
+	// Arbitrary field of a resource
+	case valueFrom.FieldRef != nil:
+		// Only the watched kinds can be read, at the version they are watched with
+		gvk, err := r.fieldRefPolicy.Resolve(valueFrom.FieldRef)
+		if err != nil {
+			return nil, err
+		}
+		obj := r.newObject(gvk)
+		if err := r.Get(ctx, name, obj); err != nil {
+			return nil, fmt.Errorf("Cannot get %s %s: %s", gvk.Kind, name, err)
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot convert %s %s: %s", gvk.Kind, name, err)
+		}
+		results, err := evalJSONPath(valueFrom.FieldRef.JSONPath, content)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			values = append(values, splitValues(valueFrom.Type, result)...)
+		}
+
+	}
+
+	return toRRSetData(valueFrom.Type, values)
+}
+
+// newObject returns an empty object of the given kind, typed if the kind is known to the scheme,
+// so that it is read from the same cache used by the watches.
+func (r *DNSRecordReconciler) newObject(gvk schema.GroupVersionKind) runtime.Object {
+	if obj, err := r.Scheme.New(gvk); err == nil {
+		return obj
+	}
+	return newUnstructured(gvk)
+}
+
+// splitValues splits a textual value in the values of the single records.
+// TXT records are never split.
+func splitValues(rtype string, value string) []string {
+	if rtype == "TXT" {
+		return []string{value}
+	}
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+}
+
+// evalJSONPath evaluates a JSONPath expression against an object, returning the textual representation of the results.
+func evalJSONPath(expression string, obj interface{}) ([]string, error) {
+	if !strings.HasPrefix(expression, "{") {
+		expression = "{" + expression + "}"
+	}
+	jp := jsonpath.New("valueFrom")
+	if err := jp.Parse(expression); err != nil {
+		return nil, fmt.Errorf("Invalid JSONPath %s: %s", expression, err)
+	}
+	results, err := jp.FindResults(obj)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot evaluate JSONPath %s: %s", expression, err)
+	}
+
+	var res []string
+	for _, set := range results {
+		for _, value := range set {
+			if value.Kind() == reflect.Interface {
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Slice {
+				for i := 0; i < value.Len(); i++ {
+					res = append(res, fmt.Sprint(value.Index(i).Interface()))
+				}
+			} else {
+				res = append(res, fmt.Sprint(value.Interface()))
+			}
+		}
+	}
+	return res, nil
+}
+
+// toRRSetData converts the values taken from a resource to the contents of a record of the given type.
+// Values are sorted, so that the same values always produce the same record.
+func toRRSetData(rtype string, values []string) (*dnsv1alpha1.DNSRecordSetData, error) {
+	var data dnsv1alpha1.DNSRecordSetData
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" && rtype != "TXT" {
+			continue
+		}
+		switch rtype {
+		case "A":
+			if ip := net.ParseIP(value); ip != nil && ip.To4() != nil {
+				data.A = append(data.A, dnsv1alpha1.Ipv4String(ip.String()))
+			}
+		case "AAAA":
+			if ip := net.ParseIP(value); ip != nil && ip.To4() == nil {
+				data.AAAA = append(data.AAAA, dnsv1alpha1.Ipv6String(ip.String()))
+			}
+		case "CNAME":
+			// A CNAME record can only have a single target
+			if len(data.CNAME) == 0 {
+				name, err := dnsname.NewName(value)
+				if err != nil {
+					return nil, err
+				}
+				data.CNAME = []dnsname.Name{*name.ToFQDN()}
+			}
+		case "TXT":
+			data.TXT = append(data.TXT, value)
+		default:
+			return nil, fmt.Errorf("Unsupported record type %s for valueFrom", rtype)
+		}
+	}
+
+	sort.Slice(data.A, func(i, j int) bool { return data.A[i] < data.A[j] })
+	sort.Slice(data.AAAA, func(i, j int) bool { return data.AAAA[i] < data.AAAA[j] })
+	sort.Strings(data.TXT)
+
+	if data.A == nil && data.AAAA == nil && data.CNAME == nil && data.TXT == nil {
+		return nil, fmt.Errorf("The referenced resource contains no values for a %s record", rtype)
+	}
+	return &data, nil
+}
+
+// isNodeAvailable tells whether a node can receive traffic, i.e. it is Ready and it is not cordoned.
+func isNodeAvailable(node *corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// nodeChanged filters the updates to Nodes which can change the records referencing them,
+// ignoring the frequent updates caused by the heartbeats of the nodes.
+func nodeChanged(e event.UpdateEvent) bool {
+	oldNode, ok1 := e.ObjectOld.(*corev1.Node)
+	newNode, ok2 := e.ObjectNew.(*corev1.Node)
+	if !ok1 || !ok2 {
+		return true
+	}
+	return isNodeAvailable(oldNode) != isNodeAvailable(newNode) ||
+		!equality.Semantic.DeepEqual(oldNode.Status.Addresses, newNode.Status.Addresses) ||
+		!equality.Semantic.DeepEqual(oldNode.Labels, newNode.Labels)
+}
+
+// listRecordsReferencing returns a function mapping the resources of the given kind
+// to the DNSRecords referencing them with `valueFrom`.
+func (r *DNSRecordReconciler) listRecordsReferencing(kind schema.GroupKind) handler.ToRequestsFunc {
+	return func(obj handler.MapObject) []ctrl.Request {
+		keys := []string{valueFromKey(kind, obj.Meta.GetNamespace(), obj.Meta.GetName())}
+		if kind == nodeKind {
+			keys = append(keys, valueFromKey(nodeKind, "", ""))
+		}
+
+		var res []ctrl.Request
+		for _, key := range keys {
+			var list dnsv1alpha1.DNSRecordList
+			if err := r.List(r.Context.RootContext, &list, client.MatchingField(valueFromIndex, key)); err != nil {
+				r.Log.Error(err, "Cannot list DNSRecords impacted by a change to a referenced resource", "resource", key)
+				return nil
+			}
+			for _, record := range list.Items {
+				res = append(res, ctrl.Request{
+					NamespacedName: k8stypes.NamespacedName{
+						Name:      record.Name,
+						Namespace: record.Namespace,
+					},
+				})
+			}
+		}
+		return res
+	}
+}
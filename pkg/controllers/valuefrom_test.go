@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+)
+
+func TestSplitValues(t *testing.T) {
+	require := require.New(t)
+
+	table := []struct {
+		rtype    string
+		value    string
+		expected []string
+	}{
+		{"A", "1.1.1.1", []string{"1.1.1.1"}},
+		{"A", "1.1.1.1, 2.2.2.2\n3.3.3.3\t4.4.4.4\r\n", []string{"1.1.1.1", "2.2.2.2", "3.3.3.3", "4.4.4.4"}},
+		{"CNAME", ",,", []string{}},
+		{"TXT", "some text, with commas", []string{"some text, with commas"}},
+		{"TXT", "", []string{""}},
+	}
+
+	for _, entry := range table {
+		require.Equal(entry.expected, splitValues(entry.rtype, entry.value), "Type: %s, Value: %q", entry.rtype, entry.value)
+	}
+}
+
+func TestEvalJSONPath(t *testing.T) {
+	require := require.New(t)
+
+	obj := map[string]interface{}{
+		"status": map[string]interface{}{
+			"podIP": "10.0.0.1",
+			"podIPs": []interface{}{
+				map[string]interface{}{"ip": "10.0.0.1"},
+				map[string]interface{}{"ip": "fd00::1"},
+			},
+			"ports":     []interface{}{int64(80), int64(443)},
+			"hostnames": []interface{}{"a.example.com", "b.example.com"},
+		},
+	}
+
+	table := []struct {
+		expression string
+		expected   []string
+		success    bool
+	}{
+		{"{.status.podIP}", []string{"10.0.0.1"}, true},
+		{".status.podIP", []string{"10.0.0.1"}, true},
+		{"{.status.podIPs[*].ip}", []string{"10.0.0.1", "fd00::1"}, true},
+		{"{.status.hostnames}", []string{"a.example.com", "b.example.com"}, true},
+		{"{.status.ports[1]}", []string{"443"}, true},
+		{"{.status.missing}", nil, false},
+		{"{.status[}", nil, false},
+	}
+
+	for _, entry := range table {
+		actual, err := evalJSONPath(entry.expression, obj)
+		if !entry.success {
+			require.NotNil(err, "Expression: %s", entry.expression)
+			continue
+		}
+		require.Nil(err, "Expression: %s", entry.expression)
+		require.Equal(entry.expected, actual, "Expression: %s", entry.expression)
+	}
+}
+
+func TestToRRSetData(t *testing.T) {
+	require := require.New(t)
+
+	table := []struct {
+		rtype    string
+		values   []string
+		expected *dnsv1alpha1.DNSRecordSetData
+	}{
+		{
+			"A", []string{"2.2.2.2", " 1.1.1.1 ", "fd00::1", "invalid", ""},
+			&dnsv1alpha1.DNSRecordSetData{A: []dnsv1alpha1.Ipv4String{"1.1.1.1", "2.2.2.2"}},
+		},
+		{
+			"AAAA", []string{"1.1.1.1", "FD00::2", "fd00::1"},
+			&dnsv1alpha1.DNSRecordSetData{AAAA: []dnsv1alpha1.Ipv6String{"fd00::1", "fd00::2"}},
+		},
+		{
+			"CNAME", []string{"", "lb.example.com", "other.example.com"},
+			&dnsv1alpha1.DNSRecordSetData{CNAME: []dnsname.Name{mustName("lb.example.com.")}},
+		},
+		{
+			"TXT", []string{"b", "a", ""},
+			&dnsv1alpha1.DNSRecordSetData{TXT: []string{"", "a", "b"}},
+		},
+		{"A", []string{"fd00::1"}, nil},
+		{"AAAA", nil, nil},
+		{"CNAME", []string{"invalid..name"}, nil},
+		{"MX", []string{"mail.example.com"}, nil},
+	}
+
+	for _, entry := range table {
+		actual, err := toRRSetData(entry.rtype, entry.values)
+		if entry.expected == nil {
+			require.NotNil(err, "Type: %s, Values: %v", entry.rtype, entry.values)
+			continue
+		}
+		require.Nil(err, "Type: %s, Values: %v", entry.rtype, entry.values)
+		require.Equal(entry.expected, actual, "Type: %s, Values: %v", entry.rtype, entry.values)
+	}
+}
+
+func TestFieldRefPolicy(t *testing.T) {
+	require := require.New(t)
+
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{{Version: "v1"}, {Group: "apps", Version: "v1"}})
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Node"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, meta.RESTScopeNamespace)
+	policy := &FieldRefPolicy{
+		Kinds: []schema.GroupKind{
+			{Kind: "Pod"}, {Group: "apps", Kind: "Deployment"}, {Kind: "Node"}, {Kind: "Secret"}, {Kind: "Missing"},
+		},
+		Mapper: mapper,
+	}
+
+	table := []struct {
+		apiVersion string
+		kind       string
+		expected   schema.GroupVersionKind
+		success    bool
+	}{
+		{"v1", "Pod", schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, true},
+		{"apps/v1beta2", "Deployment", schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, true},
+		{"extensions/v1beta1", "Deployment", schema.GroupVersionKind{}, false},
+		{"v1", "Secret", schema.GroupVersionKind{}, false},
+		{"v1", "Node", schema.GroupVersionKind{}, false},
+		{"v1", "Missing", schema.GroupVersionKind{}, false},
+		{"v1", "ConfigMap", schema.GroupVersionKind{}, false},
+	}
+
+	for _, entry := range table {
+		ref := &dnsv1alpha1.ObjectFieldSelector{APIVersion: entry.apiVersion, Kind: entry.kind}
+		gvk, err := policy.Resolve(ref)
+		if !entry.success {
+			require.NotNil(err, "Reference: %s %s", entry.apiVersion, entry.kind)
+			continue
+		}
+		require.Nil(err, "Reference: %s %s", entry.apiVersion, entry.kind)
+		require.Equal(entry.expected, gvk, "Reference: %s %s", entry.apiVersion, entry.kind)
+	}
+}
+
+func TestIsNodeAvailable(t *testing.T) {
+	require := require.New(t)
+
+	node := func(unschedulable bool, conditions ...corev1.NodeCondition) *corev1.Node {
+		return &corev1.Node{
+			Spec:   corev1.NodeSpec{Unschedulable: unschedulable},
+			Status: corev1.NodeStatus{Conditions: conditions},
+		}
+	}
+	ready := func(status corev1.ConditionStatus) corev1.NodeCondition {
+		return corev1.NodeCondition{Type: corev1.NodeReady, Status: status}
+	}
+	pressure := corev1.NodeCondition{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionTrue}
+
+	table := []struct {
+		description string
+		node        *corev1.Node
+		expected    bool
+	}{
+		{"ready", node(false, ready(corev1.ConditionTrue)), true},
+		{"ready with other conditions", node(false, pressure, ready(corev1.ConditionTrue)), true},
+		{"not ready", node(false, ready(corev1.ConditionFalse)), false},
+		{"unknown readiness", node(false, ready(corev1.ConditionUnknown)), false},
+		{"no ready condition", node(false, pressure), false},
+		{"no conditions", node(false), false},
+		{"cordoned", node(true, ready(corev1.ConditionTrue)), false},
+	}
+
+	for _, entry := range table {
+		require.Equal(entry.expected, isNodeAvailable(entry.node), entry.description)
+	}
+}
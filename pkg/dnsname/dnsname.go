@@ -10,11 +10,17 @@ import (
 //
 // Domain names are a sequence of dot-separated labels, each of which:
 // - Can use only the following characters: [a-zA-Z0-9\.\-]
+// - Can optionally start with an underscore, as used by service labels (e.g., `_sip._tcp`, `_dmarc`)
 // - Does not start nor end with a dash
 // - Is between 1 and 63 chars long
-var nameRegexp = regexp.MustCompile("^(?:(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9\\-]{0,61}[a-zA-Z0-9])\\.)*(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9\\-]{0,61}[a-zA-Z0-9])\\.?$")
+//
+// Additionally, the leftmost label can be a single `*` to represent a wildcard name.
+const labelRegexp = "(?:_[a-zA-Z0-9](?:[a-zA-Z0-9\\-]{0,60}[a-zA-Z0-9])?|[a-zA-Z0-9](?:[a-zA-Z0-9\\-]{0,61}[a-zA-Z0-9])?)"
+
+var nameRegexp = regexp.MustCompile("^(?:" + labelRegexp + "\\.)*" + labelRegexp + "\\.?$")
 
 // Name represents a valid DNS resource name.
+// Internationalized domain names are accepted and normalized to their A-label (punycode) form.
 // +kubebuilder:validation:Type=string
 type Name struct {
 	// We have a json tag here only because kubebuilder cannot handle a struct without tags,
@@ -24,11 +30,22 @@ type Name struct {
 
 // NewName validates the given domain name and constructs a new Name.
 // The domain name can be either be fully qualified or not.
+// Labels containing non-ASCII characters are converted to their A-label form following IDNA2008/UTS-46.
 func NewName(name string) (*Name, error) {
-	if !isValidDomainName(name) {
-		return nil, &invalidNameError{name: name}
+	ascii, err := parseName(name)
+	if err != nil {
+		return nil, err
 	}
-	return &Name{name: name}, nil
+	return &Name{name: ascii}, nil
+}
+
+// NewRecordName is like NewName, but additionally accepts `@` to refer to the apex of the zone a relative record name
+// is resolved against. `@` is meaningful only as the name of a record, so NewName rejects it.
+func NewRecordName(name string) (*Name, error) {
+	if name == "@" {
+		return &Name{name: name}, nil
+	}
+	return NewName(name)
 }
 
 // IsRoot returns `true` when the domain name is the root domain ".".
@@ -36,6 +53,11 @@ func (name *Name) IsRoot() bool {
 	return name.name == "."
 }
 
+// IsApex returns `true` when the domain name is `@`, which denotes the apex of the zone a relative name is resolved against.
+func (name *Name) IsApex() bool {
+	return name.name == "@"
+}
+
 // IsFQDN return `true` when the domain name is a Fully Qualified Domain Name (i.e., it ends with a dot).
 func (name *Name) IsFQDN() bool {
 	return name.name[len(name.name)-1:] == "."
@@ -49,28 +71,142 @@ func (name *Name) ToFQDN() *Name {
 	return &Name{name: name.name + "."}
 }
 
+// IsWildcard returns `true` when the leftmost label of the domain name is `*`.
+func (name *Name) IsWildcard() bool {
+	return name.name == "*" || strings.HasPrefix(name.name, "*.")
+}
+
 // IsChildOf returns `true` if this domain name is a child of the given parent name.
-// A domain is a child of another one if the latter is a suffix of the former.
-// Note: this method ignores the final dot of a FQDN.
+//
+// Deprecated: use IsSubdomainOf, which has the same semantics.
 func (name *Name) IsChildOf(parent *Name) bool {
-	childName := name.name
+	return name.IsSubdomainOf(parent)
+}
+
+// Labels returns the labels composing this domain name, from the leftmost to the rightmost.
+// The root domain has no labels.
+func (name *Name) Labels() []string {
+	trimmed := strings.TrimSuffix(name.name, ".")
+	if trimmed == "" {
+		return []string{}
+	}
+	return strings.Split(trimmed, ".")
+}
+
+// Parent returns the domain name obtained by removing the leftmost label of this one.
+// The parent of a single-label name is the root domain, and the root domain has no parent (nil is returned).
+func (name *Name) Parent() *Name {
+	labels := name.Labels()
+	if len(labels) == 0 {
+		return nil
+	}
+	if len(labels) == 1 {
+		return &Name{name: "."}
+	}
+
+	parent := strings.Join(labels[1:], ".")
+	if name.IsFQDN() {
+		parent += "."
+	}
+	return &Name{name: parent}
+}
+
+// Equal returns `true` if the two domain names are the same.
+// The comparison is case-insensitive and ignores the final dot of a FQDN.
+func (name *Name) Equal(other *Name) bool {
+	return strings.EqualFold(strings.TrimSuffix(name.name, "."), strings.TrimSuffix(other.name, "."))
+}
+
+// IsSubdomainOf returns `true` if this domain name is equal to or below the given parent name.
+// The comparison is performed label by label, so `badexample.com` is not a subdomain of `example.com`.
+// A wildcard name is a subdomain of the names its wildcard label expands under,
+// but only a wildcard name itself is a subdomain of a wildcard name, since it does not identify a single node of the tree.
+// Note: this method is case-insensitive and ignores the final dot of a FQDN.
+func (name *Name) IsSubdomainOf(parent *Name) bool {
+	if parent.IsWildcard() {
+		return name.Equal(parent)
+	}
+
+	childLabels := name.Labels()
+	parentLabels := parent.Labels()
+	if len(parentLabels) > len(childLabels) {
+		return false
+	}
+
+	offset := len(childLabels) - len(parentLabels)
+	for i, label := range parentLabels {
+		if !strings.EqualFold(label, childLabels[offset+i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// RelativeTo returns this domain name relative to the given zone, i.e., without the labels of the zone.
+// The zone apex is represented by `@`.
+func (name *Name) RelativeTo(zone *Name) (string, error) {
+	if !name.IsSubdomainOf(zone) {
+		return "", &notInZoneError{name: name.name, zone: zone.name}
+	}
+
+	labels := name.Labels()
+	relative := labels[:len(labels)-len(zone.Labels())]
+	if len(relative) == 0 {
+		return "@", nil
+	}
+	return strings.Join(relative, "."), nil
+}
+
+// Resolve returns the fully qualified domain name obtained by interpreting this name relative to the given origin.
+// Fully qualified names are returned as they are, and `@` resolves to the origin itself.
+func (name *Name) Resolve(origin *Name) (*Name, error) {
 	if name.IsFQDN() {
-		childName = childName[0 : len(childName)-1]
+		return name, nil
+	}
+	if name.IsApex() {
+		return origin.ToFQDN(), nil
 	}
+	if origin.IsRoot() {
+		return name.ToFQDN(), nil
+	}
+	return NewName(name.name + "." + origin.ToFQDN().name)
+}
 
-	parentName := parent.name
-	if parent.IsFQDN() {
-		parentName = parentName[0 : len(parentName)-1]
+// Compare compares two domain names using the canonical ordering defined in RFC 4034, section 6.1.
+// Names are sorted by their labels starting from the rightmost one, comparing labels case-insensitively
+// as byte strings. The result is 0 if the names are equal, -1 if this name sorts first, +1 otherwise.
+func (name *Name) Compare(other *Name) int {
+	labels := name.Labels()
+	otherLabels := other.Labels()
+
+	for i := 1; i <= len(labels) && i <= len(otherLabels); i++ {
+		label := strings.ToLower(labels[len(labels)-i])
+		otherLabel := strings.ToLower(otherLabels[len(otherLabels)-i])
+		if c := strings.Compare(label, otherLabel); c != 0 {
+			return c
+		}
 	}
 
-	return strings.HasSuffix(childName, parentName)
+	switch {
+	case len(labels) < len(otherLabels):
+		return -1
+	case len(labels) > len(otherLabels):
+		return 1
+	default:
+		return 0
+	}
 }
 
-// String returns a string representation of this domain name.
+// String returns a string representation of this domain name, using the A-label form for internationalized labels.
 func (name *Name) String() string {
 	return name.name
 }
 
+// Unicode returns the display form of this domain name, with all the A-labels converted back to Unicode.
+func (name *Name) Unicode() string {
+	return toUnicode(name.name)
+}
+
 // UnmarshalJSON parses the given JSON data as a domain name.
 func (name *Name) UnmarshalJSON(data []byte) error {
 
@@ -81,11 +217,12 @@ func (name *Name) UnmarshalJSON(data []byte) error {
 	}
 
 	// Validate the domain name
-	if !isValidDomainName(s) {
-		return &invalidNameError{name: s}
+	ascii, err := parseName(s)
+	if err != nil {
+		return err
 	}
 
-	name.name = s
+	name.name = ascii
 	return nil
 
 }
@@ -95,6 +232,15 @@ func (name *Name) MarshalJSON() ([]byte, error) {
 	return json.Marshal(name.name)
 }
 
+// parseName converts the given name to its ASCII form and validates it.
+func parseName(name string) (string, error) {
+	ascii, err := toASCII(name)
+	if err != nil || !isValidDomainName(ascii) {
+		return "", &invalidNameError{name: name}
+	}
+	return ascii, nil
+}
+
 func isValidDomainName(name string) bool {
 
 	// No empty domain names
@@ -112,6 +258,12 @@ func isValidDomainName(name string) bool {
 		return true
 	}
 
+	// A wildcard label is allowed only in the leftmost position
+	if name == "*" || name == "*." {
+		return true
+	}
+	name = strings.TrimPrefix(name, "*.")
+
 	// Validate the name using a regex
 	if !nameRegexp.MatchString(name) {
 		return false
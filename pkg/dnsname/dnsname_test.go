@@ -41,6 +41,40 @@ func TestConstructor(t *testing.T) {
 		{"a-.b.c", false},
 		{"-a-.b.c", false},
 
+		// Service labels used by SRV records
+		{"_sip._tcp.example.com", true},
+		{"_xmpp-server._tcp.example.com.", true},
+		{"__sip._tcp.example.com", false},
+		{"_-sip._tcp.example.com", false},
+		{"_._tcp.example.com", false},
+		{"_" + strings.Repeat("a", 62), true},
+		{"_" + strings.Repeat("a", 63), false},
+
+		// Underscore labels used by DMARC, DKIM and ACME
+		{"_dmarc.example.com", true},
+		{"_acme-challenge.example.com", true},
+		{"selector1._domainkey.example.com", true},
+		{"a_b.example.com", false},
+		{"_a_.example.com", false},
+
+		// Wildcards are allowed only as a single leftmost label
+		{"*", true},
+		{"*.", true},
+		{"*.example.com", true},
+		{"*.example.com.", true},
+		{"*._tcp.example.com", true},
+		{"a.*.example.com", false},
+		{"*.*.example.com", false},
+		{"**.example.com", false},
+		{"*a.example.com", false},
+		{"a*.example.com", false},
+		{"*example.com", false},
+
+		// The zone apex is accepted only by NewRecordName
+		{"@", false},
+		{"@.", false},
+		{"@.example.com", false},
+
 		// Length tests
 		{strings.Repeat("a", 63), true},
 		{strings.Repeat("a", 64), false},
@@ -89,6 +123,19 @@ func TestAccessors(t *testing.T) {
 	require.False(name.IsRoot())
 	require.True(name.IsFQDN())
 
+	// Wildcards
+	require.False(name.IsWildcard())
+	name, err = NewName("*.example.com")
+	require.Nil(err)
+	require.True(name.IsWildcard())
+	require.True(name.ToFQDN().IsWildcard())
+	name, err = NewName("*")
+	require.Nil(err)
+	require.True(name.IsWildcard())
+	name, err = NewName("_dmarc.example.com")
+	require.Nil(err)
+	require.False(name.IsWildcard())
+
 }
 
 func TestIsChildOf(t *testing.T) {
@@ -106,6 +153,16 @@ func TestIsChildOf(t *testing.T) {
 		{"example.net", "com", false},
 		{"example.net", ".", true},
 		{"com", ".", true},
+		{"*.example.com", "example.com", true},
+		{"*.example.com", "com", true},
+		{"*.example.com", "example2.com", false},
+		{"foo.example.com", "*.example.com", false},
+		{"*.example.com", "*.example.com", true},
+		{"_dmarc.example.com", "example.com", true},
+		{"badexample.com", "example.com", false},
+		{"example.com", "ample.com", false},
+		{"foo.Example.COM", "example.com", true},
+		{"com", "example.com", false},
 	}
 
 	for _, entry := range table {
@@ -126,6 +183,213 @@ func TestIsChildOf(t *testing.T) {
 
 }
 
+func TestIsSubdomainOf(t *testing.T) {
+	require := require.New(t)
+
+	table := []struct {
+		child    string
+		parent   string
+		expected bool
+	}{
+		{"example.com", "example.com", true},
+		{"a.b.example.com", "example.com", true},
+		{"a.b.example.com", "b.example.com", true},
+		{"a.b.example.com", "a.example.com", false},
+		{"badexample.com", "example.com", false},
+		{"EXAMPLE.com", "example.COM", true},
+		{"example.com", ".", true},
+		{".", ".", true},
+		{".", "com", false},
+
+		// Wildcards
+		{"*.example.com", "example.com", true},
+		{"*.example.com", "*.example.com", true},
+		{"*.EXAMPLE.com", "*.example.com.", true},
+		{"a.example.com", "*.example.com", false},
+		{"*.a.example.com", "*.example.com", false},
+	}
+
+	for _, entry := range table {
+		child, err := NewName(entry.child)
+		require.Nil(err)
+		parent, err := NewName(entry.parent)
+		require.Nil(err)
+
+		require.Equal(entry.expected, child.IsSubdomainOf(parent), "Child: %s, Parent: %s", child.String(), parent.String())
+		require.Equal(entry.expected, child.ToFQDN().IsSubdomainOf(parent.ToFQDN()), "Child: %s, Parent: %s", child.String(), parent.String())
+	}
+}
+
+func TestLabelsAndParent(t *testing.T) {
+	require := require.New(t)
+
+	table := []struct {
+		name   string
+		labels []string
+		parent string
+	}{
+		{".", []string{}, ""},
+		{"com", []string{"com"}, "."},
+		{"com.", []string{"com"}, "."},
+		{"example.com", []string{"example", "com"}, "com"},
+		{"example.com.", []string{"example", "com"}, "com."},
+		{"_sip._tcp.example.com", []string{"_sip", "_tcp", "example", "com"}, "_tcp.example.com"},
+		{"*.example.com", []string{"*", "example", "com"}, "example.com"},
+	}
+
+	for _, entry := range table {
+		name, err := NewName(entry.name)
+		require.Nil(err)
+		require.Equal(entry.labels, name.Labels(), "Name: %s", entry.name)
+		if entry.parent == "" {
+			require.Nil(name.Parent(), "Name: %s", entry.name)
+		} else {
+			require.Equal(entry.parent, name.Parent().String(), "Name: %s", entry.name)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	require := require.New(t)
+
+	table := []struct {
+		a        string
+		b        string
+		expected bool
+	}{
+		{"example.com", "example.com", true},
+		{"example.com", "example.com.", true},
+		{"Example.COM", "example.com", true},
+		{"example.com", "example.net", false},
+		{"www.example.com", "example.com", false},
+		{".", ".", true},
+	}
+
+	for _, entry := range table {
+		a, err := NewName(entry.a)
+		require.Nil(err)
+		b, err := NewName(entry.b)
+		require.Nil(err)
+		require.Equal(entry.expected, a.Equal(b), "A: %s, B: %s", entry.a, entry.b)
+		require.Equal(entry.expected, b.Equal(a), "A: %s, B: %s", entry.a, entry.b)
+	}
+}
+
+func TestRelativeTo(t *testing.T) {
+	require := require.New(t)
+
+	table := []struct {
+		name     string
+		zone     string
+		expected string
+		success  bool
+	}{
+		{"api.example.com", "example.com", "api", true},
+		{"a.b.example.com.", "example.com", "a.b", true},
+		{"example.com", "example.com.", "@", true},
+		{"*.example.com", "example.com", "*", true},
+		{"API.Example.com", "example.com", "API", true},
+		{"badexample.com", "example.com", "", false},
+		{"example.com", "api.example.com", "", false},
+	}
+
+	for _, entry := range table {
+		name, err := NewName(entry.name)
+		require.Nil(err)
+		zone, err := NewName(entry.zone)
+		require.Nil(err)
+
+		relative, err := name.RelativeTo(zone)
+		if entry.success {
+			require.Nil(err, "Name: %s, Zone: %s", entry.name, entry.zone)
+			require.Equal(entry.expected, relative)
+		} else {
+			require.NotNil(err, "Name: %s, Zone: %s", entry.name, entry.zone)
+			require.IsType(&notInZoneError{}, err)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	require := require.New(t)
+
+	table := []struct {
+		name     string
+		origin   string
+		expected string
+		success  bool
+	}{
+		{"api", "example.com", "api.example.com.", true},
+		{"a.b", "example.com.", "a.b.example.com.", true},
+		{"@", "example.com", "example.com.", true},
+		{"*", "example.com", "*.example.com.", true},
+		{"_dmarc", "example.com", "_dmarc.example.com.", true},
+		{"api.example.com.", "example.net", "api.example.com.", true},
+		{"api", ".", "api.", true},
+		{strings.Repeat("a.b.", 62) + "a", "example.com", "", false},
+	}
+
+	for _, entry := range table {
+		name, err := NewRecordName(entry.name)
+		require.Nil(err)
+		origin, err := NewName(entry.origin)
+		require.Nil(err)
+
+		resolved, err := name.Resolve(origin)
+		if entry.success {
+			require.Nil(err, "Name: %s, Origin: %s", entry.name, entry.origin)
+			require.Equal(entry.expected, resolved.String())
+		} else {
+			require.NotNil(err, "Name: %s, Origin: %s", entry.name, entry.origin)
+		}
+	}
+
+	// Apex
+	name, err := NewRecordName("@")
+	require.Nil(err)
+	require.True(name.IsApex())
+	require.False(name.IsFQDN())
+	_, err = NewRecordName("@.example.com")
+	require.NotNil(err)
+}
+
+func TestCompare(t *testing.T) {
+	require := require.New(t)
+
+	// Example taken from RFC 4034, section 6.1 (without the escaped labels)
+	ordered := []string{
+		"example",
+		"a.example",
+		"yljkjljk.a.example",
+		"Z.a.example",
+		"zABC.a.EXAMPLE",
+		"z.example",
+		"*.z.example",
+	}
+
+	for i := range ordered {
+		for j := range ordered {
+			a, err := NewName(ordered[i])
+			require.Nil(err)
+			b, err := NewName(ordered[j])
+			require.Nil(err)
+
+			expected := 0
+			if i < j {
+				expected = -1
+			} else if i > j {
+				expected = 1
+			}
+			require.Equal(expected, a.Compare(b), "A: %s, B: %s", ordered[i], ordered[j])
+		}
+	}
+
+	// Case and final dot do not matter
+	a, _ := NewName("Example.COM.")
+	b, _ := NewName("example.com")
+	require.Equal(0, a.Compare(b))
+}
+
 func TestJSON(t *testing.T) {
 	require := require.New(t)
 
@@ -146,3 +410,55 @@ func TestJSON(t *testing.T) {
 	require.NotNil(err)
 
 }
+
+func TestIDNA(t *testing.T) {
+	require := require.New(t)
+
+	table := []struct {
+		input   string
+		ascii   string
+		unicode string
+		success bool
+	}{
+		// Unicode input is normalized to the A-label form
+		{"bücher.example.com", "xn--bcher-kva.example.com", "bücher.example.com", true},
+		{"Bücher.example.com.", "xn--bcher-kva.example.com.", "bücher.example.com.", true},
+		{"città.it", "xn--citt-3na.it", "città.it", true},
+		{"_dmarc.straße.de", "_dmarc.xn--strae-oqa.de", "_dmarc.straße.de", true},
+		{"*.münchen.de", "*.xn--mnchen-3ya.de", "*.münchen.de", true},
+		{"bücher。example。com", "xn--bcher-kva.example.com", "bücher.example.com", true},
+
+		// A-labels are accepted as they are
+		{"xn--bcher-kva.example.com", "xn--bcher-kva.example.com", "bücher.example.com", true},
+
+		// ASCII names are not touched
+		{"Example.COM", "Example.COM", "Example.COM", true},
+
+		// Invalid input
+		{"xn--a.example.com", "", "", false},
+		{"bü cher.example.com", "", "", false},
+		{"bücher..example.com", "", "", false},
+		{strings.Repeat("ü", 60) + ".com", "", "", false},
+	}
+
+	for _, entry := range table {
+		name, err := NewName(entry.input)
+		if entry.success {
+			require.Nil(err, "Valid domain name %s failed parsing", entry.input)
+			require.Equal(entry.ascii, name.String())
+			require.Equal(entry.unicode, name.Unicode())
+		} else {
+			require.NotNil(err, "Invalid domain name %s has been correctly parsed", entry.input)
+			require.IsType(&invalidNameError{}, err)
+		}
+	}
+
+	// JSON uses the A-label form
+	var name Name
+	err := json.Unmarshal([]byte("\"bücher.example.com\""), &name)
+	require.Nil(err)
+	require.Equal("xn--bcher-kva.example.com", name.String())
+	data, err := json.Marshal(&name)
+	require.Nil(err)
+	require.Equal("\"xn--bcher-kva.example.com\"", string(data))
+}
@@ -12,3 +12,12 @@ func (e *invalidNameError) Error() string {
 	}
 	return fmt.Sprintf("Invalid domain name: %s", e.name)
 }
+
+type notInZoneError struct {
+	name string
+	zone string
+}
+
+func (e *notInZoneError) Error() string {
+	return fmt.Sprintf("Domain name %s is not in zone %s", e.name, e.zone)
+}
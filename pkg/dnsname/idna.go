@@ -0,0 +1,73 @@
+package dnsname
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// Profile used to convert internationalized labels, following the UTS-46 non-transitional processing,
+// which is compatible with IDNA2008 (i.e., deviation characters like `ß` are preserved).
+var idnaProfile = idna.New(idna.MapForLookup(), idna.BidiRule())
+
+// Full stops which UTS-46 maps to the ASCII label separator.
+var dotsReplacer = strings.NewReplacer("。", ".", "．", ".", "｡", ".")
+
+// acePrefix is the prefix of the A-labels of internationalized domain names.
+const acePrefix = "xn--"
+
+// toASCII converts every non-ASCII label of the given name to its A-label form.
+// ASCII labels are kept untouched (underscore and wildcard labels are not valid IDNA input),
+// except for A-labels which are checked to be valid punycode.
+func toASCII(name string) (string, error) {
+	if !isASCII(name) {
+		name = dotsReplacer.Replace(name)
+	}
+
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		if isASCII(label) {
+			if hasACEPrefix(label) {
+				if _, err := idnaProfile.ToUnicode(label); err != nil {
+					return "", err
+				}
+			}
+			continue
+		}
+
+		ascii, err := idnaProfile.ToASCII(label)
+		if err != nil {
+			return "", err
+		}
+		labels[i] = ascii
+	}
+
+	return strings.Join(labels, "."), nil
+}
+
+// toUnicode converts every A-label of the given name back to Unicode.
+func toUnicode(name string) string {
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		if hasACEPrefix(label) {
+			if unicode, err := idnaProfile.ToUnicode(label); err == nil {
+				labels[i] = unicode
+			}
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+func hasACEPrefix(label string) bool {
+	return len(label) >= len(acePrefix) && strings.EqualFold(label[:len(acePrefix)], acePrefix)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+// Package metrics contains the Prometheus metrics exposed by the operator.
+// All the metrics are registered on the controller-runtime registry,
+// so that they are served by the metrics endpoint of the manager (`--metrics-addr`).
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+const namespace = "dns_operator"
+
+var (
+	// ProviderOperations counts the operations performed against the backends of the providers.
+	ProviderOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "provider",
+		Name:      "operations_total",
+		Help:      "Number of operations performed against the backends of the DNS providers.",
+	}, []string{"provider_type", "zone", "operation"})
+
+	// ProviderOperationErrors counts the operations performed against the backends of the providers which failed.
+	ProviderOperationErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "provider",
+		Name:      "operation_errors_total",
+		Help:      "Number of operations performed against the backends of the DNS providers which failed.",
+	}, []string{"provider_type", "zone", "operation"})
+
+	// ProviderOperationDuration measures the duration of the operations performed against the backends of the providers.
+	ProviderOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "provider",
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of the operations performed against the backends of the DNS providers.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider_type", "zone", "operation"})
+
+	// ProviderRateLimits counts the requests rejected by the backends of the providers because of rate limiting.
+	ProviderRateLimits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "provider",
+		Name:      "rate_limits_total",
+		Help:      "Number of requests rejected by the backends of the DNS providers because of rate limiting.",
+	}, []string{"provider_type"})
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(
+		ProviderOperations,
+		ProviderOperationErrors,
+		ProviderOperationDuration,
+		ProviderRateLimits,
+	)
+}
+
+// ObserveOperation records an operation performed by a provider of the given type on a zone,
+// started at `start` and completed with `err`. The zone is expected as a lowercase FQDN, so that the
+// same zone always has the same label. Operations not supported by the provider are not recorded.
+func ObserveOperation(providerType, zone, operation string, start time.Time, err error) {
+	if errors.Is(err, types.ErrNotSupported) {
+		return
+	}
+	ProviderOperations.WithLabelValues(providerType, zone, operation).Inc()
+	ProviderOperationDuration.WithLabelValues(providerType, zone, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		ProviderOperationErrors.WithLabelValues(providerType, zone, operation).Inc()
+	}
+}
+
+// rateLimitTransport counts the HTTP responses signalling that the backend of a provider is rate limiting the operator.
+type rateLimitTransport struct {
+	providerType string
+	next         http.RoundTripper
+}
+
+// RateLimitTransport wraps an http.RoundTripper counting the `429 Too Many Requests` responses
+// received from the backend of a provider of the given type.
+// If `next` is nil, http.DefaultTransport is used.
+func RateLimitTransport(providerType string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &rateLimitTransport{providerType: providerType, next: next}
+}
+
+// RoundTrip performs the request with the wrapped transport, counting the rate limited responses.
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err == nil && res.StatusCode == http.StatusTooManyRequests {
+		ProviderRateLimits.WithLabelValues(t.providerType).Inc()
+	}
+	return res, err
+}
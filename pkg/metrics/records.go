@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+)
+
+// recordsCollector reports the number of DNSRecords by namespace and status of the Ready condition.
+// Records are counted at each scrape, so that deleted records never leave stale series behind.
+type recordsCollector struct {
+	client client.Reader
+	desc   *prometheus.Desc
+}
+
+// NewRecordsCollector returns a collector reporting the number of DNSRecords by namespace and Ready status,
+// reading the records with the given client.
+func NewRecordsCollector(c client.Reader) prometheus.Collector {
+	return &recordsCollector{
+		client: c,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "records"),
+			"Number of DNSRecords by namespace and status of the Ready condition.",
+			[]string{"namespace", "ready"},
+			nil,
+		),
+	}
+}
+
+// Describe sends the descriptor of the metric to the given channel.
+func (c *recordsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect counts the DNSRecords and sends the resulting metrics to the given channel.
+func (c *recordsCollector) Collect(ch chan<- prometheus.Metric) {
+	var list dnsv1alpha1.DNSRecordList
+	if err := c.client.List(context.Background(), &list); err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+
+	type key struct{ namespace, ready string }
+	counts := make(map[key]int)
+	for _, record := range list.Items {
+		ready := string(dnsv1alpha1.UnknownStatus)
+		if i := record.Status.GetCondition(dnsv1alpha1.ReadyCondition); i >= 0 {
+			ready = string(record.Status.Conditions[i].Status)
+		}
+		counts[key{record.Namespace, ready}]++
+	}
+
+	for k, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), k.namespace, k.ready)
+	}
+}
@@ -0,0 +1,447 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/miekg/dns"
+	"golang.org/x/oauth2/clientcredentials"
+
+	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+const (
+	azureDefaultEndpoint      = "https://management.azure.com"
+	azureDefaultAuthorityHost = "https://login.microsoftonline.com"
+	azureDNSAPIVersion        = "2018-05-01"
+	azurePrivateDNSAPIVersion = "2018-09-01"
+)
+
+// AzureDNS DNS provider.
+// It manages either public zones (`Microsoft.Network/dnsZones`) or private zones (`Microsoft.Network/privateDnsZones`).
+type AzureDNS struct {
+	log                logr.Logger
+	zones              []dnsname.Name
+	client             *http.Client
+	endpoint           string
+	subscriptionID     string
+	resourceGroup      string
+	zoneResourceGroups map[string]string
+	private            bool
+}
+
+// NewAzureDNS creates a new instance of the Azure DNS provider.
+// `zoneResourceGroups` maps the DNS zones to the resource groups containing them:
+// the zones not listed there belong to `resourceGroup`.
+// The given HTTP client must authenticate the requests.
+func NewAzureDNS(log logr.Logger, zones []dnsname.Name, client *http.Client, endpoint, subscriptionID, resourceGroup string, zoneResourceGroups map[string]string, private bool) (*AzureDNS, error) {
+	zoneResourceGroups, err := zoneMap("zoneResourceGroups", zoneResourceGroups)
+	if err != nil {
+		return nil, err
+	}
+	if resourceGroup == "" {
+		for _, zone := range zones {
+			if _, ok := zoneResourceGroups[zoneKey(&zone)]; !ok {
+				return nil, fmt.Errorf("No resource group configured for zone %s", zone.String())
+			}
+		}
+	}
+	return &AzureDNS{
+		log:                log.WithName("providers").WithName("AzureDNS"),
+		zones:              zones,
+		client:             client,
+		endpoint:           strings.TrimSuffix(endpoint, "/"),
+		subscriptionID:     subscriptionID,
+		resourceGroup:      resourceGroup,
+		zoneResourceGroups: zoneResourceGroups,
+		private:            private,
+	}, nil
+}
+
+// Zones returns a slice containing the DNS zones managed by this provider.
+func (az *AzureDNS) Zones() []dnsname.Name {
+	return az.zones
+}
+
+// UpdateRecord replaces the whole RRset on Azure DNS with the given one.
+func (az *AzureDNS) UpdateRecord(ctx context.Context, zone dnsname.Name, resource v1alpha1.DNSRecord) error {
+	u, err := az.recordSetURL(&zone, &resource.Spec.Name, resource.RType())
+	if err != nil {
+		return err
+	}
+	properties, err := toAzureProperties(&resource, az.private)
+	if err != nil {
+		return err
+	}
+
+	// Public and private zones spell the same properties differently
+	var body struct {
+		Properties interface{} `json:"properties"`
+	}
+	body.Properties = properties
+	if az.private {
+		body.Properties = azurePrivateProperties(*properties)
+	}
+
+	az.log.V(1).Info("Updating DNS record", "name", resource.Spec.Name.String(), "type", resource.RType())
+	return doJSON(ctx, az.client, http.MethodPut, u, nil, &body, nil, azureError)
+}
+
+// DeleteRecord deletes the given RRset from Azure DNS.
+func (az *AzureDNS) DeleteRecord(ctx context.Context, zone dnsname.Name, resource v1alpha1.DNSRecord) error {
+	u, err := az.recordSetURL(&zone, &resource.Spec.Name, resource.RType())
+	if err != nil {
+		return err
+	}
+
+	// Deleting a record set which does not exist succeeds
+	az.log.V(1).Info("Deleting DNS record", "name", resource.Spec.Name.String(), "type", resource.RType())
+	return doJSON(ctx, az.client, http.MethodDelete, u, nil, nil, nil, azureError)
+}
+
+// GetRecord returns the RRset registered on Azure DNS with the same name and type of the given resource.
+func (az *AzureDNS) GetRecord(ctx context.Context, zone dnsname.Name, resource v1alpha1.DNSRecord) (*v1alpha1.DNSRecord, error) {
+	u, err := az.recordSetURL(&zone, &resource.Spec.Name, resource.RType())
+	if err != nil {
+		return nil, err
+	}
+
+	var rrset azureRecordSet
+	err = doJSON(ctx, az.client, http.MethodGet, u, nil, nil, &rrset, azureError)
+	var apiErr *azureAPIError
+	if errors.As(err, &apiErr) && apiErr.isRecordNotFound() {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	set := newRecordSet()
+	if err := addAzureRecordSet(set, &zone, &rrset); err != nil {
+		return nil, err
+	}
+	return set.first(), nil
+}
+
+// ListRecords returns all the RRsets registered on Azure DNS for the given zone.
+func (az *AzureDNS) ListRecords(ctx context.Context, zone dnsname.Name) ([]v1alpha1.DNSRecord, error) {
+	zoneURL := az.zoneURL(&zone)
+	u := zoneURL + "/recordsets?api-version=" + az.apiVersion()
+	if az.private {
+		u = zoneURL + "/ALL?api-version=" + az.apiVersion()
+	}
+
+	// Follow the pagination
+	set := newRecordSet()
+	for u != "" {
+		var res struct {
+			Value    []azureRecordSet `json:"value"`
+			NextLink string           `json:"nextLink"`
+		}
+		if err := doJSON(ctx, az.client, http.MethodGet, u, nil, nil, &res, azureError); err != nil {
+			return nil, err
+		}
+		for i := range res.Value {
+			if err := addAzureRecordSet(set, &zone, &res.Value[i]); err != nil {
+				return nil, err
+			}
+		}
+		u = res.NextLink
+	}
+
+	return set.list(), nil
+}
+
+func (az *AzureDNS) apiVersion() string {
+	if az.private {
+		return azurePrivateDNSAPIVersion
+	}
+	return azureDNSAPIVersion
+}
+
+// zoneURL returns the URL of the ARM resource of the given zone.
+func (az *AzureDNS) zoneURL(zone *dnsname.Name) string {
+	resourceGroup := az.resourceGroup
+	if group, ok := az.zoneResourceGroups[zoneKey(zone)]; ok {
+		resourceGroup = group
+	}
+	kind := "dnsZones"
+	if az.private {
+		kind = "privateDnsZones"
+	}
+	return fmt.Sprintf("%s/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/%s/%s",
+		az.endpoint, url.PathEscape(az.subscriptionID), url.PathEscape(resourceGroup), kind, strings.ToLower(strings.Join(zone.Labels(), ".")))
+}
+
+// recordSetURL returns the URL of the ARM resource of the record set with the given name and type.
+// Record sets are named relative to their zone.
+func (az *AzureDNS) recordSetURL(zone *dnsname.Name, name *dnsname.Name, rtype string) (string, error) {
+	relative, err := name.ToFQDN().RelativeTo(zone.ToFQDN())
+	if err != nil {
+		return "", err
+	}
+	return az.zoneURL(zone) + "/" + rtype + "/" + url.PathEscape(strings.ToLower(relative)) + "?api-version=" + az.apiVersion(), nil
+}
+
+// azureAPIError is an error response of the Azure Resource Manager API.
+type azureAPIError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *azureAPIError) Error() string {
+	if e.code == "" {
+		return fmt.Sprintf("Azure DNS request failed with status %d", e.status)
+	}
+	return fmt.Sprintf("Azure DNS request failed: %s: %s", e.code, e.message)
+}
+
+// isRecordNotFound tells whether the error signals that a record set does not exist,
+// as opposed to its zone or its resource group.
+func (e *azureAPIError) isRecordNotFound() bool {
+	return e.status == http.StatusNotFound && e.code != "ParentResourceNotFound" && e.code != "ResourceGroupNotFound"
+}
+
+// azureError builds an error out of an error response of the Azure Resource Manager API.
+func azureError(status int, body []byte) error {
+	var res struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(body, &res)
+	return &azureAPIError{status: status, code: res.Error.Code, message: res.Error.Message}
+}
+
+// Azure DNS API payloads
+
+type azureARecord struct {
+	IPv4Address string `json:"ipv4Address"`
+}
+
+type azureAAAARecord struct {
+	IPv6Address string `json:"ipv6Address"`
+}
+
+type azureMXRecord struct {
+	Preference uint16 `json:"preference"`
+	Exchange   string `json:"exchange"`
+}
+
+type azureCNAMERecord struct {
+	CNAME string `json:"cname"`
+}
+
+type azureTXTRecord struct {
+	Value []string `json:"value"`
+}
+
+type azureSRVRecord struct {
+	Priority uint16 `json:"priority"`
+	Weight   uint16 `json:"weight"`
+	Port     uint16 `json:"port"`
+	Target   string `json:"target"`
+}
+
+type azureCAARecord struct {
+	Flags uint8  `json:"flags"`
+	Tag   string `json:"tag"`
+	Value string `json:"value"`
+}
+
+// azureProperties are the properties of a record set in a public zone.
+// Since JSON fields are matched case-insensitively when decoding, they also decode the properties of private zones.
+type azureProperties struct {
+	TTL         uint32            `json:"TTL"`
+	ARecords    []azureARecord    `json:"ARecords,omitempty"`
+	AAAARecords []azureAAAARecord `json:"AAAARecords,omitempty"`
+	MXRecords   []azureMXRecord   `json:"MXRecords,omitempty"`
+	CNAMERecord *azureCNAMERecord `json:"CNAMERecord,omitempty"`
+	TXTRecords  []azureTXTRecord  `json:"TXTRecords,omitempty"`
+	SRVRecords  []azureSRVRecord  `json:"SRVRecords,omitempty"`
+	CAARecords  []azureCAARecord  `json:"caaRecords,omitempty"`
+}
+
+// azurePrivateProperties are the properties of a record set in a private zone.
+type azurePrivateProperties struct {
+	TTL         uint32            `json:"ttl"`
+	ARecords    []azureARecord    `json:"aRecords,omitempty"`
+	AAAARecords []azureAAAARecord `json:"aaaaRecords,omitempty"`
+	MXRecords   []azureMXRecord   `json:"mxRecords,omitempty"`
+	CNAMERecord *azureCNAMERecord `json:"cnameRecord,omitempty"`
+	TXTRecords  []azureTXTRecord  `json:"txtRecords,omitempty"`
+	SRVRecords  []azureSRVRecord  `json:"srvRecords,omitempty"`
+	CAARecords  []azureCAARecord  `json:"caaRecords,omitempty"`
+}
+
+type azureRecordSet struct {
+	Name       string          `json:"name"`
+	Type       string          `json:"type"`
+	Properties azureProperties `json:"properties"`
+}
+
+// toAzureProperties converts a DNSRecord resource to the properties of an Azure record set.
+func toAzureProperties(resource *v1alpha1.DNSRecord, private bool) (*azureProperties, error) {
+	rrs, err := toRRSet(resource)
+	if err != nil {
+		return nil, err
+	}
+	if len(rrs) == 0 {
+		return nil, fmt.Errorf("Unsupported DNS record")
+	}
+
+	properties := &azureProperties{TTL: rrs[0].Header().Ttl}
+	for _, rr := range rrs {
+		switch rr := rr.(type) {
+		case *dns.A:
+			properties.ARecords = append(properties.ARecords, azureARecord{IPv4Address: rr.A.String()})
+		case *dns.AAAA:
+			properties.AAAARecords = append(properties.AAAARecords, azureAAAARecord{IPv6Address: rr.AAAA.String()})
+		case *dns.MX:
+			properties.MXRecords = append(properties.MXRecords, azureMXRecord{Preference: rr.Preference, Exchange: rr.Mx})
+		case *dns.CNAME:
+			if properties.CNAMERecord != nil {
+				return nil, fmt.Errorf("Azure DNS supports a single target for CNAME records")
+			}
+			properties.CNAMERecord = &azureCNAMERecord{CNAME: rr.Target}
+		case *dns.TXT:
+			properties.TXTRecords = append(properties.TXTRecords, azureTXTRecord{Value: rr.Txt})
+		case *dns.SRV:
+			properties.SRVRecords = append(properties.SRVRecords, azureSRVRecord{Priority: rr.Priority, Weight: rr.Weight, Port: rr.Port, Target: rr.Target})
+		case *dns.CAA:
+			if private {
+				return nil, fmt.Errorf("Azure Private DNS does not support CAA records")
+			}
+			properties.CAARecords = append(properties.CAARecords, azureCAARecord{Flags: rr.Flag, Tag: rr.Tag, Value: rr.Value})
+		}
+	}
+	return properties, nil
+}
+
+// addAzureRecordSet adds a record set read from Azure DNS to the given set, performing the opposite conversion of toAzureProperties.
+// Record sets of unsupported types are ignored.
+func addAzureRecordSet(set *recordSet, zone *dnsname.Name, rrset *azureRecordSet) error {
+	relative, err := dnsname.NewRecordName(rrset.Name)
+	if err != nil {
+		return err
+	}
+	owner, err := relative.Resolve(zone)
+	if err != nil {
+		return err
+	}
+	rtype := rrset.Type[strings.LastIndex(rrset.Type, "/")+1:]
+	properties := &rrset.Properties
+	ttl := properties.TTL
+
+	parseName := func(s string) (*dnsname.Name, error) {
+		name, err := dnsname.NewName(s)
+		if err != nil {
+			return nil, err
+		}
+		return name.ToFQDN(), nil
+	}
+
+	switch rtype {
+	case "A":
+		data := set.rrset(*owner, rtype, &ttl)
+		for _, record := range properties.ARecords {
+			data.A = append(data.A, v1alpha1.Ipv4String(record.IPv4Address))
+		}
+
+	case "AAAA":
+		data := set.rrset(*owner, rtype, &ttl)
+		for _, record := range properties.AAAARecords {
+			data.AAAA = append(data.AAAA, v1alpha1.Ipv6String(record.IPv6Address))
+		}
+
+	case "MX":
+		data := set.rrset(*owner, rtype, &ttl)
+		for _, record := range properties.MXRecords {
+			host, err := parseName(record.Exchange)
+			if err != nil {
+				return err
+			}
+			data.MX = append(data.MX, v1alpha1.MXRData{Preference: record.Preference, Host: *host})
+		}
+
+	case "CNAME":
+		data := set.rrset(*owner, rtype, &ttl)
+		if properties.CNAMERecord != nil {
+			target, err := parseName(properties.CNAMERecord.CNAME)
+			if err != nil {
+				return err
+			}
+			data.CNAME = append(data.CNAME, *target)
+		}
+
+	case "TXT":
+		data := set.rrset(*owner, rtype, &ttl)
+		for _, record := range properties.TXTRecords {
+			data.TXT = append(data.TXT, strings.Join(record.Value, ""))
+		}
+
+	case "SRV":
+		data := set.rrset(*owner, rtype, &ttl)
+		for _, record := range properties.SRVRecords {
+			target, err := parseName(record.Target)
+			if err != nil {
+				return err
+			}
+			data.SRV = append(data.SRV, v1alpha1.SRVRData{Priority: record.Priority, Weight: record.Weight, Port: record.Port, Target: *target})
+		}
+
+	case "CAA":
+		data := set.rrset(*owner, rtype, &ttl)
+		for _, record := range properties.CAARecords {
+			data.CAA = append(data.CAA, v1alpha1.CAARData{Flags: record.Flags, Tag: v1alpha1.CAATag(record.Tag), Value: record.Value})
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	RegisterProviderConstructor("azureDNS", func(ctx context.Context, controllerCtx *types.ControllerContext, resource *dnsv1alpha1.DNSProvider) (types.Provider, error) {
+		spec := resource.Spec.AzureDNS
+
+		clientSecret, err := readSecretKey(ctx, controllerCtx, resource, &spec.ClientSecretSecretRef)
+		if err != nil {
+			return nil, err
+		}
+
+		endpoint := azureDefaultEndpoint
+		if spec.Endpoint != nil && *spec.Endpoint != "" {
+			endpoint = strings.TrimSuffix(*spec.Endpoint, "/")
+		}
+		authorityHost := azureDefaultAuthorityHost
+		if spec.AuthorityHost != nil && *spec.AuthorityHost != "" {
+			authorityHost = strings.TrimSuffix(*spec.AuthorityHost, "/")
+		}
+		var resourceGroup string
+		if spec.ResourceGroup != nil {
+			resourceGroup = *spec.ResourceGroup
+		}
+		private := spec.PrivateZones != nil && *spec.PrivateZones
+
+		conf := &clientcredentials.Config{
+			ClientID:     spec.ClientID,
+			ClientSecret: string(clientSecret),
+			TokenURL:     authorityHost + "/" + url.PathEscape(spec.TenantID) + "/oauth2/v2.0/token",
+			Scopes:       []string{endpoint + "/.default"},
+		}
+		client := oauth2Client(resource.GetTimeout(), "azureDNS", conf.TokenSource)
+
+		return NewAzureDNS(controllerCtx.Log, resource.Spec.Zones, client, endpoint, spec.SubscriptionID, resourceGroup, spec.ZoneResourceGroups, private)
+	})
+}
@@ -0,0 +1,247 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+// fakeAzureDNS is a minimal in-memory implementation of the ARM record set API and of the token endpoint,
+// serving the zone `example.com` in the resource group `my-group`.
+type fakeAzureDNS struct {
+	sync.Mutex
+	private   bool
+	tokens    int
+	server    *httptest.Server
+	recordSet map[string]map[string]interface{}
+}
+
+func (f *fakeAzureDNS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	fail := func(status int, code string) {
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"code": code, "message": code + " " + r.URL.Path}})
+	}
+
+	if r.URL.Path == "/my-tenant/oauth2/v2.0/token" {
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok {
+			clientID, clientSecret = r.FormValue("client_id"), r.FormValue("client_secret")
+		}
+		if r.FormValue("grant_type") != "client_credentials" || clientID != "my-client" || clientSecret != "my-secret" {
+			fail(http.StatusUnauthorized, "invalid_client")
+			return
+		}
+		f.tokens++
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "token_type": "Bearer", "expires_in": 3600})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer token" {
+		fail(http.StatusUnauthorized, "AuthenticationFailed")
+		return
+	}
+
+	kind, apiVersion, ttlKey, list := "dnsZones", azureDNSAPIVersion, "TTL", "recordsets"
+	if f.private {
+		kind, apiVersion, ttlKey, list = "privateDnsZones", azurePrivateDNSAPIVersion, "ttl", "ALL"
+	}
+	zonePath := "/subscriptions/my-subscription/resourceGroups/my-group/providers/Microsoft.Network/" + kind + "/example.com/"
+	if r.URL.Query().Get("api-version") != apiVersion {
+		fail(http.StatusBadRequest, "InvalidApiVersionParameter")
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, zonePath) {
+		fail(http.StatusNotFound, "ParentResourceNotFound")
+		return
+	}
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, zonePath), "/")
+
+	switch {
+	case r.Method == http.MethodGet && len(path) == 1 && path[0] == list:
+		var keys []string
+		for key := range f.recordSet {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		// Return pages of two record sets, to exercise the pagination
+		start, _ := strconv.Atoi(r.URL.Query().Get("$skipToken"))
+		res := map[string]interface{}{}
+		var value []interface{}
+		for i := start; i < len(keys) && i < start+2; i++ {
+			value = append(value, f.recordSet[keys[i]])
+		}
+		res["value"] = value
+		if start+2 < len(keys) {
+			res["nextLink"] = f.server.URL + r.URL.Path + "?api-version=" + apiVersion + "&$skipToken=" + strconv.Itoa(start+2)
+		}
+		_ = json.NewEncoder(w).Encode(res)
+
+	case len(path) == 2:
+		key := path[0] + " " + path[1]
+		switch r.Method {
+		case http.MethodGet:
+			rrset, ok := f.recordSet[key]
+			if !ok {
+				fail(http.StatusNotFound, "NotFound")
+				return
+			}
+			_ = json.NewEncoder(w).Encode(rrset)
+		case http.MethodPut:
+			var body struct {
+				Properties map[string]interface{} `json:"properties"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Properties[ttlKey] == nil {
+				fail(http.StatusBadRequest, "BadRequest")
+				return
+			}
+			f.recordSet[key] = map[string]interface{}{
+				"name":       path[1],
+				"type":       "Microsoft.Network/" + strings.ToLower(kind) + "/" + path[0],
+				"properties": body.Properties,
+			}
+			_ = json.NewEncoder(w).Encode(f.recordSet[key])
+		case http.MethodDelete:
+			delete(f.recordSet, key)
+		}
+
+	default:
+		fail(http.StatusBadRequest, "BadRequest")
+	}
+}
+
+func newFakeAzureDNS(t *testing.T, private bool) (types.Provider, *fakeAzureDNS) {
+	fakeAPI := &fakeAzureDNS{private: private, recordSet: make(map[string]map[string]interface{})}
+	fakeAPI.server = httptest.NewServer(fakeAPI)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "azure", Namespace: "default"},
+		Data:       map[string][]byte{"secret": []byte("my-secret")},
+	}
+	resource := &v1alpha1.DNSProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "azure", Namespace: "default"},
+		Spec: v1alpha1.DNSProviderSpec{
+			Zones: []dnsname.Name{mustName("example.com")},
+			AzureDNS: &v1alpha1.DNSProviderAzureDNS{
+				TenantID:              "my-tenant",
+				ClientID:              "my-client",
+				ClientSecretSecretRef: v1alpha1.SecretReference{ObjectReference: v1alpha1.ObjectReference{Name: "azure"}, Key: "secret"},
+				SubscriptionID:        "my-subscription",
+				ZoneResourceGroups:    map[string]string{"example.com": "my-group"},
+				PrivateZones:          &private,
+				Endpoint:              &fakeAPI.server.URL,
+				AuthorityHost:         &fakeAPI.server.URL,
+			},
+		},
+	}
+	controllerCtx := &types.ControllerContext{
+		Client: fake.NewFakeClientWithScheme(clientgoscheme.Scheme, secret),
+		Log:    ctrl.Log,
+	}
+	provider, err := ProviderFor(context.Background(), controllerCtx, resource)
+	require.Nil(t, err)
+	return provider, fakeAPI
+}
+
+func TestAzureDNS(t *testing.T) {
+	for _, private := range []bool{false, true} {
+		t.Run("private="+strconv.FormatBool(private), func(t *testing.T) {
+			require := require.New(t)
+			ctx := context.Background()
+			zone := mustName("example.com")
+			provider, fakeAPI := newFakeAzureDNS(t, private)
+			defer fakeAPI.server.Close()
+
+			var records []v1alpha1.DNSRecord
+			for _, record := range append(testRecords(), testWildcardRecord()) {
+				if private && record.RType() == "CAA" {
+					require.NotNil(provider.UpdateRecord(ctx, zone, record), "CAA records are not supported in private zones")
+					continue
+				}
+				record.Name = "record-" + strconv.Itoa(len(records))
+				records = append(records, record)
+			}
+			testProviderRoundTrip(t, provider, records)
+
+			// Missing zones are errors, and not missing records
+			_, err := provider.GetRecord(ctx, mustName("example.org"), v1alpha1.DNSRecord{Spec: v1alpha1.DNSRecordSpec{
+				Name:  mustName("www.example.org"),
+				RRSet: v1alpha1.DNSRecordSetData{A: []v1alpha1.Ipv4String{"1.1.1.1"}},
+			}})
+			require.NotNil(err)
+
+			// The token is reused
+			require.Equal(1, fakeAPI.tokens)
+		})
+	}
+}
+
+func TestAzureDNSFixtures(t *testing.T) {
+	const zonesPath = "/subscriptions/my-subscription/resourceGroups/my-group/providers/Microsoft.Network/"
+	server := serveFixtures(t, map[string]string{
+		"GET " + zonesPath + "dnsZones/example.com/recordsets": "azuredns/recordsets.json",
+		"GET " + zonesPath + "privateDnsZones/example.com/ALL": "azuredns/private-recordsets.json",
+	})
+	defer server.Close()
+
+	for _, private := range []bool{false, true} {
+		t.Run("private="+strconv.FormatBool(private), func(t *testing.T) {
+			require := require.New(t)
+
+			// NS and SOA are ignored, and private zones do not support CAA
+			var expected []v1alpha1.DNSRecord
+			for _, record := range append(testRecords(), testWildcardRecord()) {
+				if !private || record.RType() != "CAA" {
+					expected = append(expected, record)
+				}
+			}
+			az, err := NewAzureDNS(ctrl.Log, nil, server.Client(), server.URL, "my-subscription", "my-group", nil, private)
+			require.Nil(err)
+			list, err := az.ListRecords(context.Background(), mustName("example.com"))
+			require.Nil(err)
+			requireSameRecords(t, expected, list)
+		})
+	}
+}
+
+func TestAzureDNSResourceGroups(t *testing.T) {
+	require := require.New(t)
+	zones := []dnsname.Name{mustName("example.com"), mustName("example.org")}
+
+	_, err := NewAzureDNS(ctrl.Log, zones, nil, azureDefaultEndpoint, "sub", "", map[string]string{"example.com": "group"}, false)
+	require.EqualError(err, "No resource group configured for zone example.org")
+
+	az, err := NewAzureDNS(ctrl.Log, zones, nil, azureDefaultEndpoint, "sub", "default", map[string]string{"Example.com.": "group"}, true)
+	require.Nil(err)
+	u, err := az.recordSetURL(&zones[0], mustNamePtr("example.com"), "A")
+	require.Nil(err)
+	require.Equal("https://management.azure.com/subscriptions/sub/resourceGroups/group/providers/Microsoft.Network/privateDnsZones/example.com/A/@?api-version=2018-09-01", u)
+	u, err = az.recordSetURL(&zones[1], mustNamePtr("*.Example.org"), "CNAME")
+	require.Nil(err)
+	require.Equal("https://management.azure.com/subscriptions/sub/resourceGroups/default/providers/Microsoft.Network/privateDnsZones/example.org/CNAME/%2A?api-version=2018-09-01", u)
+}
+
+func mustNamePtr(name string) *dnsname.Name {
+	n := mustName(name)
+	return &n
+}
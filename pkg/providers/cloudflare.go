@@ -2,8 +2,11 @@ package providers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 
 	cloudflare "github.com/cloudflare/cloudflare-go"
@@ -14,6 +17,7 @@ import (
 	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
 	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
 	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	"github.com/95ulisse/dns-operator/pkg/metrics"
 	"github.com/95ulisse/dns-operator/pkg/types"
 )
 
@@ -48,18 +52,14 @@ func (cf *Cloudflare) Zones() []dnsname.Name {
 }
 
 // UpdateRecord reconciles the given RRset with the records registered on Cloudflare.
-func (cf *Cloudflare) UpdateRecord(zone dnsname.Name, resource v1alpha1.DNSRecord) error {
+func (cf *Cloudflare) UpdateRecord(ctx context.Context, zone dnsname.Name, resource v1alpha1.DNSRecord) error {
 
 	// Retrieve the list of records of the RRset already registered on Cloudflare
-	zoneID, err := cf.zoneIDFromName(zone)
+	zoneID, err := cf.zoneIDFromName(ctx, zone)
 	if err != nil {
 		return err
 	}
-	filter := cloudflare.DNSRecord{
-		Type: resource.RType(),
-		Name: resource.Spec.Name.String(),
-	}
-	recordsAlreadyPresent, err := cf.cf.DNSRecords(zoneID, filter)
+	recordsAlreadyPresent, err := cf.listRRSet(ctx, zoneID, &resource)
 	if err != nil {
 		return err
 	}
@@ -93,15 +93,13 @@ func (cf *Cloudflare) UpdateRecord(zone dnsname.Name, resource v1alpha1.DNSRecor
 	// Synchronize the diff with cloudflare
 	for _, id := range toRemove {
 		cf.log.V(1).Info("Deleting old DNS record", "id", id)
-		err := cf.cf.DeleteDNSRecord(zoneID, id)
-		if err != nil {
+		if err := cf.deleteRecord(ctx, zoneID, id); err != nil {
 			return err
 		}
 	}
 	for _, rr := range toCreate {
 		cf.log.V(1).Info("Creating new DNS record", "record", rr)
-		_, err := cf.cf.CreateDNSRecord(zoneID, rr)
-		if err != nil {
+		if err := cf.createRecord(ctx, zoneID, rr); err != nil {
 			return err
 		}
 	}
@@ -110,18 +108,14 @@ func (cf *Cloudflare) UpdateRecord(zone dnsname.Name, resource v1alpha1.DNSRecor
 }
 
 // DeleteRecord deletes the given RRset from Cloudflare.
-func (cf *Cloudflare) DeleteRecord(zone dnsname.Name, resource v1alpha1.DNSRecord) error {
+func (cf *Cloudflare) DeleteRecord(ctx context.Context, zone dnsname.Name, resource v1alpha1.DNSRecord) error {
 
 	// Retrieve the list of records of the RRset already registered on Cloudflare
-	zoneID, err := cf.zoneIDFromName(zone)
+	zoneID, err := cf.zoneIDFromName(ctx, zone)
 	if err != nil {
 		return err
 	}
-	filter := cloudflare.DNSRecord{
-		Type: resource.RType(),
-		Name: resource.Spec.Name.String(),
-	}
-	recordsAlreadyPresent, err := cf.cf.DNSRecords(zoneID, filter)
+	recordsAlreadyPresent, err := cf.listRRSet(ctx, zoneID, &resource)
 	if err != nil {
 		return err
 	}
@@ -129,8 +123,7 @@ func (cf *Cloudflare) DeleteRecord(zone dnsname.Name, resource v1alpha1.DNSRecor
 	// Delete all the records
 	for _, rr := range recordsAlreadyPresent {
 		cf.log.V(1).Info("Deleting old DNS record", "id", rr.ID)
-		err := cf.cf.DeleteDNSRecord(zoneID, rr.ID)
-		if err != nil {
+		if err := cf.deleteRecord(ctx, zoneID, rr.ID); err != nil {
 			return err
 		}
 	}
@@ -138,13 +131,92 @@ func (cf *Cloudflare) DeleteRecord(zone dnsname.Name, resource v1alpha1.DNSRecor
 	return nil
 }
 
-func (cf *Cloudflare) zoneIDFromName(zone dnsname.Name) (string, error) {
+// GetRecord returns the RRset registered on Cloudflare with the same name and type of the given resource.
+func (cf *Cloudflare) GetRecord(ctx context.Context, zone dnsname.Name, resource v1alpha1.DNSRecord) (*v1alpha1.DNSRecord, error) {
+	zoneID, err := cf.zoneIDFromName(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	records, err := cf.listRRSet(ctx, zoneID, &resource)
+	if err != nil {
+		return nil, err
+	}
+
+	set := newRecordSet()
+	for _, rr := range records {
+		if err := addCFRecord(set, &rr); err != nil {
+			return nil, err
+		}
+	}
+	return set.first(), nil
+}
+
+// ListRecords returns all the RRsets registered on Cloudflare for the given zone.
+func (cf *Cloudflare) ListRecords(ctx context.Context, zone dnsname.Name) ([]v1alpha1.DNSRecord, error) {
+	zoneID, err := cf.zoneIDFromName(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []cloudflare.DNSRecord
+	err = runWithContext(ctx, func() error {
+		var err error
+		records, err = cf.cf.DNSRecords(zoneID, cloudflare.DNSRecord{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	set := newRecordSet()
+	for _, rr := range records {
+		if err := addCFRecord(set, &rr); err != nil {
+			return nil, err
+		}
+	}
+	return set.list(), nil
+}
+
+// listRRSet returns the records registered on Cloudflare belonging to the same RRset of the given resource.
+func (cf *Cloudflare) listRRSet(ctx context.Context, zoneID string, resource *v1alpha1.DNSRecord) ([]cloudflare.DNSRecord, error) {
+	filter := cloudflare.DNSRecord{
+		Type: resource.RType(),
+		Name: cfName(&resource.Spec.Name),
+	}
+
+	// The Cloudflare client does not support contexts, so we have to wrap all the calls
+	var records []cloudflare.DNSRecord
+	err := runWithContext(ctx, func() error {
+		var err error
+		records, err = cf.cf.DNSRecords(zoneID, filter)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (cf *Cloudflare) createRecord(ctx context.Context, zoneID string, rr cloudflare.DNSRecord) error {
+	return runWithContext(ctx, func() error {
+		_, err := cf.cf.CreateDNSRecord(zoneID, rr)
+		return err
+	})
+}
+
+func (cf *Cloudflare) deleteRecord(ctx context.Context, zoneID string, id string) error {
+	return runWithContext(ctx, func() error {
+		return cf.cf.DeleteDNSRecord(zoneID, id)
+	})
+}
+
+func (cf *Cloudflare) zoneIDFromName(ctx context.Context, zone dnsname.Name) (string, error) {
 
 	// First check if the zone is in the cache
 	id := func() string {
 		cf.cfZonesIDCacheLock.RLock()
 		defer cf.cfZonesIDCacheLock.RUnlock()
-		if id, ok := cf.cfZonesIDCache[zone.String()]; ok {
+		if id, ok := cf.cfZonesIDCache[cfName(&zone)]; ok {
 			return id
 		}
 		return ""
@@ -154,7 +226,11 @@ func (cf *Cloudflare) zoneIDFromName(zone dnsname.Name) (string, error) {
 	}
 
 	// Resolve the zone using CF api
-	id, err := cf.cf.ZoneIDByName(zone.String())
+	err := runWithContext(ctx, func() error {
+		var err error
+		id, err = cf.cf.ZoneIDByName(cfName(&zone))
+		return err
+	})
 	if err != nil {
 		cf.log.Error(err, "Could not resolve zone name", "zone", zone.String())
 		return "", err
@@ -163,7 +239,7 @@ func (cf *Cloudflare) zoneIDFromName(zone dnsname.Name) (string, error) {
 	// Store the id in the cache
 	cf.cfZonesIDCacheLock.Lock()
 	defer cf.cfZonesIDCacheLock.Unlock()
-	cf.cfZonesIDCache[zone.String()] = id
+	cf.cfZonesIDCache[cfName(&zone)] = id
 
 	return id, nil
 
@@ -187,15 +263,16 @@ func (cf *Cloudflare) toCFRecords(resource *v1alpha1.DNSRecord) ([]cloudflare.DN
 	}
 
 	rrset := make([]cloudflare.DNSRecord, 0, 1)
-	push := func(content string, priority int) {
+	push := func(content string, priority int) *cloudflare.DNSRecord {
 		var rr cloudflare.DNSRecord
 		rr.Type = resource.RType()
-		rr.Name = resource.Spec.Name.String()
+		rr.Name = cfName(&resource.Spec.Name)
 		rr.Content = content
 		rr.TTL = ttl
 		rr.Priority = priority
 		rr.Proxied = proxied
 		rrset = append(rrset, rr)
+		return &rrset[len(rrset)-1]
 	}
 
 	switch resource.RType() {
@@ -211,32 +288,201 @@ func (cf *Cloudflare) toCFRecords(resource *v1alpha1.DNSRecord) ([]cloudflare.DN
 
 	case "CNAME":
 		for _, value := range resource.Spec.RRSet.CNAME {
-			push(value.String(), 0)
+			push(cfName(&value), 0)
 		}
 
 	case "TXT":
 		for _, value := range resource.Spec.RRSet.TXT {
-			push(value, 0)
+			rr := push(value, 0)
+			rr.Proxied = false // TXT records cannot be proxied
 		}
 
 	case "MX":
 		for _, mx := range resource.Spec.RRSet.MX {
-			push(mx.Host.String(), int(mx.Preference))
+			push(cfName(&mx.Host), int(mx.Preference))
+		}
+
+	case "SRV":
+		// Cloudflare wants SRV records as structured data, with the owner name split in its components
+		labels := resource.Spec.Name.Labels()
+		if len(labels) < 3 || !strings.HasPrefix(labels[0], "_") || !strings.HasPrefix(labels[1], "_") {
+			return nil, fmt.Errorf("SRV record name %s is not in the form _service._proto.name", resource.Spec.Name.String())
+		}
+		for _, srv := range resource.Spec.RRSet.SRV {
+			target := cfName(&srv.Target)
+			rr := push(fmt.Sprintf("%d %d %s", srv.Weight, srv.Port, target), int(srv.Priority))
+			rr.Proxied = false // SRV records cannot be proxied
+			rr.Data = cfSRVData{
+				Service:  labels[0],
+				Proto:    labels[1],
+				Name:     strings.ToLower(strings.Join(labels[2:], ".")),
+				Priority: srv.Priority,
+				Weight:   srv.Weight,
+				Port:     srv.Port,
+				Target:   target,
+			}
+		}
+
+	case "CAA":
+		for _, caa := range resource.Spec.RRSet.CAA {
+			if err := caa.Validate(); err != nil {
+				return nil, err
+			}
+			rr := push(fmt.Sprintf("%d %s \"%s\"", caa.Flags, caa.Tag, caa.Value), 0)
+			rr.Proxied = false // CAA records cannot be proxied
+			rr.Data = cfCAAData{
+				Flags: caa.Flags,
+				Tag:   string(caa.Tag),
+				Value: caa.Value,
+			}
 		}
 
 	default:
 		return nil, fmt.Errorf("Unsupported DNS record")
 	}
 
+	// The TTL of proxied records is always managed by Cloudflare
+	for i := range rrset {
+		if rrset[i].Proxied {
+			rrset[i].TTL = 1
+		}
+	}
+
 	return rrset, nil
 }
 
+// addCFRecord adds a record read from Cloudflare to the given set, performing the opposite conversion of toCFRecords.
+// Records of unsupported types are ignored.
+func addCFRecord(set *recordSet, rr *cloudflare.DNSRecord) error {
+	owner, err := dnsname.NewName(rr.Name)
+	if err != nil {
+		return err
+	}
+
+	// A TTL of 1 means that the TTL is automatically managed by Cloudflare
+	var ttl *uint32
+	if rr.TTL > 1 {
+		value := uint32(rr.TTL)
+		ttl = &value
+	}
+
+	switch rr.Type {
+	case "A":
+		data := set.rrset(*owner, rr.Type, ttl)
+		data.A = append(data.A, v1alpha1.Ipv4String(rr.Content))
+
+	case "AAAA":
+		data := set.rrset(*owner, rr.Type, ttl)
+		data.AAAA = append(data.AAAA, v1alpha1.Ipv6String(rr.Content))
+
+	case "CNAME":
+		target, err := dnsname.NewName(rr.Content)
+		if err != nil {
+			return err
+		}
+		data := set.rrset(*owner, rr.Type, ttl)
+		data.CNAME = append(data.CNAME, *target)
+
+	case "TXT":
+		data := set.rrset(*owner, rr.Type, ttl)
+		data.TXT = append(data.TXT, rr.Content)
+
+	case "MX":
+		host, err := dnsname.NewName(rr.Content)
+		if err != nil {
+			return err
+		}
+		data := set.rrset(*owner, rr.Type, ttl)
+		data.MX = append(data.MX, v1alpha1.MXRData{Preference: uint16(rr.Priority), Host: *host})
+
+	case "SRV":
+		var srv cfSRVData
+		if err := decodeData(rr, &srv); err != nil {
+			return err
+		}
+		target, err := dnsname.NewName(srv.Target)
+		if err != nil {
+			return err
+		}
+		data := set.rrset(*owner, rr.Type, ttl)
+		data.SRV = append(data.SRV, v1alpha1.SRVRData{Priority: srv.Priority, Weight: srv.Weight, Port: srv.Port, Target: *target})
+
+	case "CAA":
+		var caa cfCAAData
+		if err := decodeData(rr, &caa); err != nil {
+			return err
+		}
+		data := set.rrset(*owner, rr.Type, ttl)
+		data.CAA = append(data.CAA, v1alpha1.CAARData{Flags: caa.Flags, Tag: v1alpha1.CAATag(caa.Tag), Value: caa.Value})
+	}
+
+	return nil
+}
+
+// cfName returns the representation of a domain name used by Cloudflare:
+// lowercase and without the final dot.
+func cfName(name *dnsname.Name) string {
+	return strings.ToLower(strings.Join(name.Labels(), "."))
+}
+
+// cfSRVData is the structured payload Cloudflare uses for SRV records.
+type cfSRVData struct {
+	Service  string `json:"service"`
+	Proto    string `json:"proto"`
+	Name     string `json:"name"`
+	Priority uint16 `json:"priority"`
+	Weight   uint16 `json:"weight"`
+	Port     uint16 `json:"port"`
+	Target   string `json:"target"`
+}
+
+// cfCAAData is the structured payload Cloudflare uses for CAA records.
+type cfCAAData struct {
+	Flags uint8  `json:"flags"`
+	Tag   string `json:"tag"`
+	Value string `json:"value"`
+}
+
+// decodeData extracts the structured payload from a Cloudflare record.
+// Records returned by the API carry a generic map, so we round-trip it through JSON.
+func decodeData(rr *cloudflare.DNSRecord, out interface{}) error {
+	raw, err := json.Marshal(rr.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
 func rrEquals(rr1 *cloudflare.DNSRecord, rr2 *cloudflare.DNSRecord) bool {
-	return rr1.Type == rr2.Type &&
-		rr1.Name == rr2.Name &&
-		rr1.Content == rr2.Content &&
+	if rr1.Type != rr2.Type || rr1.Name != rr2.Name || rr1.TTL != rr2.TTL {
+		return false
+	}
+
+	// Records with structured data are compared on the data itself,
+	// since the content returned by Cloudflare is only a rendering of it.
+	switch rr1.Type {
+	case "SRV":
+		var srv1, srv2 cfSRVData
+		if decodeData(rr1, &srv1) != nil || decodeData(rr2, &srv2) != nil {
+			return false
+		}
+		return srv1.Priority == srv2.Priority &&
+			srv1.Weight == srv2.Weight &&
+			srv1.Port == srv2.Port &&
+			strings.EqualFold(strings.TrimSuffix(srv1.Target, "."), strings.TrimSuffix(srv2.Target, "."))
+
+	case "CAA":
+		var caa1, caa2 cfCAAData
+		if decodeData(rr1, &caa1) != nil || decodeData(rr2, &caa2) != nil {
+			return false
+		}
+		return caa1.Flags == caa2.Flags &&
+			strings.EqualFold(caa1.Tag, caa2.Tag) &&
+			caa1.Value == caa2.Value
+	}
+
+	return rr1.Content == rr2.Content &&
 		rr1.Proxied == rr2.Proxied &&
-		rr1.TTL == rr2.TTL &&
 		rr1.Priority == rr2.Priority
 }
 
@@ -249,7 +495,7 @@ func removeRR(s []cloudflare.DNSRecord, i int) []cloudflare.DNSRecord {
 }
 
 func init() {
-	RegisterProviderConstructor("cloudflare", func(ctx *types.ControllerContext, resource *dnsv1alpha1.DNSProvider) (types.Provider, error) {
+	RegisterProviderConstructor("cloudflare", func(ctx context.Context, controllerCtx *types.ControllerContext, resource *dnsv1alpha1.DNSProvider) (types.Provider, error) {
 
 		// Extract the required parameters
 		email := resource.Spec.Cloudflare.Email
@@ -273,7 +519,7 @@ func init() {
 			secretNamespace = &resource.Namespace
 		}
 		var secret corev1.Secret
-		if err := ctx.Client.Get(context.Background(), k8stypes.NamespacedName{Name: secretName, Namespace: *secretNamespace}, &secret); err != nil {
+		if err := controllerCtx.Client.Get(ctx, k8stypes.NamespacedName{Name: secretName, Namespace: *secretNamespace}, &secret); err != nil {
 			return nil, err
 		}
 
@@ -283,13 +529,18 @@ func init() {
 			return nil, fmt.Errorf("Cannot find key %s in secret %s/%s", secretRef.Key, *secretNamespace, secretName)
 		}
 
-		// Build a Cloudflare client
+		// Build a Cloudflare client.
+		// Since the client does not support contexts, bound the duration of the requests at the HTTP level.
 		var cf *cloudflare.API
 		var err error
+		httpClient := cloudflare.HTTPClient(&http.Client{
+			Timeout:   resource.GetTimeout(),
+			Transport: metrics.RateLimitTransport("cloudflare", nil),
+		})
 		if apiKey != nil {
-			cf, err = cloudflare.New(string(key), *email)
+			cf, err = cloudflare.New(string(key), *email, httpClient)
 		} else {
-			cf, err = cloudflare.NewWithAPIToken(string(key))
+			cf, err = cloudflare.NewWithAPIToken(string(key), httpClient)
 		}
 		if err != nil {
 			return nil, err
@@ -300,6 +551,6 @@ func init() {
 			proxiedByDefault = *resource.Spec.Cloudflare.ProxiedByDefault
 		}
 
-		return NewCloudflare(ctx.Log, resource.Spec.Zones, cf, proxiedByDefault), nil
+		return NewCloudflare(controllerCtx.Log, resource.Spec.Zones, cf, proxiedByDefault), nil
 	})
 }
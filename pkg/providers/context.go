@@ -0,0 +1,65 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+// timeoutProvider wraps a Provider bounding the duration of each of its operations.
+type timeoutProvider struct {
+	types.Provider
+	timeout time.Duration
+}
+
+// UpdateRecord calls the wrapped provider with a context bounded by the configured timeout.
+func (p *timeoutProvider) UpdateRecord(ctx context.Context, zone dnsname.Name, rrset v1alpha1.DNSRecord) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	return p.Provider.UpdateRecord(ctx, zone, rrset)
+}
+
+// DeleteRecord calls the wrapped provider with a context bounded by the configured timeout.
+func (p *timeoutProvider) DeleteRecord(ctx context.Context, zone dnsname.Name, rrset v1alpha1.DNSRecord) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	return p.Provider.DeleteRecord(ctx, zone, rrset)
+}
+
+// ListRecords calls the wrapped provider with a context bounded by the configured timeout.
+func (p *timeoutProvider) ListRecords(ctx context.Context, zone dnsname.Name) ([]v1alpha1.DNSRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	return p.Provider.ListRecords(ctx, zone)
+}
+
+// GetRecord calls the wrapped provider with a context bounded by the configured timeout.
+func (p *timeoutProvider) GetRecord(ctx context.Context, zone dnsname.Name, rrset v1alpha1.DNSRecord) (*v1alpha1.DNSRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	return p.Provider.GetRecord(ctx, zone, rrset)
+}
+
+// runWithContext runs `f` and waits for it to complete, or for the context to be done, whichever comes first.
+// This is needed for cloudflare-go, which does not accept a context: `f` keeps running in the background
+// after the context is done, so the library must still be configured to time out on its own.
+func runWithContext(ctx context.Context, f func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,72 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+)
+
+// blockingProvider is a provider whose operations hang until the context is done.
+type blockingProvider struct {
+	*Dummy
+}
+
+func (p *blockingProvider) UpdateRecord(ctx context.Context, zone dnsname.Name, rrset v1alpha1.DNSRecord) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRunWithContext(t *testing.T) {
+	require := require.New(t)
+
+	// Completed functions return their own result
+	expected := errors.New("failure")
+	err := runWithContext(context.Background(), func() error { return expected })
+	require.Equal(expected, err)
+
+	// Hanging functions are abandoned when the context expires
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	release := make(chan struct{})
+	defer close(release)
+	err = runWithContext(ctx, func() error {
+		<-release
+		return nil
+	})
+	require.Equal(context.DeadlineExceeded, err)
+
+	// Functions are not even started with a context already done
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	started := false
+	err = runWithContext(cancelled, func() error {
+		started = true
+		return nil
+	})
+	require.Equal(context.Canceled, err)
+	require.False(started)
+}
+
+func TestTimeoutProvider(t *testing.T) {
+	require := require.New(t)
+
+	provider := &timeoutProvider{
+		Provider: &blockingProvider{NewDummy(ctrl.Log, nil)},
+		timeout:  10 * time.Millisecond,
+	}
+
+	start := time.Now()
+	err := provider.UpdateRecord(context.Background(), dnsname.Name{}, v1alpha1.DNSRecord{})
+	require.Equal(context.DeadlineExceeded, err)
+	require.Less(int64(time.Since(start)), int64(time.Second))
+
+	// Operations not overridden by the wrapped provider are still forwarded
+	require.Nil(provider.DeleteRecord(context.Background(), dnsname.Name{}, v1alpha1.DNSRecord{}))
+}
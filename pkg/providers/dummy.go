@@ -1,6 +1,8 @@
 package providers
 
 import (
+	"context"
+
 	"github.com/go-logr/logr"
 
 	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
@@ -28,19 +30,29 @@ func (dummy *Dummy) Zones() []dnsname.Name {
 }
 
 // UpdateRecord dummy noop.
-func (dummy *Dummy) UpdateRecord(zone dnsname.Name, rrset v1alpha1.DNSRecord) error {
+func (dummy *Dummy) UpdateRecord(ctx context.Context, zone dnsname.Name, rrset v1alpha1.DNSRecord) error {
 	dummy.log.Info("Updating record")
 	return nil
 }
 
 // DeleteRecord dummy noop.
-func (dummy *Dummy) DeleteRecord(zone dnsname.Name, rrset v1alpha1.DNSRecord) error {
+func (dummy *Dummy) DeleteRecord(ctx context.Context, zone dnsname.Name, rrset v1alpha1.DNSRecord) error {
 	dummy.log.Info("Delete successful")
 	return nil
 }
 
+// ListRecords is not supported by the Dummy provider.
+func (dummy *Dummy) ListRecords(ctx context.Context, zone dnsname.Name) ([]v1alpha1.DNSRecord, error) {
+	return nil, types.ErrNotSupported
+}
+
+// GetRecord is not supported by the Dummy provider.
+func (dummy *Dummy) GetRecord(ctx context.Context, zone dnsname.Name, rrset v1alpha1.DNSRecord) (*v1alpha1.DNSRecord, error) {
+	return nil, types.ErrNotSupported
+}
+
 func init() {
-	RegisterProviderConstructor("dummy", func(ctx *types.ControllerContext, resource *v1alpha1.DNSProvider) (types.Provider, error) {
-		return NewDummy(ctx.Log, resource.Spec.Zones), nil
+	RegisterProviderConstructor("dummy", func(ctx context.Context, controllerCtx *types.ControllerContext, resource *v1alpha1.DNSProvider) (types.Provider, error) {
+		return NewDummy(controllerCtx.Log, resource.Spec.Zones), nil
 	})
 }
@@ -1,15 +1,22 @@
 package providers
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"sync"
 
+	corev1 "k8s.io/api/core/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
 	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
 	types "github.com/95ulisse/dns-operator/pkg/types"
 )
 
 // ProviderContructor constructs a Provider given a kubernetes resource and a Context.
-type ProviderContructor func(*types.ControllerContext, *dnsv1alpha1.DNSProvider) (types.Provider, error)
+// The context.Context bounds the operations needed to build the provider (e.g., reading secrets).
+type ProviderContructor func(context.Context, *types.ControllerContext, *dnsv1alpha1.DNSProvider) (types.Provider, error)
 
 var (
 	constructors     = make(map[string]ProviderContructor)
@@ -25,7 +32,9 @@ func RegisterProviderConstructor(name string, c ProviderContructor) {
 }
 
 // ProviderFor builds a new Provider from the given kubernetes resource.
-func ProviderFor(ctx *types.ControllerContext, resource *dnsv1alpha1.DNSProvider) (types.Provider, error) {
+// All the operations of the returned provider are bounded by the timeout configured in the resource
+// and are recorded in the metrics of the operator, and the provider refuses to modify or delete the RRsets not owned by the resource being reconciled.
+func ProviderFor(ctx context.Context, controllerCtx *types.ControllerContext, resource *dnsv1alpha1.DNSProvider) (types.Provider, error) {
 	providerType, err := resource.GetProviderType()
 	if err != nil {
 		return nil, fmt.Errorf("Could not get provider type: %s", err.Error())
@@ -34,8 +43,58 @@ func ProviderFor(ctx *types.ControllerContext, resource *dnsv1alpha1.DNSProvider
 	constructorsLock.RLock()
 	defer constructorsLock.RUnlock()
 	if constructor, ok := constructors[providerType]; ok {
-		return constructor(ctx, resource)
+		timeout := resource.GetTimeout()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		provider, err := constructor(ctx, controllerCtx, resource)
+		if err != nil {
+			return nil, err
+		}
+		return &ownershipProvider{
+			Provider: &timeoutProvider{
+				Provider: &metricsProvider{Provider: provider, providerType: providerType},
+				timeout:  timeout,
+			},
+			clusterID: controllerCtx.ClusterID,
+		}, nil
 	}
 
 	return nil, fmt.Errorf("Provider %s not registered", providerType)
 }
+
+// readSecretKey returns the value of the key of a secret referenced by the given resource.
+// Secrets referenced without a namespace are read from the namespace of the resource.
+func readSecretKey(ctx context.Context, controllerCtx *types.ControllerContext, resource *dnsv1alpha1.DNSProvider, secretRef *dnsv1alpha1.SecretReference) ([]byte, error) {
+	secretNamespace := secretRef.Namespace
+	if secretNamespace == nil {
+		secretNamespace = &resource.Namespace
+	}
+	var secret corev1.Secret
+	if err := controllerCtx.Client.Get(ctx, k8stypes.NamespacedName{Name: secretRef.Name, Namespace: *secretNamespace}, &secret); err != nil {
+		return nil, err
+	}
+	value, ok := secret.Data[secretRef.Key]
+	if !ok {
+		return nil, fmt.Errorf("Cannot find key %s in secret %s/%s", secretRef.Key, *secretNamespace, secretRef.Name)
+	}
+	return value, nil
+}
+
+// zoneMap normalizes a map keyed by DNS zones, as configured in a resource, so that it can be looked up with zoneKey.
+func zoneMap(field string, m map[string]string) (map[string]string, error) {
+	res := make(map[string]string, len(m))
+	for zone, value := range m {
+		name, err := dnsname.NewName(zone)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid zone %s in %s: %s", zone, field, err)
+		}
+		res[zoneKey(name)] = value
+	}
+	return res, nil
+}
+
+// zoneKey returns the key identifying a zone in the maps returned by zoneMap.
+func zoneKey(zone *dnsname.Name) string {
+	return strings.ToLower(zone.ToFQDN().String())
+}
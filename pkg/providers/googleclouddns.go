@@ -0,0 +1,363 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/oauth2/jwt"
+
+	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	"github.com/95ulisse/dns-operator/pkg/metrics"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+const (
+	googleCloudDNSDefaultEndpoint = "https://dns.googleapis.com/dns/v1"
+	googleCloudDNSScope           = "https://www.googleapis.com/auth/ndev.clouddns.readwrite"
+	googleDefaultTokenURL         = "https://oauth2.googleapis.com/token"
+)
+
+// GoogleCloudDNS DNS provider.
+type GoogleCloudDNS struct {
+	log              logr.Logger
+	zones            []dnsname.Name
+	client           *http.Client
+	endpoint         string
+	project          string
+	managedZones     map[string]string
+	managedZonesLock sync.RWMutex
+}
+
+// NewGoogleCloudDNS creates a new instance of the Google Cloud DNS provider.
+// `managedZones` maps the DNS zones to the names of the managed zones of the project:
+// the zones not listed there are looked up by name on the first use.
+// The given HTTP client must authenticate the requests.
+func NewGoogleCloudDNS(log logr.Logger, zones []dnsname.Name, client *http.Client, endpoint, project string, managedZones map[string]string) (*GoogleCloudDNS, error) {
+	managedZones, err := zoneMap("managedZones", managedZones)
+	if err != nil {
+		return nil, err
+	}
+	return &GoogleCloudDNS{
+		log:          log.WithName("providers").WithName("GoogleCloudDNS"),
+		zones:        zones,
+		client:       client,
+		endpoint:     strings.TrimSuffix(endpoint, "/"),
+		project:      project,
+		managedZones: managedZones,
+	}, nil
+}
+
+// Zones returns a slice containing the DNS zones managed by this provider.
+func (g *GoogleCloudDNS) Zones() []dnsname.Name {
+	return g.zones
+}
+
+// UpdateRecord replaces the whole RRset on Google Cloud DNS with the given one, with a single atomic change.
+func (g *GoogleCloudDNS) UpdateRecord(ctx context.Context, zone dnsname.Name, resource v1alpha1.DNSRecord) error {
+	managedZone, err := g.managedZoneFromName(ctx, zone)
+	if err != nil {
+		return err
+	}
+	desired, err := toGCDRecordSet(&resource)
+	if err != nil {
+		return err
+	}
+
+	// Additions must not clash with existing RRsets, so the published one is deleted in the same change
+	existing, err := g.getRRSet(ctx, managedZone, &resource.Spec.Name, resource.RType())
+	if err != nil {
+		return err
+	}
+	var change gcdChange
+	if existing != nil {
+		if existing.TTL == desired.TTL && reflect.DeepEqual(existing.RRDatas, desired.RRDatas) {
+			return nil
+		}
+		change.Deletions = []gcdResourceRecordSet{*existing}
+	}
+	change.Additions = []gcdResourceRecordSet{*desired}
+
+	g.log.V(1).Info("Updating DNS record", "name", desired.Name, "type", desired.Type)
+	return g.createChange(ctx, managedZone, &change)
+}
+
+// DeleteRecord deletes the given RRset from Google Cloud DNS.
+func (g *GoogleCloudDNS) DeleteRecord(ctx context.Context, zone dnsname.Name, resource v1alpha1.DNSRecord) error {
+	managedZone, err := g.managedZoneFromName(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	// Deletions must match exactly the published RRset, which might differ from the resource
+	existing, err := g.getRRSet(ctx, managedZone, &resource.Spec.Name, resource.RType())
+	if err != nil || existing == nil {
+		return err
+	}
+
+	g.log.V(1).Info("Deleting DNS record", "name", existing.Name, "type", existing.Type)
+	return g.createChange(ctx, managedZone, &gcdChange{Deletions: []gcdResourceRecordSet{*existing}})
+}
+
+// GetRecord returns the RRset registered on Google Cloud DNS with the same name and type of the given resource.
+func (g *GoogleCloudDNS) GetRecord(ctx context.Context, zone dnsname.Name, resource v1alpha1.DNSRecord) (*v1alpha1.DNSRecord, error) {
+	managedZone, err := g.managedZoneFromName(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	rrset, err := g.getRRSet(ctx, managedZone, &resource.Spec.Name, resource.RType())
+	if err != nil || rrset == nil {
+		return nil, err
+	}
+
+	set := newRecordSet()
+	if err := addGCDRecordSet(set, rrset); err != nil {
+		return nil, err
+	}
+	return set.first(), nil
+}
+
+// ListRecords returns all the RRsets registered on Google Cloud DNS for the given zone.
+func (g *GoogleCloudDNS) ListRecords(ctx context.Context, zone dnsname.Name) ([]v1alpha1.DNSRecord, error) {
+	managedZone, err := g.managedZoneFromName(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	set := newRecordSet()
+	rrsets, err := g.listRRSets(ctx, managedZone, url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range rrsets {
+		if err := addGCDRecordSet(set, &rrsets[i]); err != nil {
+			return nil, err
+		}
+	}
+	return set.list(), nil
+}
+
+// getRRSet returns the RRset with the given name and type published on Google Cloud DNS, or nil if it does not exist.
+func (g *GoogleCloudDNS) getRRSet(ctx context.Context, managedZone string, name *dnsname.Name, rtype string) (*gcdResourceRecordSet, error) {
+	query := url.Values{}
+	query.Set("name", strings.ToLower(name.ToFQDN().String()))
+	query.Set("type", rtype)
+	rrsets, err := g.listRRSets(ctx, managedZone, query)
+	if err != nil || len(rrsets) == 0 {
+		return nil, err
+	}
+	return &rrsets[0], nil
+}
+
+// listRRSets lists the RRsets of a managed zone matching the given query, following the pagination.
+func (g *GoogleCloudDNS) listRRSets(ctx context.Context, managedZone string, query url.Values) ([]gcdResourceRecordSet, error) {
+	var rrsets []gcdResourceRecordSet
+	for {
+		var res struct {
+			RRSets        []gcdResourceRecordSet `json:"rrsets"`
+			NextPageToken string                 `json:"nextPageToken"`
+		}
+		u := g.zoneURL(managedZone) + "/rrsets"
+		if len(query) > 0 {
+			u += "?" + query.Encode()
+		}
+		if err := doJSON(ctx, g.client, http.MethodGet, u, nil, nil, &res, gcdError); err != nil {
+			return nil, err
+		}
+		rrsets = append(rrsets, res.RRSets...)
+
+		if res.NextPageToken == "" {
+			return rrsets, nil
+		}
+		query.Set("pageToken", res.NextPageToken)
+	}
+}
+
+// createChange submits a change to a managed zone. Changes are applied atomically.
+func (g *GoogleCloudDNS) createChange(ctx context.Context, managedZone string, change *gcdChange) error {
+	return doJSON(ctx, g.client, http.MethodPost, g.zoneURL(managedZone)+"/changes", nil, change, nil, gcdError)
+}
+
+func (g *GoogleCloudDNS) zoneURL(managedZone string) string {
+	return g.endpoint + "/projects/" + url.PathEscape(g.project) + "/managedZones/" + url.PathEscape(managedZone)
+}
+
+func (g *GoogleCloudDNS) managedZoneFromName(ctx context.Context, zone dnsname.Name) (string, error) {
+	name := zoneKey(&zone)
+
+	// First check if the zone is configured or in the cache
+	managedZone := func() string {
+		g.managedZonesLock.RLock()
+		defer g.managedZonesLock.RUnlock()
+		return g.managedZones[name]
+	}()
+	if managedZone != "" {
+		return managedZone, nil
+	}
+
+	// Look up the managed zone by DNS name
+	var res struct {
+		ManagedZones []struct {
+			Name    string `json:"name"`
+			DNSName string `json:"dnsName"`
+		} `json:"managedZones"`
+	}
+	u := g.endpoint + "/projects/" + url.PathEscape(g.project) + "/managedZones?dnsName=" + url.QueryEscape(name)
+	if err := doJSON(ctx, g.client, http.MethodGet, u, nil, nil, &res, gcdError); err != nil {
+		g.log.Error(err, "Could not resolve zone name", "zone", zone.String())
+		return "", err
+	}
+	for _, z := range res.ManagedZones {
+		if strings.ToLower(z.DNSName) != name {
+			continue
+		}
+		if managedZone != "" {
+			return "", fmt.Errorf("Found multiple managed zones named %s, use managedZones to choose one", name)
+		}
+		managedZone = z.Name
+	}
+	if managedZone == "" {
+		return "", fmt.Errorf("Cannot find managed zone %s in project %s", name, g.project)
+	}
+
+	// Store the name in the cache
+	g.managedZonesLock.Lock()
+	defer g.managedZonesLock.Unlock()
+	g.managedZones[name] = managedZone
+
+	return managedZone, nil
+}
+
+// gcdError builds an error out of an error response of the Google Cloud DNS API.
+func gcdError(status int, body []byte) error {
+	var res struct {
+		Error struct {
+			Message string `json:"message"`
+			Errors  []struct {
+				Reason string `json:"reason"`
+			} `json:"errors"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil || res.Error.Message == "" {
+		return fmt.Errorf("Google Cloud DNS request failed with status %d", status)
+	}
+
+	// Exceeded quotas are signalled with a `403 Forbidden`, so they are not caught by metrics.RateLimitTransport
+	if status == http.StatusForbidden {
+		for _, e := range res.Error.Errors {
+			if e.Reason == "rateLimitExceeded" || e.Reason == "userRateLimitExceeded" {
+				metrics.ProviderRateLimits.WithLabelValues("googleCloudDNS").Inc()
+				break
+			}
+		}
+	}
+
+	return fmt.Errorf("Google Cloud DNS request failed: %s", res.Error.Message)
+}
+
+// Google Cloud DNS API payloads
+
+type gcdResourceRecordSet struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	TTL     uint32   `json:"ttl"`
+	RRDatas []string `json:"rrdatas"`
+}
+
+type gcdChange struct {
+	Additions []gcdResourceRecordSet `json:"additions,omitempty"`
+	Deletions []gcdResourceRecordSet `json:"deletions,omitempty"`
+}
+
+// toGCDRecordSet converts a DNSRecord resource to a Google Cloud DNS RRset.
+func toGCDRecordSet(resource *v1alpha1.DNSRecord) (*gcdResourceRecordSet, error) {
+	rdata, err := toRData(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := v1alpha1.DefaultTTLSeconds
+	if resource.Spec.TTLSeconds != nil {
+		ttl = *resource.Spec.TTLSeconds
+	}
+	return &gcdResourceRecordSet{
+		Name:    strings.ToLower(resource.Spec.Name.ToFQDN().String()),
+		Type:    resource.RType(),
+		TTL:     ttl,
+		RRDatas: rdata,
+	}, nil
+}
+
+// addGCDRecordSet adds an RRset read from Google Cloud DNS to the given set, performing the opposite conversion of toGCDRecordSet.
+// RRsets of unsupported types are ignored.
+func addGCDRecordSet(set *recordSet, rrset *gcdResourceRecordSet) error {
+	for _, rdata := range rrset.RRDatas {
+		if err := addRData(set, rrset.Name, rrset.Type, rrset.TTL, rdata); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gcdServiceAccount contains the fields of the JSON key of a service account needed for authentication.
+type gcdServiceAccount struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+func init() {
+	RegisterProviderConstructor("googleCloudDNS", func(ctx context.Context, controllerCtx *types.ControllerContext, resource *dnsv1alpha1.DNSProvider) (types.Provider, error) {
+		spec := resource.Spec.GoogleCloudDNS
+
+		// Parse the key of the service account
+		key, err := readSecretKey(ctx, controllerCtx, resource, &spec.ServiceAccountSecretRef)
+		if err != nil {
+			return nil, err
+		}
+		var account gcdServiceAccount
+		if err := json.Unmarshal(key, &account); err != nil {
+			return nil, fmt.Errorf("Invalid service account key: %s", err)
+		}
+		if account.Type != "service_account" {
+			return nil, fmt.Errorf("Invalid service account key: expected type service_account, found %s", account.Type)
+		}
+		if account.TokenURI == "" {
+			account.TokenURI = googleDefaultTokenURL
+		}
+
+		project := account.ProjectID
+		if spec.Project != nil && *spec.Project != "" {
+			project = *spec.Project
+		}
+		if project == "" {
+			return nil, fmt.Errorf("`project` is required when the service account key does not contain a project")
+		}
+		endpoint := googleCloudDNSDefaultEndpoint
+		if spec.Endpoint != nil && *spec.Endpoint != "" {
+			endpoint = *spec.Endpoint
+		}
+
+		conf := &jwt.Config{
+			Email:        account.ClientEmail,
+			PrivateKey:   []byte(account.PrivateKey),
+			PrivateKeyID: account.PrivateKeyID,
+			Scopes:       []string{googleCloudDNSScope},
+			TokenURL:     account.TokenURI,
+		}
+		client := oauth2Client(resource.GetTimeout(), "googleCloudDNS", conf.TokenSource)
+
+		return NewGoogleCloudDNS(controllerCtx.Log, resource.Spec.Zones, client, endpoint, project, spec.ManagedZones)
+	})
+}
@@ -0,0 +1,242 @@
+package providers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	"github.com/95ulisse/dns-operator/pkg/metrics"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+// fakeGoogleCloudDNS is a minimal in-memory implementation of the Google Cloud DNS API and of the token endpoint,
+// serving the managed zone `example-com` of the project `my-project`.
+type fakeGoogleCloudDNS struct {
+	sync.Mutex
+	tokens      int
+	zoneLookups int
+	rrsets      map[string]gcdResourceRecordSet
+}
+
+func (f *fakeGoogleCloudDNS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	const zonePath = "/dns/v1/projects/my-project/managedZones/example-com"
+	fail := func(status int, message string) {
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{"code": status, "message": message}})
+	}
+
+	if r.URL.Path == "/token" {
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || r.FormValue("assertion") == "" {
+			fail(http.StatusBadRequest, "invalid grant")
+			return
+		}
+		f.tokens++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "token_type": "Bearer", "expires_in": 3600})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer token" {
+		fail(http.StatusUnauthorized, "unauthenticated")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/dns/v1/projects/my-project/managedZones":
+		f.zoneLookups++
+		var zones []map[string]string
+		if r.URL.Query().Get("dnsName") == "example.com." {
+			zones = append(zones, map[string]string{"name": "example-com", "dnsName": "example.com."})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"managedZones": zones})
+
+	case r.Method == http.MethodGet && r.URL.Path == zonePath+"/rrsets":
+		var keys []string
+		for key, rrset := range f.rrsets {
+			if (r.URL.Query().Get("name") == "" || r.URL.Query().Get("name") == rrset.Name) &&
+				(r.URL.Query().Get("type") == "" || r.URL.Query().Get("type") == rrset.Type) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		// Return pages of two RRsets, to exercise the pagination
+		start, _ := strconv.Atoi(r.URL.Query().Get("pageToken"))
+		res := struct {
+			RRSets        []gcdResourceRecordSet `json:"rrsets"`
+			NextPageToken string                 `json:"nextPageToken,omitempty"`
+		}{}
+		for i := start; i < len(keys) && i < start+2; i++ {
+			res.RRSets = append(res.RRSets, f.rrsets[keys[i]])
+		}
+		if start+2 < len(keys) {
+			res.NextPageToken = strconv.Itoa(start + 2)
+		}
+		_ = json.NewEncoder(w).Encode(&res)
+
+	case r.Method == http.MethodPost && r.URL.Path == zonePath+"/changes":
+		var change gcdChange
+		if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+			fail(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// Validate the whole change before applying it, since changes are atomic
+		rrsets := make(map[string]gcdResourceRecordSet)
+		for key, rrset := range f.rrsets {
+			rrsets[key] = rrset
+		}
+		for _, rrset := range change.Deletions {
+			key := rrset.Name + " " + rrset.Type
+			if existing, ok := rrsets[key]; !ok || !reflect.DeepEqual(existing, rrset) {
+				fail(http.StatusPreconditionFailed, "The resource record set "+key+" does not match")
+				return
+			}
+			delete(rrsets, key)
+		}
+		for _, rrset := range change.Additions {
+			key := rrset.Name + " " + rrset.Type
+			if _, ok := rrsets[key]; ok {
+				fail(http.StatusConflict, "The resource record set "+key+" already exists")
+				return
+			}
+			rrsets[key] = rrset
+		}
+		f.rrsets = rrsets
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": "done"})
+
+	default:
+		fail(http.StatusNotFound, "Unexpected request "+r.Method+" "+r.URL.Path)
+	}
+}
+
+func newFakeGoogleCloudDNS(t *testing.T) (types.Provider, *fakeGoogleCloudDNS, *httptest.Server) {
+	fakeAPI := &fakeGoogleCloudDNS{rrsets: make(map[string]gcdResourceRecordSet)}
+	server := httptest.NewServer(fakeAPI)
+
+	// Build the key of a service account pointing to the fake token endpoint
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	key, err := json.Marshal(&gcdServiceAccount{
+		Type:         "service_account",
+		ProjectID:    "my-project",
+		PrivateKeyID: "key",
+		PrivateKey:   string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})),
+		ClientEmail:  "dns-operator@my-project.iam.gserviceaccount.com",
+		TokenURI:     server.URL + "/token",
+	})
+	require.Nil(t, err)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "gcd", Namespace: "default"},
+		Data:       map[string][]byte{"key.json": key},
+	}
+	endpoint := server.URL + "/dns/v1"
+	resource := &v1alpha1.DNSProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "gcd", Namespace: "default"},
+		Spec: v1alpha1.DNSProviderSpec{
+			Zones: []dnsname.Name{mustName("example.com")},
+			GoogleCloudDNS: &v1alpha1.DNSProviderGoogleCloudDNS{
+				ServiceAccountSecretRef: v1alpha1.SecretReference{ObjectReference: v1alpha1.ObjectReference{Name: "gcd"}, Key: "key.json"},
+				Endpoint:                &endpoint,
+			},
+		},
+	}
+	controllerCtx := &types.ControllerContext{
+		Client: fake.NewFakeClientWithScheme(clientgoscheme.Scheme, secret),
+		Log:    ctrl.Log,
+	}
+	provider, err := ProviderFor(context.Background(), controllerCtx, resource)
+	require.Nil(t, err)
+	return provider, fakeAPI, server
+}
+
+func TestGoogleCloudDNS(t *testing.T) {
+	provider, fakeAPI, server := newFakeGoogleCloudDNS(t)
+	defer server.Close()
+
+	records := testRecords()
+	for i := range records {
+		records[i].Name = "record-" + strconv.Itoa(i)
+	}
+	testProviderRoundTrip(t, provider, records)
+
+	// The token and the name of the managed zone are reused
+	require.Equal(t, 1, fakeAPI.tokens)
+	require.Equal(t, 1, fakeAPI.zoneLookups)
+}
+
+func TestGoogleCloudDNSFixtures(t *testing.T) {
+	require := require.New(t)
+	server := serveFixtures(t, map[string]string{
+		"GET /dns/v1/projects/my-project/managedZones":                    "googleclouddns/managed-zones.json",
+		"GET /dns/v1/projects/my-project/managedZones/example-com/rrsets": "googleclouddns/rrsets.json",
+	})
+	defer server.Close()
+
+	// RRsets with routing policies, NS and SOA are ignored
+	g, err := NewGoogleCloudDNS(ctrl.Log, nil, server.Client(), server.URL+"/dns/v1", "my-project", nil)
+	require.Nil(err)
+	list, err := g.ListRecords(context.Background(), mustName("example.com"))
+	require.Nil(err)
+	requireSameRecords(t, append(testRecords(), testWildcardRecord()), list)
+}
+
+func TestGoogleCloudDNSErrors(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	provider, _, server := newFakeGoogleCloudDNS(t)
+	defer server.Close()
+
+	_, err := provider.ListRecords(ctx, mustName("example.org"))
+	require.EqualError(err, "Cannot find managed zone example.org. in project my-project")
+
+	g, err := NewGoogleCloudDNS(ctrl.Log, nil, server.Client(), server.URL+"/dns/v1", "my-project", nil)
+	require.Nil(err)
+	_, err = g.ListRecords(ctx, mustName("example.com"))
+	require.EqualError(err, "Google Cloud DNS request failed: unauthenticated")
+
+	// Exceeded quotas are counted as rate limits
+	rateLimits := testutil.ToFloat64(metrics.ProviderRateLimits.WithLabelValues("googleCloudDNS"))
+	body := []byte(`{"error": {"code": 403, "message": "Rate limit exceeded", "errors": [` +
+		`{"message": "Rate limit exceeded", "domain": "usageLimits", "reason": "rateLimitExceeded"}]}}`)
+	require.EqualError(gcdError(http.StatusForbidden, body), "Google Cloud DNS request failed: Rate limit exceeded")
+	body = []byte(`{"error": {"code": 403, "message": "Forbidden", "errors": [{"domain": "global", "reason": "forbidden"}]}}`)
+	require.EqualError(gcdError(http.StatusForbidden, body), "Google Cloud DNS request failed: Forbidden")
+	require.Equal(rateLimits+1, testutil.ToFloat64(metrics.ProviderRateLimits.WithLabelValues("googleCloudDNS")))
+}
+
+func TestGoogleCloudDNSManagedZones(t *testing.T) {
+	require := require.New(t)
+
+	g, err := NewGoogleCloudDNS(ctrl.Log, nil, nil, googleCloudDNSDefaultEndpoint, "my-project", map[string]string{"Example.com": "example-com"})
+	require.Nil(err)
+	managedZone, err := g.managedZoneFromName(context.Background(), mustName("example.com."))
+	require.Nil(err)
+	require.Equal("example-com", managedZone)
+	require.Equal("https://dns.googleapis.com/dns/v1/projects/my-project/managedZones/example-com", g.zoneURL(managedZone))
+
+	_, err = NewGoogleCloudDNS(ctrl.Log, nil, nil, googleCloudDNSDefaultEndpoint, "my-project", map[string]string{"invalid..name": "zone"})
+	require.NotNil(err)
+}
@@ -0,0 +1,84 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/95ulisse/dns-operator/pkg/metrics"
+)
+
+// maxResponseSize bounds the size of the responses read from the HTTP APIs of the providers.
+const maxResponseSize = 10 << 20
+
+// jsonErrorDecoder converts an error response of an HTTP API to an error.
+type jsonErrorDecoder func(status int, body []byte) error
+
+// doJSON performs a request against an HTTP API speaking JSON, encoding `body` and decoding the response in `out`.
+// Both `body` and `out` can be nil. Responses with a non-2xx status are converted to errors by `decodeError`.
+func doJSON(ctx context.Context, client *http.Client, method, url string, header http.Header, body interface{}, out interface{}, decodeError jsonErrorDecoder) error {
+	var payload io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, payload)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	raw, err := ioutil.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return decodeError(res.StatusCode, raw)
+	}
+	if out != nil && len(raw) > 0 {
+		if err := json.Unmarshal(raw, out); err != nil {
+			return fmt.Errorf("Cannot decode response of %s %s: %s", method, req.URL.Path, err)
+		}
+	}
+	return nil
+}
+
+// oauth2Client builds an HTTP client authenticating the requests with the tokens of the source built by `src`.
+// The tokens are refreshed outside of any operation, so their requests are bounded only by the client timeout.
+func oauth2Client(timeout time.Duration, providerType string, src func(context.Context) oauth2.TokenSource) *http.Client {
+	baseClient := &http.Client{
+		Timeout:   timeout,
+		Transport: metrics.RateLimitTransport(providerType, nil),
+	}
+	tokenCtx := context.WithValue(context.Background(), oauth2.HTTPClient, baseClient)
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &oauth2.Transport{
+			Source: src(tokenCtx),
+			Base:   baseClient.Transport,
+		},
+	}
+}
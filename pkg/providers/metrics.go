@@ -0,0 +1,49 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	"github.com/95ulisse/dns-operator/pkg/metrics"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+// metricsProvider wraps a Provider recording the number, the outcome and the duration of each of its operations.
+type metricsProvider struct {
+	types.Provider
+	providerType string
+}
+
+// UpdateRecord calls the wrapped provider and records the operation.
+func (p *metricsProvider) UpdateRecord(ctx context.Context, zone dnsname.Name, rrset v1alpha1.DNSRecord) error {
+	start := time.Now()
+	err := p.Provider.UpdateRecord(ctx, zone, rrset)
+	metrics.ObserveOperation(p.providerType, zoneKey(&zone), "update", start, err)
+	return err
+}
+
+// DeleteRecord calls the wrapped provider and records the operation.
+func (p *metricsProvider) DeleteRecord(ctx context.Context, zone dnsname.Name, rrset v1alpha1.DNSRecord) error {
+	start := time.Now()
+	err := p.Provider.DeleteRecord(ctx, zone, rrset)
+	metrics.ObserveOperation(p.providerType, zoneKey(&zone), "delete", start, err)
+	return err
+}
+
+// ListRecords calls the wrapped provider and records the operation.
+func (p *metricsProvider) ListRecords(ctx context.Context, zone dnsname.Name) ([]v1alpha1.DNSRecord, error) {
+	start := time.Now()
+	res, err := p.Provider.ListRecords(ctx, zone)
+	metrics.ObserveOperation(p.providerType, zoneKey(&zone), "list", start, err)
+	return res, err
+}
+
+// GetRecord calls the wrapped provider and records the operation.
+func (p *metricsProvider) GetRecord(ctx context.Context, zone dnsname.Name, rrset v1alpha1.DNSRecord) (*v1alpha1.DNSRecord, error) {
+	start := time.Now()
+	res, err := p.Provider.GetRecord(ctx, zone, rrset)
+	metrics.ObserveOperation(p.providerType, zoneKey(&zone), "get", start, err)
+	return res, err
+}
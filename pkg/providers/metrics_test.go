@@ -0,0 +1,66 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/metrics"
+)
+
+func TestMetricsProvider(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	zone := mustName("Metrics.example.com.")
+
+	provider := &metricsProvider{Provider: newMemoryProvider(), providerType: "memory"}
+	var record v1alpha1.DNSRecord
+	record.Spec.Name = mustName("www.metrics.example.com.")
+	record.Spec.RRSet.A = []v1alpha1.Ipv4String{"1.1.1.1"}
+
+	require.Nil(provider.UpdateRecord(ctx, zone, record))
+	require.Nil(provider.UpdateRecord(ctx, zone, record))
+	_, err := provider.ListRecords(ctx, zone)
+	require.Nil(err)
+
+	// The same zone has the same label whether it is fully qualified or not
+	require.Nil(provider.DeleteRecord(ctx, mustName("metrics.example.com"), record))
+	require.Nil(provider.DeleteRecord(ctx, zone, record))
+	require.Equal(2.0, testutil.ToFloat64(metrics.ProviderOperations.WithLabelValues("memory", "metrics.example.com.", "delete")))
+
+	require.Equal(2.0, testutil.ToFloat64(metrics.ProviderOperations.WithLabelValues("memory", "metrics.example.com.", "update")))
+	require.Equal(1.0, testutil.ToFloat64(metrics.ProviderOperations.WithLabelValues("memory", "metrics.example.com.", "list")))
+	require.Equal(0.0, testutil.ToFloat64(metrics.ProviderOperationErrors.WithLabelValues("memory", "metrics.example.com.", "update")))
+
+	// Operations not supported by the provider are not recorded
+	unsupported := &metricsProvider{Provider: NewDummy(ctrl.Log, nil), providerType: "unsupported"}
+	_, err = unsupported.GetRecord(ctx, zone, record)
+	require.NotNil(err)
+	require.Equal(0.0, testutil.ToFloat64(metrics.ProviderOperations.WithLabelValues("unsupported", "metrics.example.com.", "get")))
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	require := require.New(t)
+
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: metrics.RateLimitTransport("test", nil)}
+	for _, s := range []int{http.StatusOK, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusTooManyRequests} {
+		status = s
+		res, err := client.Get(server.URL)
+		require.Nil(err)
+		res.Body.Close()
+	}
+
+	require.Equal(2.0, testutil.ToFloat64(metrics.ProviderRateLimits.WithLabelValues("test")))
+}
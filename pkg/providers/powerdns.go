@@ -0,0 +1,310 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+
+	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	"github.com/95ulisse/dns-operator/pkg/metrics"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+const powerDNSDefaultServerID = "localhost"
+
+// PowerDNS DNS provider, using the HTTP API of a PowerDNS Authoritative Server.
+type PowerDNS struct {
+	log              logr.Logger
+	zones            []dnsname.Name
+	client           *http.Client
+	serverURL        string
+	apiKey           string
+	createZones      bool
+	nameservers      []string
+	zonesIDCache     map[string]string
+	zonesIDCacheLock sync.RWMutex
+}
+
+// NewPowerDNS creates a new instance of the PowerDNS provider.
+// `serverURL` is the URL of the server in the API (e.g., `http://powerdns:8081/api/v1/servers/localhost`).
+// If `createZones` is true, the zones which do not exist are created with the given nameservers.
+func NewPowerDNS(log logr.Logger, zones []dnsname.Name, client *http.Client, serverURL, apiKey string, createZones bool, nameservers []dnsname.Name) *PowerDNS {
+	provider := &PowerDNS{
+		log:          log.WithName("providers").WithName("PowerDNS"),
+		zones:        zones,
+		client:       client,
+		serverURL:    strings.TrimSuffix(serverURL, "/"),
+		apiKey:       apiKey,
+		createZones:  createZones,
+		nameservers:  []string{},
+		zonesIDCache: make(map[string]string),
+	}
+	for _, ns := range nameservers {
+		provider.nameservers = append(provider.nameservers, strings.ToLower(ns.ToFQDN().String()))
+	}
+	return provider
+}
+
+// Zones returns a slice containing the DNS zones managed by this provider.
+func (pdns *PowerDNS) Zones() []dnsname.Name {
+	return pdns.zones
+}
+
+// UpdateRecord replaces the whole RRset on PowerDNS with the given one.
+func (pdns *PowerDNS) UpdateRecord(ctx context.Context, zone dnsname.Name, resource v1alpha1.DNSRecord) error {
+	zoneID, err := pdns.zoneIDFromName(ctx, zone)
+	if err != nil {
+		return err
+	}
+	rrset, err := toPDNSRRSet(&resource)
+	if err != nil {
+		return err
+	}
+	rrset.ChangeType = "REPLACE"
+
+	pdns.log.V(1).Info("Replacing DNS record", "name", rrset.Name, "type", rrset.Type)
+	return pdns.patchRRSet(ctx, zoneID, rrset)
+}
+
+// DeleteRecord deletes the given RRset from PowerDNS.
+func (pdns *PowerDNS) DeleteRecord(ctx context.Context, zone dnsname.Name, resource v1alpha1.DNSRecord) error {
+	zoneID, err := pdns.zoneIDFromName(ctx, zone)
+	if err != nil {
+		return err
+	}
+	rrset := &pdnsRRSet{
+		Name:       strings.ToLower(resource.Spec.Name.ToFQDN().String()),
+		Type:       resource.RType(),
+		ChangeType: "DELETE",
+	}
+
+	pdns.log.V(1).Info("Deleting DNS record", "name", rrset.Name, "type", rrset.Type)
+	return pdns.patchRRSet(ctx, zoneID, rrset)
+}
+
+// GetRecord returns the RRset registered on PowerDNS with the same name and type of the given resource.
+func (pdns *PowerDNS) GetRecord(ctx context.Context, zone dnsname.Name, resource v1alpha1.DNSRecord) (*v1alpha1.DNSRecord, error) {
+	zoneID, err := pdns.zoneIDFromName(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	// Recent versions of PowerDNS return only the requested RRset, older ones the whole zone
+	name := strings.ToLower(resource.Spec.Name.ToFQDN().String())
+	query := url.Values{}
+	query.Set("rrset_name", name)
+	query.Set("rrset_type", resource.RType())
+	rrsets, err := pdns.listRRSets(ctx, zoneID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	set := newRecordSet()
+	for i := range rrsets {
+		if strings.ToLower(rrsets[i].Name) == name && rrsets[i].Type == resource.RType() {
+			if err := addPDNSRRSet(set, &rrsets[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return set.first(), nil
+}
+
+// ListRecords returns all the RRsets registered on PowerDNS for the given zone.
+func (pdns *PowerDNS) ListRecords(ctx context.Context, zone dnsname.Name) ([]v1alpha1.DNSRecord, error) {
+	zoneID, err := pdns.zoneIDFromName(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	rrsets, err := pdns.listRRSets(ctx, zoneID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	set := newRecordSet()
+	for i := range rrsets {
+		if err := addPDNSRRSet(set, &rrsets[i]); err != nil {
+			return nil, err
+		}
+	}
+	return set.list(), nil
+}
+
+// listRRSets returns the RRsets of a zone, optionally filtered by the given query.
+func (pdns *PowerDNS) listRRSets(ctx context.Context, zoneID string, query url.Values) ([]pdnsRRSet, error) {
+	u := pdns.serverURL + "/zones/" + url.PathEscape(zoneID)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var res struct {
+		RRSets []pdnsRRSet `json:"rrsets"`
+	}
+	if err := doJSON(ctx, pdns.client, http.MethodGet, u, pdns.header(), nil, &res, pdnsError); err != nil {
+		return nil, err
+	}
+	return res.RRSets, nil
+}
+
+// patchRRSet applies a single change to a zone. Changes to an RRset are atomic.
+func (pdns *PowerDNS) patchRRSet(ctx context.Context, zoneID string, rrset *pdnsRRSet) error {
+	body := struct {
+		RRSets []pdnsRRSet `json:"rrsets"`
+	}{
+		RRSets: []pdnsRRSet{*rrset},
+	}
+	return doJSON(ctx, pdns.client, http.MethodPatch, pdns.serverURL+"/zones/"+url.PathEscape(zoneID), pdns.header(), &body, nil, pdnsError)
+}
+
+func (pdns *PowerDNS) header() http.Header {
+	header := http.Header{}
+	header.Set("X-API-Key", pdns.apiKey)
+	return header
+}
+
+func (pdns *PowerDNS) zoneIDFromName(ctx context.Context, zone dnsname.Name) (string, error) {
+	name := zoneKey(&zone)
+
+	// First check if the zone is in the cache
+	id := func() string {
+		pdns.zonesIDCacheLock.RLock()
+		defer pdns.zonesIDCacheLock.RUnlock()
+		return pdns.zonesIDCache[name]
+	}()
+	if id != "" {
+		return id, nil
+	}
+
+	// Look up the zone by name
+	var zones []pdnsZone
+	if err := doJSON(ctx, pdns.client, http.MethodGet, pdns.serverURL+"/zones?zone="+url.QueryEscape(name), pdns.header(), nil, &zones, pdnsError); err != nil {
+		pdns.log.Error(err, "Could not resolve zone name", "zone", zone.String())
+		return "", err
+	}
+	for _, z := range zones {
+		if strings.ToLower(z.Name) == name {
+			id = z.ID
+		}
+	}
+
+	// Create the zone if it does not exist
+	if id == "" {
+		if !pdns.createZones {
+			return "", fmt.Errorf("Cannot find zone %s", name)
+		}
+		pdns.log.Info("Creating zone", "zone", name)
+		created := pdnsZone{Name: name, Kind: "Native", Nameservers: pdns.nameservers}
+		if err := doJSON(ctx, pdns.client, http.MethodPost, pdns.serverURL+"/zones", pdns.header(), &created, &created, pdnsError); err != nil {
+			return "", err
+		}
+		id = created.ID
+	}
+
+	// Store the id in the cache
+	pdns.zonesIDCacheLock.Lock()
+	defer pdns.zonesIDCacheLock.Unlock()
+	pdns.zonesIDCache[name] = id
+
+	return id, nil
+}
+
+// pdnsError builds an error out of an error response of the PowerDNS API.
+func pdnsError(status int, body []byte) error {
+	var res struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil || res.Error == "" {
+		return fmt.Errorf("PowerDNS request failed with status %d", status)
+	}
+	return fmt.Errorf("PowerDNS request failed: %s", res.Error)
+}
+
+// PowerDNS API payloads
+
+type pdnsZone struct {
+	ID          string   `json:"id,omitempty"`
+	Name        string   `json:"name"`
+	Kind        string   `json:"kind,omitempty"`
+	Nameservers []string `json:"nameservers"`
+}
+
+type pdnsRecord struct {
+	Content  string `json:"content"`
+	Disabled bool   `json:"disabled"`
+}
+
+type pdnsRRSet struct {
+	Name       string       `json:"name"`
+	Type       string       `json:"type"`
+	TTL        uint32       `json:"ttl,omitempty"`
+	ChangeType string       `json:"changetype,omitempty"`
+	Records    []pdnsRecord `json:"records,omitempty"`
+}
+
+// toPDNSRRSet converts a DNSRecord resource to a PowerDNS RRset.
+func toPDNSRRSet(resource *v1alpha1.DNSRecord) (*pdnsRRSet, error) {
+	rdata, err := toRData(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := v1alpha1.DefaultTTLSeconds
+	if resource.Spec.TTLSeconds != nil {
+		ttl = *resource.Spec.TTLSeconds
+	}
+	rrset := &pdnsRRSet{
+		Name:    strings.ToLower(resource.Spec.Name.ToFQDN().String()),
+		Type:    resource.RType(),
+		TTL:     ttl,
+		Records: make([]pdnsRecord, 0, len(rdata)),
+	}
+	for _, content := range rdata {
+		rrset.Records = append(rrset.Records, pdnsRecord{Content: content})
+	}
+	return rrset, nil
+}
+
+// addPDNSRRSet adds an RRset read from PowerDNS to the given set, performing the opposite conversion of toPDNSRRSet.
+// Disabled records and RRsets of unsupported types are ignored.
+func addPDNSRRSet(set *recordSet, rrset *pdnsRRSet) error {
+	for _, record := range rrset.Records {
+		if record.Disabled {
+			continue
+		}
+		if err := addRData(set, rrset.Name, rrset.Type, rrset.TTL, record.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	RegisterProviderConstructor("powerdns", func(ctx context.Context, controllerCtx *types.ControllerContext, resource *dnsv1alpha1.DNSProvider) (types.Provider, error) {
+		spec := resource.Spec.PowerDNS
+
+		apiKey, err := readSecretKey(ctx, controllerCtx, resource, &spec.APIKeySecretRef)
+		if err != nil {
+			return nil, err
+		}
+
+		serverID := powerDNSDefaultServerID
+		if spec.ServerID != nil && *spec.ServerID != "" {
+			serverID = *spec.ServerID
+		}
+		serverURL := strings.TrimSuffix(spec.URL, "/") + "/api/v1/servers/" + url.PathEscape(serverID)
+		createZones := spec.CreateZones != nil && *spec.CreateZones
+
+		client := &http.Client{
+			Timeout:   resource.GetTimeout(),
+			Transport: metrics.RateLimitTransport("powerdns", nil),
+		}
+		return NewPowerDNS(controllerCtx.Log, resource.Spec.Zones, client, serverURL, string(apiKey), createZones, spec.Nameservers), nil
+	})
+}
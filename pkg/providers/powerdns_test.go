@@ -0,0 +1,198 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+// fakePowerDNS is a minimal in-memory implementation of the HTTP API of a PowerDNS Authoritative Server.
+type fakePowerDNS struct {
+	sync.Mutex
+	zones map[string]*pdnsZone
+	rrset map[string]map[string]pdnsRRSet
+}
+
+func (f *fakePowerDNS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	fail := func(status int, message string) {
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+	}
+
+	if r.Header.Get("X-API-Key") != "my-key" {
+		fail(http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	const serverPath = "/api/v1/servers/localhost/zones"
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == serverPath:
+		zones := []*pdnsZone{}
+		if zone, ok := f.zones[r.URL.Query().Get("zone")]; ok {
+			zones = append(zones, zone)
+		}
+		_ = json.NewEncoder(w).Encode(zones)
+
+	case r.Method == http.MethodPost && r.URL.Path == serverPath:
+		var zone pdnsZone
+		if err := json.NewDecoder(r.Body).Decode(&zone); err != nil || zone.Nameservers == nil {
+			fail(http.StatusUnprocessableEntity, "Invalid zone")
+			return
+		}
+		zone.ID = zone.Name
+		f.zones[zone.Name] = &zone
+		f.rrset[zone.ID] = make(map[string]pdnsRRSet)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(&zone)
+
+	case strings.HasPrefix(r.URL.Path, serverPath+"/"):
+		rrsets, ok := f.rrset[strings.TrimPrefix(r.URL.Path, serverPath+"/")]
+		if !ok {
+			fail(http.StatusNotFound, "Not Found")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			// Return the whole zone regardless of the filters, like older versions of PowerDNS
+			var keys []string
+			for key := range rrsets {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			res := struct {
+				RRSets []pdnsRRSet `json:"rrsets"`
+			}{}
+			for _, key := range keys {
+				res.RRSets = append(res.RRSets, rrsets[key])
+			}
+			_ = json.NewEncoder(w).Encode(&res)
+
+		case http.MethodPatch:
+			var req struct {
+				RRSets []pdnsRRSet `json:"rrsets"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				fail(http.StatusBadRequest, err.Error())
+				return
+			}
+			for _, rrset := range req.RRSets {
+				key := rrset.Name + " " + rrset.Type
+				switch rrset.ChangeType {
+				case "REPLACE":
+					rrset.ChangeType = ""
+					rrsets[key] = rrset
+				case "DELETE":
+					delete(rrsets, key)
+				default:
+					fail(http.StatusUnprocessableEntity, "Invalid changetype "+rrset.ChangeType)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+
+	default:
+		fail(http.StatusNotFound, "Not Found")
+	}
+}
+
+func newFakePowerDNS(t *testing.T, createZones bool) (types.Provider, *fakePowerDNS, *httptest.Server) {
+	fakeAPI := &fakePowerDNS{zones: make(map[string]*pdnsZone), rrset: make(map[string]map[string]pdnsRRSet)}
+	server := httptest.NewServer(fakeAPI)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "powerdns", Namespace: "default"},
+		Data:       map[string][]byte{"key": []byte("my-key")},
+	}
+	resource := &v1alpha1.DNSProvider{
+		ObjectMeta: metav1.ObjectMeta{Name: "powerdns", Namespace: "default"},
+		Spec: v1alpha1.DNSProviderSpec{
+			Zones: []dnsname.Name{mustName("example.com")},
+			PowerDNS: &v1alpha1.DNSProviderPowerDNS{
+				URL:             server.URL,
+				APIKeySecretRef: v1alpha1.SecretReference{ObjectReference: v1alpha1.ObjectReference{Name: "powerdns"}, Key: "key"},
+				CreateZones:     &createZones,
+				Nameservers:     []dnsname.Name{mustName("ns1.example.com")},
+			},
+		},
+	}
+	controllerCtx := &types.ControllerContext{
+		Client: fake.NewFakeClientWithScheme(clientgoscheme.Scheme, secret),
+		Log:    ctrl.Log,
+	}
+	provider, err := ProviderFor(context.Background(), controllerCtx, resource)
+	require.Nil(t, err)
+	return provider, fakeAPI, server
+}
+
+func TestPowerDNS(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	zone := mustName("example.com")
+	provider, fakeAPI, server := newFakePowerDNS(t, true)
+	defer server.Close()
+
+	records := append(testRecords(), testWildcardRecord())
+	for i := range records {
+		records[i].Name = "record-" + strconv.Itoa(i)
+	}
+	testProviderRoundTrip(t, provider, records)
+
+	// The zone has been created on the first use
+	require.Equal([]string{"ns1.example.com."}, fakeAPI.zones["example.com."].Nameservers)
+
+	// Disabled records are ignored
+	require.Nil(provider.UpdateRecord(ctx, zone, records[0]))
+	rrset := fakeAPI.rrset["example.com."]["www.example.com. A"]
+	rrset.Records = append(rrset.Records, pdnsRecord{Content: "9.9.9.9", Disabled: true})
+	fakeAPI.rrset["example.com."]["www.example.com. A"] = rrset
+	published, err := provider.GetRecord(ctx, zone, records[0])
+	require.Nil(err)
+	require.Equal(records[0].Spec.RRSet.A, published.Spec.RRSet.A)
+}
+
+func TestPowerDNSFixtures(t *testing.T) {
+	require := require.New(t)
+	server := serveFixtures(t, map[string]string{
+		"GET /api/v1/servers/localhost/zones":              "powerdns/zones.json",
+		"GET /api/v1/servers/localhost/zones/example.com.": "powerdns/zone.json",
+	})
+	defer server.Close()
+
+	// Disabled records, NS and SOA are ignored
+	provider := NewPowerDNS(ctrl.Log, nil, server.Client(), server.URL+"/api/v1/servers/localhost", "my-key", false, nil)
+	list, err := provider.ListRecords(context.Background(), mustName("example.com"))
+	require.Nil(err)
+	requireSameRecords(t, append(testRecords(), testWildcardRecord()), list)
+}
+
+func TestPowerDNSWithoutZoneCreation(t *testing.T) {
+	require := require.New(t)
+	provider, fakeAPI, server := newFakePowerDNS(t, false)
+	defer server.Close()
+
+	_, err := provider.ListRecords(context.Background(), mustName("example.com"))
+	require.EqualError(err, "Cannot find zone example.com.")
+	require.Empty(fakeAPI.zones)
+}
@@ -0,0 +1,82 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+)
+
+// recordSet accumulates the records read back from a backend, grouping them in RRsets by name and type.
+type recordSet struct {
+	keys    []string
+	records map[string]*v1alpha1.DNSRecord
+}
+
+func newRecordSet() *recordSet {
+	return &recordSet{
+		records: make(map[string]*v1alpha1.DNSRecord),
+	}
+}
+
+// rrset returns the data of the RRset with the given name and type, creating it if needed.
+// A nil TTL means that the TTL is not known or is managed automatically by the backend.
+func (set *recordSet) rrset(name dnsname.Name, rtype string, ttl *uint32) *v1alpha1.DNSRecordSetData {
+	key := strings.ToLower(name.ToFQDN().String()) + " " + rtype
+	record, ok := set.records[key]
+	if !ok {
+		record = &v1alpha1.DNSRecord{}
+		record.Spec.Name = *name.ToFQDN()
+		record.Spec.TTLSeconds = ttl
+		set.records[key] = record
+		set.keys = append(set.keys, key)
+	}
+	return &record.Spec.RRSet
+}
+
+// list returns all the RRsets in the order they were first seen.
+func (set *recordSet) list() []v1alpha1.DNSRecord {
+	list := make([]v1alpha1.DNSRecord, 0, len(set.keys))
+	for _, key := range set.keys {
+		list = append(list, *set.records[key])
+	}
+	return list
+}
+
+// first returns the first RRset, or nil if the set is empty.
+func (set *recordSet) first() *v1alpha1.DNSRecord {
+	if len(set.keys) == 0 {
+		return nil
+	}
+	return set.records[set.keys[0]]
+}
+
+// toRData converts a DNSRecord resource to the contents of its records in the presentation format of the zone files,
+// which is the format used by many HTTP APIs (e.g., `10 mail.example.com.` for an MX record).
+func toRData(resource *v1alpha1.DNSRecord) ([]string, error) {
+	rrs, err := toRRSet(resource)
+	if err != nil {
+		return nil, err
+	}
+	rdata := make([]string, 0, len(rrs))
+	for _, rr := range rrs {
+		rdata = append(rdata, strings.TrimPrefix(rr.String(), rr.Header().String()))
+	}
+	return rdata, nil
+}
+
+// addRData adds a record in the presentation format of the zone files to the given set,
+// performing the opposite conversion of toRData. Records of unsupported types are ignored.
+func addRData(set *recordSet, name string, rtype string, ttl uint32, rdata string) error {
+	rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", name, ttl, rtype, rdata))
+	if err != nil {
+		return fmt.Errorf("Cannot parse %s record %s: %s", rtype, name, err)
+	}
+	if rr == nil {
+		return nil
+	}
+	return addRR(set, rr)
+}
@@ -0,0 +1,190 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+func mustName(name string) dnsname.Name {
+	n, err := dnsname.NewName(name)
+	if err != nil {
+		panic(err)
+	}
+	return *n
+}
+
+func testRecords() []v1alpha1.DNSRecord {
+	ttl := uint32(300)
+	records := []v1alpha1.DNSRecord{
+		{Spec: v1alpha1.DNSRecordSpec{Name: mustName("www.example.com"), RRSet: v1alpha1.DNSRecordSetData{
+			A: []v1alpha1.Ipv4String{"1.1.1.1", "8.8.8.8"},
+		}}},
+		{Spec: v1alpha1.DNSRecordSpec{Name: mustName("www.example.com"), RRSet: v1alpha1.DNSRecordSetData{
+			AAAA: []v1alpha1.Ipv6String{"2001:db8::1"},
+		}}},
+		{Spec: v1alpha1.DNSRecordSpec{Name: mustName("example.com"), RRSet: v1alpha1.DNSRecordSetData{
+			MX: []v1alpha1.MXRData{{Preference: 10, Host: mustName("mail.example.com")}},
+		}}},
+		{Spec: v1alpha1.DNSRecordSpec{Name: mustName("alias.example.com"), RRSet: v1alpha1.DNSRecordSetData{
+			CNAME: []dnsname.Name{mustName("www.example.com")},
+		}}},
+		{Spec: v1alpha1.DNSRecordSpec{Name: mustName("example.com"), RRSet: v1alpha1.DNSRecordSetData{
+			TXT: []string{"v=spf1 -all", strings.Repeat("a", 300)},
+		}}},
+		{Spec: v1alpha1.DNSRecordSpec{Name: mustName("_sip._tcp.example.com"), RRSet: v1alpha1.DNSRecordSetData{
+			SRV: []v1alpha1.SRVRData{{Priority: 10, Weight: 5, Port: 5060, Target: mustName("sip.example.com")}},
+		}}},
+		{Spec: v1alpha1.DNSRecordSpec{Name: mustName("example.com"), RRSet: v1alpha1.DNSRecordSetData{
+			CAA: []v1alpha1.CAARData{{Tag: v1alpha1.CAAIssueTag, Value: "letsencrypt.org"}},
+		}}},
+	}
+	for i := range records {
+		records[i].Spec.TTLSeconds = &ttl
+	}
+	return records
+}
+
+// testWildcardRecord returns the RRset `*.example.com A 1.1.1.1`, published by the providers supporting wildcards.
+func testWildcardRecord() v1alpha1.DNSRecord {
+	return v1alpha1.DNSRecord{Spec: v1alpha1.DNSRecordSpec{Name: mustName("*.example.com"), RRSet: v1alpha1.DNSRecordSetData{
+		A: []v1alpha1.Ipv4String{"1.1.1.1"},
+	}}}
+}
+
+// testProviderRoundTrip publishes the given records in the zone `example.com`, and checks that they are read back unchanged,
+// that updates replace the whole RRset and that deletions remove it. The first record must be an A RRset.
+func testProviderRoundTrip(t *testing.T, provider types.Provider, records []v1alpha1.DNSRecord) {
+	require := require.New(t)
+	ctx := context.Background()
+	zone := mustName("example.com")
+
+	// Every RRset is read back as it was published
+	for _, record := range records {
+		require.Nil(provider.UpdateRecord(ctx, zone, record))
+		published, err := provider.GetRecord(ctx, zone, record)
+		require.Nil(err)
+		require.NotNil(published, "RRset %s %s not found", record.Spec.Name.String(), record.RType())
+		require.True(record.RRSetEquals(published), "RRset %s %s changed in conversion", record.Spec.Name.String(), record.RType())
+	}
+	list, err := provider.ListRecords(ctx, zone)
+	require.Nil(err)
+	requireSameRecords(t, records, list)
+
+	// The whole RRset is replaced
+	record := records[0]
+	record.Spec.RRSet.A = []v1alpha1.Ipv4String{"9.9.9.9"}
+	require.Nil(provider.UpdateRecord(ctx, zone, record))
+	published, err := provider.GetRecord(ctx, zone, record)
+	require.Nil(err)
+	require.Equal([]v1alpha1.Ipv4String{"9.9.9.9"}, published.Spec.RRSet.A)
+
+	// Deletion removes the published RRset, even if it differs from the resource
+	require.Nil(provider.DeleteRecord(ctx, zone, records[0]))
+	published, err = provider.GetRecord(ctx, zone, records[0])
+	require.Nil(err)
+	require.Nil(published)
+	require.Nil(provider.DeleteRecord(ctx, zone, records[0]))
+}
+
+// requireSameRecords checks that the two lists contain the same RRsets, regardless of their order.
+func requireSameRecords(t *testing.T, expected []v1alpha1.DNSRecord, actual []v1alpha1.DNSRecord) {
+	require.Len(t, actual, len(expected))
+	for i := range expected {
+		found := false
+		for j := range actual {
+			found = found || expected[i].RRSetEquals(&actual[j])
+		}
+		require.True(t, found, "RRset %s %s not found", expected[i].Spec.Name.String(), expected[i].RType())
+	}
+}
+
+// serveFixtures starts a server answering the given requests, in the form `METHOD /path`,
+// with the content of the corresponding files in `testdata`, which reproduce the bodies returned by the real APIs.
+func serveFixtures(t *testing.T, fixtures map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, ok := fixtures[r.Method+" "+r.URL.Path]
+		if !ok {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, err := ioutil.ReadFile(filepath.Join("testdata", file))
+		if err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if filepath.Ext(file) == ".xml" {
+			w.Header().Set("Content-Type", "text/xml")
+		} else {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		}
+		_, _ = w.Write(body)
+	}))
+}
+
+func TestRFC2136RoundTrip(t *testing.T) {
+	require := require.New(t)
+
+	for _, record := range testRecords() {
+		rrset, err := toRRSet(&record)
+		require.Nil(err)
+
+		set := newRecordSet()
+		for _, rr := range rrset {
+			require.Nil(addRR(set, rr))
+		}
+		require.Len(set.list(), 1)
+		require.True(record.RRSetEquals(set.first()), "RRset %s %s changed in conversion", record.Spec.Name.String(), record.RType())
+	}
+}
+
+func TestCloudflareRoundTrip(t *testing.T) {
+	require := require.New(t)
+	cf := NewCloudflare(ctrl.Log, nil, nil, false)
+
+	for _, record := range testRecords() {
+		rrset, err := cf.toCFRecords(&record)
+		require.Nil(err)
+
+		// Simulate the generic payload returned by the API
+		set := newRecordSet()
+		for _, rr := range rrset {
+			raw, err := json.Marshal(rr)
+			require.Nil(err)
+			require.Nil(json.Unmarshal(raw, &rr))
+			require.Nil(addCFRecord(set, &rr))
+		}
+		require.Len(set.list(), 1)
+		require.True(record.RRSetEquals(set.first()), "RRset %s %s changed in conversion", record.Spec.Name.String(), record.RType())
+	}
+}
+
+func TestRecordSetGrouping(t *testing.T) {
+	require := require.New(t)
+
+	set := newRecordSet()
+	set.rrset(mustName("www.example.com"), "A", nil).A = []v1alpha1.Ipv4String{"1.1.1.1"}
+	data := set.rrset(mustName("WWW.example.com."), "A", nil)
+	data.A = append(data.A, "8.8.8.8")
+	set.rrset(mustName("www.example.com"), "AAAA", nil).AAAA = []v1alpha1.Ipv6String{"::1"}
+
+	list := set.list()
+	require.Len(list, 2)
+	require.Equal("A", list[0].RType())
+	require.Len(list[0].Spec.RRSet.A, 2)
+	require.Equal("AAAA", list[1].RType())
+}
@@ -0,0 +1,165 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+// registryPrefix is the prefix of the first label of the TXT records holding the ownership of the RRsets.
+const registryPrefix = "_dns-operator-"
+
+// ownershipProvider wraps a Provider keeping track of the owner of each RRset with a companion TXT record,
+// and refusing to modify or delete the RRsets not owned by the resource being reconciled.
+//
+// The ownership record of an RRset is published at `_dns-operator-<type>.<name>`
+// (`_dns-operator-wildcard-<type>.<parent>` for wildcard names), since names with a CNAME cannot have other records.
+// Providers which cannot read back records cannot verify the ownership, so they are left unchecked.
+type ownershipProvider struct {
+	types.Provider
+	clusterID string
+}
+
+// UpdateRecord updates the given RRset, if owned by the resource or if the resource allows adoption.
+func (p *ownershipProvider) UpdateRecord(ctx context.Context, zone dnsname.Name, rrset v1alpha1.DNSRecord) error {
+	registry, err := p.checkOwnership(ctx, zone, &rrset)
+	if errors.Is(err, types.ErrNotSupported) {
+		return p.Provider.UpdateRecord(ctx, zone, rrset)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The ownership is claimed before touching the RRset, so that a failed update can be retried
+	if err := p.Provider.UpdateRecord(ctx, zone, *registry); err != nil {
+		return err
+	}
+	return p.Provider.UpdateRecord(ctx, zone, rrset)
+}
+
+// DeleteRecord deletes the given RRset, if owned by the resource or if the resource allows adoption.
+func (p *ownershipProvider) DeleteRecord(ctx context.Context, zone dnsname.Name, rrset v1alpha1.DNSRecord) error {
+	registry, err := p.checkOwnership(ctx, zone, &rrset)
+	if errors.Is(err, types.ErrNotSupported) {
+		return p.Provider.DeleteRecord(ctx, zone, rrset)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The ownership is released only after the RRset is gone, so that a failed deletion can be retried
+	if err := p.Provider.DeleteRecord(ctx, zone, rrset); err != nil {
+		return err
+	}
+	return p.Provider.DeleteRecord(ctx, zone, *registry)
+}
+
+// ListRecords returns all the RRsets of the zone, hiding the ownership records.
+func (p *ownershipProvider) ListRecords(ctx context.Context, zone dnsname.Name) ([]v1alpha1.DNSRecord, error) {
+	records, err := p.Provider.ListRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]v1alpha1.DNSRecord, 0, len(records))
+	for _, record := range records {
+		if !isRegistryName(&record.Spec.Name) {
+			res = append(res, record)
+		}
+	}
+	return res, nil
+}
+
+// checkOwnership verifies that the given resource can modify its RRset,
+// and returns the ownership record to publish alongside it.
+func (p *ownershipProvider) checkOwnership(ctx context.Context, zone dnsname.Name, resource *v1alpha1.DNSRecord) (*v1alpha1.DNSRecord, error) {
+	registry, err := registryRecord(p.clusterID, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	// Look up both the RRset and its current owner
+	existing, err := p.Provider.GetRecord(ctx, zone, *resource)
+	if err != nil {
+		return nil, err
+	}
+	owners, err := p.Provider.GetRecord(ctx, zone, *registry)
+	if err != nil {
+		return nil, err
+	}
+	var current []string
+	if owners != nil {
+		current = owners.Spec.RRSet.TXT
+	}
+
+	if !isOwnedBy(existing != nil, current, registry.Spec.RRSet.TXT[0]) && !isPublishedBy(existing, current, resource) &&
+		(resource.Spec.AdoptionPolicy == nil || *resource.Spec.AdoptionPolicy != v1alpha1.AdoptPolicy) {
+		if len(current) == 0 {
+			return nil, fmt.Errorf("%w: %s %s already exists and has no owner", types.ErrNotOwned, resource.RType(), resource.Spec.Name.String())
+		}
+		return nil, fmt.Errorf("%w: %s %s is owned by %s", types.ErrNotOwned, resource.RType(), resource.Spec.Name.String(), strings.Join(current, ", "))
+	}
+
+	return registry, nil
+}
+
+// isOwnedBy tells whether an RRset with the given owners belongs to `owner`.
+// RRsets which do not exist and have no owner can be claimed by anyone.
+func isOwnedBy(exists bool, owners []string, owner string) bool {
+	if len(owners) == 0 {
+		return !exists
+	}
+	for _, o := range owners {
+		if o == owner {
+			return true
+		}
+	}
+	return false
+}
+
+// isPublishedBy tells whether an RRset without owners has been published by the given resource itself,
+// as is the case for the RRsets published by the versions of the operator predating the ownership records.
+// The status of the resource must report the same name, and the RRset must still have the contents of the resource.
+func isPublishedBy(existing *v1alpha1.DNSRecord, owners []string, resource *v1alpha1.DNSRecord) bool {
+	return existing != nil && len(owners) == 0 &&
+		resource.Status.FQDN != nil && resource.Status.FQDN.Equal(&resource.Spec.Name) &&
+		resource.RRSetEquals(existing)
+}
+
+// ownerOf returns the identifier of the owner of the given resource, as stored in the ownership records.
+func ownerOf(clusterID string, resource *v1alpha1.DNSRecord) string {
+	return fmt.Sprintf("heritage=dns-operator,cluster=%s,resource=%s/%s", clusterID, resource.Namespace, resource.Name)
+}
+
+// registryRecord returns the ownership record of the RRset of the given resource.
+func registryRecord(clusterID string, resource *v1alpha1.DNSRecord) (*v1alpha1.DNSRecord, error) {
+	labels := resource.Spec.Name.Labels()
+	prefix := registryPrefix
+	if resource.Spec.Name.IsWildcard() {
+		prefix += "wildcard-"
+		labels = labels[1:]
+	}
+	labels = append([]string{prefix + strings.ToLower(resource.RType())}, labels...)
+
+	name, err := dnsname.NewName(strings.Join(labels, ".") + ".")
+	if err != nil {
+		return nil, fmt.Errorf("Cannot build the ownership record name for %s: %s", resource.Spec.Name.String(), err)
+	}
+
+	registry := &v1alpha1.DNSRecord{}
+	registry.Spec.Name = *name
+	registry.Spec.TTLSeconds = resource.Spec.TTLSeconds
+	registry.Spec.RRSet.TXT = []string{ownerOf(clusterID, resource)}
+	return registry, nil
+}
+
+// isRegistryName tells whether the given name belongs to an ownership record.
+func isRegistryName(name *dnsname.Name) bool {
+	labels := name.Labels()
+	return len(labels) > 0 && strings.HasPrefix(strings.ToLower(labels[0]), registryPrefix)
+}
@@ -0,0 +1,159 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+// memoryProvider is a provider keeping the RRsets in memory, indexed by name and type.
+type memoryProvider struct {
+	*Dummy
+	rrsets map[string]v1alpha1.DNSRecord
+}
+
+func newMemoryProvider() *memoryProvider {
+	return &memoryProvider{Dummy: &Dummy{}, rrsets: make(map[string]v1alpha1.DNSRecord)}
+}
+
+func memoryKey(rrset *v1alpha1.DNSRecord) string {
+	return strings.ToLower(rrset.Spec.Name.ToFQDN().String()) + " " + rrset.RType()
+}
+
+func (p *memoryProvider) UpdateRecord(ctx context.Context, zone dnsname.Name, rrset v1alpha1.DNSRecord) error {
+	p.rrsets[memoryKey(&rrset)] = rrset
+	return nil
+}
+
+func (p *memoryProvider) DeleteRecord(ctx context.Context, zone dnsname.Name, rrset v1alpha1.DNSRecord) error {
+	delete(p.rrsets, memoryKey(&rrset))
+	return nil
+}
+
+func (p *memoryProvider) GetRecord(ctx context.Context, zone dnsname.Name, rrset v1alpha1.DNSRecord) (*v1alpha1.DNSRecord, error) {
+	if existing, ok := p.rrsets[memoryKey(&rrset)]; ok {
+		return &existing, nil
+	}
+	return nil, nil
+}
+
+func (p *memoryProvider) ListRecords(ctx context.Context, zone dnsname.Name) ([]v1alpha1.DNSRecord, error) {
+	var list []v1alpha1.DNSRecord
+	for _, rrset := range p.rrsets {
+		list = append(list, rrset)
+	}
+	return list, nil
+}
+
+func TestRegistryName(t *testing.T) {
+	require := require.New(t)
+
+	tests := []struct {
+		name     string
+		rrset    v1alpha1.DNSRecordSetData
+		registry string
+	}{
+		{"example.com.", v1alpha1.DNSRecordSetData{A: []v1alpha1.Ipv4String{"1.1.1.1"}}, "_dns-operator-a.example.com."},
+		{"WWW.example.com", v1alpha1.DNSRecordSetData{CNAME: []dnsname.Name{mustName("example.com")}}, "_dns-operator-cname.WWW.example.com."},
+		{"*.example.com", v1alpha1.DNSRecordSetData{TXT: []string{"text"}}, "_dns-operator-wildcard-txt.example.com."},
+	}
+
+	for _, test := range tests {
+		var record v1alpha1.DNSRecord
+		record.Namespace = "default"
+		record.Name = "record"
+		record.Spec.Name = mustName(test.name)
+		record.Spec.RRSet = test.rrset
+
+		registry, err := registryRecord("cluster", &record)
+		require.Nil(err, test.name)
+		require.Equal(test.registry, registry.Spec.Name.String(), test.name)
+		require.Equal([]string{"heritage=dns-operator,cluster=cluster,resource=default/record"}, registry.Spec.RRSet.TXT, test.name)
+		require.True(isRegistryName(&registry.Spec.Name), test.name)
+		require.False(isRegistryName(&record.Spec.Name), test.name)
+	}
+}
+
+func TestOwnershipProvider(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	zone := mustName("example.com.")
+
+	backend := newMemoryProvider()
+	provider := &ownershipProvider{Provider: backend, clusterID: "cluster"}
+
+	newRecord := func(name string, ip v1alpha1.Ipv4String) v1alpha1.DNSRecord {
+		var record v1alpha1.DNSRecord
+		record.Namespace = "default"
+		record.Name = name
+		record.Spec.Name = mustName("www.example.com.")
+		record.Spec.RRSet.A = []v1alpha1.Ipv4String{ip}
+		return record
+	}
+	first := newRecord("first", "1.1.1.1")
+	second := newRecord("second", "2.2.2.2")
+
+	// Free RRsets can be claimed, and the ownership record is hidden from the listing
+	require.Nil(provider.UpdateRecord(ctx, zone, first))
+	require.Len(backend.rrsets, 2)
+	list, err := provider.ListRecords(ctx, zone)
+	require.Nil(err)
+	require.Len(list, 1)
+
+	// The owner can update its RRset, while others cannot touch it
+	first.Spec.RRSet.A = []v1alpha1.Ipv4String{"3.3.3.3"}
+	require.Nil(provider.UpdateRecord(ctx, zone, first))
+	err = provider.UpdateRecord(ctx, zone, second)
+	require.True(errors.Is(err, types.ErrNotOwned))
+	err = provider.DeleteRecord(ctx, zone, second)
+	require.True(errors.Is(err, types.ErrNotOwned))
+	actual, err := provider.GetRecord(ctx, zone, first)
+	require.Nil(err)
+	require.Equal([]v1alpha1.Ipv4String{"3.3.3.3"}, actual.Spec.RRSet.A)
+
+	// Adoption takes over the ownership
+	adopt := v1alpha1.AdoptPolicy
+	second.Spec.AdoptionPolicy = &adopt
+	require.Nil(provider.UpdateRecord(ctx, zone, second))
+	err = provider.UpdateRecord(ctx, zone, first)
+	require.True(errors.Is(err, types.ErrNotOwned))
+
+	// Deleting releases the ownership
+	require.Nil(provider.DeleteRecord(ctx, zone, second))
+	require.Len(backend.rrsets, 0)
+
+	// RRsets created outside of the operator are never touched without adoption
+	unowned := newRecord("unowned", "4.4.4.4")
+	require.Nil(backend.UpdateRecord(ctx, zone, unowned))
+	err = provider.UpdateRecord(ctx, zone, first)
+	require.True(errors.Is(err, types.ErrNotOwned))
+	err = provider.DeleteRecord(ctx, zone, first)
+	require.True(errors.Is(err, types.ErrNotOwned))
+	require.Len(backend.rrsets, 1)
+
+	// RRsets published before the introduction of the ownership records are adopted by the resource which published them,
+	// as long as they were not changed in the meantime
+	legacy := newRecord("legacy", "4.4.4.4")
+	legacy.Status.FQDN = &legacy.Spec.Name
+	changed := legacy.DeepCopy()
+	changed.Spec.RRSet.A = []v1alpha1.Ipv4String{"5.5.5.5"}
+	err = provider.DeleteRecord(ctx, zone, *changed)
+	require.True(errors.Is(err, types.ErrNotOwned))
+	require.Nil(provider.DeleteRecord(ctx, zone, legacy))
+	require.Len(backend.rrsets, 0)
+	require.Nil(backend.UpdateRecord(ctx, zone, legacy))
+	require.Nil(provider.UpdateRecord(ctx, zone, legacy))
+	require.Len(backend.rrsets, 2)
+
+	// Providers which cannot read back records are not checked
+	unchecked := &ownershipProvider{Provider: NewDummy(ctrl.Log, nil), clusterID: "cluster"}
+	require.Nil(unchecked.UpdateRecord(ctx, zone, first))
+}
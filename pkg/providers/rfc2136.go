@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -30,22 +31,18 @@ var supportedAlgorithms = map[string]string{
 // for updates to a backend server.
 type RFC2136 struct {
 	log        logr.Logger
-	client     *dns.Client
 	zones      []dnsname.Name
 	nameserver string
 	useTsig    bool
+	tsigSecret map[string]string
 	keyName    string
 	algorithm  string
 }
 
 // NewRFC2136 creates a new DNS provider which uses Dynamic DNS for updates.
 func NewRFC2136(log logr.Logger, zones []dnsname.Name, nameserver string) *RFC2136 {
-	client := new(dns.Client)
-	client.SingleInflight = true
-
 	return &RFC2136{
 		log:        log.WithName("providers").WithName("RFC2136"),
-		client:     client,
 		zones:      zones,
 		nameserver: nameserver,
 	}
@@ -53,8 +50,8 @@ func NewRFC2136(log logr.Logger, zones []dnsname.Name, nameserver string) *RFC21
 
 // WithTsig configures transaction signatures for DNS updates.
 func (provider *RFC2136) WithTsig(secret, keyName, algorithm string) *RFC2136 {
-	provider.client.TsigSecret = make(map[string]string)
-	provider.client.TsigSecret[keyName] = secret
+	provider.tsigSecret = make(map[string]string)
+	provider.tsigSecret[keyName] = secret
 	provider.keyName = keyName
 	provider.algorithm = algorithm
 	provider.useTsig = true
@@ -67,7 +64,7 @@ func (provider *RFC2136) Zones() []dnsname.Name {
 }
 
 // UpdateRecord updates a record set on the backend server.
-func (provider *RFC2136) UpdateRecord(zone dnsname.Name, resource v1alpha1.DNSRecord) error {
+func (provider *RFC2136) UpdateRecord(ctx context.Context, zone dnsname.Name, resource v1alpha1.DNSRecord) error {
 
 	rrset, err := toRRSet(&resource)
 	if err != nil {
@@ -84,7 +81,7 @@ func (provider *RFC2136) UpdateRecord(zone dnsname.Name, resource v1alpha1.DNSRe
 	}
 
 	// Send the message
-	res, _, err := provider.client.Exchange(msg, provider.nameserver)
+	res, err := provider.exchange(ctx, "udp", msg)
 	if err != nil {
 		return fmt.Errorf("DNS update failed: %s", err)
 	}
@@ -100,7 +97,7 @@ func (provider *RFC2136) UpdateRecord(zone dnsname.Name, resource v1alpha1.DNSRe
 }
 
 // DeleteRecord deletes a record from the backend server.
-func (provider *RFC2136) DeleteRecord(zone dnsname.Name, resource v1alpha1.DNSRecord) error {
+func (provider *RFC2136) DeleteRecord(ctx context.Context, zone dnsname.Name, resource v1alpha1.DNSRecord) error {
 
 	rrset, err := toRRSet(&resource)
 	if err != nil {
@@ -116,7 +113,7 @@ func (provider *RFC2136) DeleteRecord(zone dnsname.Name, resource v1alpha1.DNSRe
 	}
 
 	// Send the message
-	res, _, err := provider.client.Exchange(msg, provider.nameserver)
+	res, err := provider.exchange(ctx, "udp", msg)
 	if err != nil {
 		return fmt.Errorf("DNS delete failed: %s", err)
 	}
@@ -131,10 +128,231 @@ func (provider *RFC2136) DeleteRecord(zone dnsname.Name, resource v1alpha1.DNSRe
 	return nil
 }
 
+// GetRecord queries the nameserver for the RRset with the same name and type of the given resource.
+//
+// Nameservers synthesize answers from wildcards, which are indistinguishable from the RRsets actually published
+// with the name of the resource. In signed zones, the signature of a synthesized answer proves its origin.
+// Otherwise, the answer is compared with the RRsets of the wildcards which could have synthesized it,
+// and only when it matches one of them the zone is transferred (AXFR) to tell the two cases apart.
+// If the transfer is refused, the answer is considered synthesized.
+func (provider *RFC2136) GetRecord(ctx context.Context, zone dnsname.Name, resource v1alpha1.DNSRecord) (*v1alpha1.DNSRecord, error) {
+
+	rtype, ok := dns.StringToType[resource.RType()]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported DNS record")
+	}
+	fqdn := resource.Spec.Name.ToFQDN().String()
+
+	answer, signatures, err := provider.query(ctx, fqdn, rtype)
+	if err != nil || answer == nil {
+		return nil, err
+	}
+
+	// The signature of a synthesized answer does not count the labels replaced by the wildcard
+	if len(signatures) > 0 {
+		for _, signature := range signatures {
+			if int(signature.Labels) < dns.CountLabel(fqdn) {
+				return nil, nil
+			}
+		}
+		return answer, nil
+	}
+
+	// Look for a wildcard in one of the ancestors within the zone with the same RRset of the answer
+	labels := dns.SplitDomainName(fqdn)
+	synthesized := false
+	for i := 1; i < len(labels) && !synthesized; i++ {
+		ancestor := dns.Fqdn(strings.Join(labels[i:], "."))
+		if !dns.IsSubDomain(zone.ToFQDN().String(), ancestor) {
+			break
+		}
+		wildcard := "*." + ancestor
+		if strings.EqualFold(wildcard, fqdn) {
+			continue
+		}
+		candidate, _, err := provider.query(ctx, wildcard, rtype)
+		if err != nil {
+			return nil, err
+		}
+		if candidate != nil {
+			candidate.Spec.Name = answer.Spec.Name
+			synthesized = answer.RRSetEquals(candidate)
+		}
+	}
+	if !synthesized {
+		return answer, nil
+	}
+
+	// Keep only the records of the requested RRset
+	set := newRecordSet()
+	err = provider.transfer(ctx, zone, func(rr dns.RR) error {
+		if rr.Header().Rrtype == rtype && strings.EqualFold(rr.Header().Name, fqdn) {
+			return addRR(set, rr)
+		}
+		return nil
+	})
+	if err != nil {
+		provider.log.V(1).Info("Cannot tell a record from a wildcard, considering it synthesized", "name", fqdn, "type", resource.RType(), "reason", err.Error())
+		return nil, nil
+	}
+
+	return set.first(), nil
+}
+
+// query asks the nameserver for the RRset with the given name and type, and returns it with its signatures, if any.
+// Returns nil if the RRset does not exist.
+func (provider *RFC2136) query(ctx context.Context, fqdn string, rtype uint16) (*v1alpha1.DNSRecord, []*dns.RRSIG, error) {
+
+	// Ask for the signatures, and fall back to TCP for the answers which do not fit in a UDP message
+	msg := new(dns.Msg)
+	msg.SetQuestion(fqdn, rtype)
+	msg.SetEdns0(4096, true)
+	res, err := provider.exchange(ctx, "udp", msg)
+	if err == nil && res.Truncated {
+		res, err = provider.exchange(ctx, "tcp", msg)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("DNS query failed: %s", err)
+	}
+	switch res.Rcode {
+	case dns.RcodeSuccess:
+	case dns.RcodeNameError:
+		return nil, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("DNS query failed. Server replied: %s", dns.RcodeToString[res.Rcode])
+	}
+
+	// The answer can contain other RRsets, e.g. when the name is an alias
+	set := newRecordSet()
+	var signatures []*dns.RRSIG
+	for _, rr := range res.Answer {
+		if !strings.EqualFold(rr.Header().Name, fqdn) {
+			continue
+		}
+		if signature, ok := rr.(*dns.RRSIG); ok && signature.TypeCovered == rtype {
+			signatures = append(signatures, signature)
+		} else if rr.Header().Rrtype == rtype {
+			if err := addRR(set, rr); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return set.first(), signatures, nil
+}
+
+// ListRecords lists all the records of a zone using a zone transfer (AXFR).
+func (provider *RFC2136) ListRecords(ctx context.Context, zone dnsname.Name) ([]v1alpha1.DNSRecord, error) {
+	set := newRecordSet()
+	if err := provider.transfer(ctx, zone, func(rr dns.RR) error { return addRR(set, rr) }); err != nil {
+		return nil, err
+	}
+	return set.list(), nil
+}
+
+// transfer performs a zone transfer (AXFR) of the given zone, calling `fn` for each of the records received.
+// The nameserver must allow transfers to the operator.
+func (provider *RFC2136) transfer(ctx context.Context, zone dnsname.Name, fn func(dns.RR) error) error {
+
+	// Prepare the DNS message
+	msg := new(dns.Msg)
+	msg.SetAxfr(zone.ToFQDN().String())
+	if provider.useTsig {
+		msg.SetTsig(provider.keyName, provider.algorithm, 300, time.Now().Unix())
+	}
+
+	conn, release, err := provider.dial(ctx, "tcp")
+	if err != nil {
+		return fmt.Errorf("DNS zone transfer failed: %s", err)
+	}
+	defer release()
+	transfer := &dns.Transfer{Conn: conn, TsigSecret: provider.tsigSecret}
+	if deadline, ok := ctx.Deadline(); ok {
+		transfer.ReadTimeout = time.Until(deadline)
+		transfer.WriteTimeout = time.Until(deadline)
+	}
+
+	// Perform the transfer
+	envelopes, err := transfer.In(msg, provider.nameserver)
+	if err != nil {
+		return fmt.Errorf("DNS zone transfer failed: %s", err)
+	}
+	for envelope := range envelopes {
+		err = envelope.Error
+		for i := 0; err == nil && i < len(envelope.RR); i++ {
+			err = fn(envelope.RR[i])
+		}
+		if err != nil {
+			// Stop the transfer, and let it terminate without leaking the goroutine receiving the records
+			release()
+			for range envelopes {
+			}
+			return fmt.Errorf("DNS zone transfer failed: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// exchange sends a message to the nameserver with the given network, honoring the deadline and the cancellation of the given context.
+func (provider *RFC2136) exchange(ctx context.Context, network string, msg *dns.Msg) (*dns.Msg, error) {
+
+	// A new client is created for every exchange, since the timeout depends on the deadline of the context
+	client := new(dns.Client)
+	client.TsigSecret = provider.tsigSecret
+	if deadline, ok := ctx.Deadline(); ok {
+		client.Timeout = time.Until(deadline)
+	}
+
+	conn, release, err := provider.dial(ctx, network)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	res, _, err := client.ExchangeWithConn(msg, conn)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+// dial opens a connection to the nameserver bound to the given context: the connection is closed as soon as
+// the context is done, so that no message is sent or received after the operation has been reported as failed.
+// The returned function releases the connection, and can be called more than once.
+func (provider *RFC2136) dial(ctx context.Context, network string) (*dns.Conn, func(), error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, provider.nameserver)
+	if err != nil {
+		return nil, nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			close(done)
+			_ = conn.Close()
+		})
+	}
+	return &dns.Conn{Conn: conn}, release, nil
+}
+
 func toRRSet(resource *v1alpha1.DNSRecord) ([]dns.RR, error) {
 
 	// Prepare a common header
-	var ttl uint32 = 3600
+	ttl := v1alpha1.DefaultTTLSeconds
 	if resource.Spec.TTLSeconds != nil {
 		ttl = *resource.Spec.TTLSeconds
 	}
@@ -215,6 +433,39 @@ func toRRSet(resource *v1alpha1.DNSRecord) ([]dns.RR, error) {
 			rrset = append(rrset, rr)
 		}
 
+	// SRV record
+	case "SRV":
+		for _, value := range spec.SRV {
+			rr := new(dns.SRV)
+			rr.Hdr = header
+			rr.Hdr.Rrtype = dns.TypeSRV
+			rr.Priority = value.Priority
+			rr.Weight = value.Weight
+			rr.Port = value.Port
+			if err := name(&value.Target, &rr.Target); err != nil {
+				return nil, err
+			}
+
+			rrset = append(rrset, rr)
+		}
+
+	// CAA record
+	case "CAA":
+		for _, value := range spec.CAA {
+			if err := value.Validate(); err != nil {
+				return nil, err
+			}
+
+			rr := new(dns.CAA)
+			rr.Hdr = header
+			rr.Hdr.Rrtype = dns.TypeCAA
+			rr.Flag = value.Flags
+			rr.Tag = string(value.Tag)
+			rr.Value = value.Value
+
+			rrset = append(rrset, rr)
+		}
+
 	default:
 		return nil, fmt.Errorf("Unsupported DNS record")
 
@@ -223,6 +474,60 @@ func toRRSet(resource *v1alpha1.DNSRecord) ([]dns.RR, error) {
 	return rrset, nil
 }
 
+// addRR adds a record read from the server to the given set, performing the opposite conversion of toRRSet.
+// Records of unsupported types are ignored.
+func addRR(set *recordSet, rr dns.RR) error {
+	owner, err := dnsname.NewName(rr.Header().Name)
+	if err != nil {
+		return err
+	}
+	ttl := rr.Header().Ttl
+
+	switch rr := rr.(type) {
+	case *dns.A:
+		data := set.rrset(*owner, "A", &ttl)
+		data.A = append(data.A, v1alpha1.Ipv4String(rr.A.String()))
+
+	case *dns.AAAA:
+		data := set.rrset(*owner, "AAAA", &ttl)
+		data.AAAA = append(data.AAAA, v1alpha1.Ipv6String(rr.AAAA.String()))
+
+	case *dns.MX:
+		host, err := dnsname.NewName(rr.Mx)
+		if err != nil {
+			return err
+		}
+		data := set.rrset(*owner, "MX", &ttl)
+		data.MX = append(data.MX, v1alpha1.MXRData{Preference: rr.Preference, Host: *host})
+
+	case *dns.CNAME:
+		target, err := dnsname.NewName(rr.Target)
+		if err != nil {
+			return err
+		}
+		data := set.rrset(*owner, "CNAME", &ttl)
+		data.CNAME = append(data.CNAME, *target)
+
+	case *dns.TXT:
+		data := set.rrset(*owner, "TXT", &ttl)
+		data.TXT = append(data.TXT, strings.Join(rr.Txt, ""))
+
+	case *dns.SRV:
+		target, err := dnsname.NewName(rr.Target)
+		if err != nil {
+			return err
+		}
+		data := set.rrset(*owner, "SRV", &ttl)
+		data.SRV = append(data.SRV, v1alpha1.SRVRData{Priority: rr.Priority, Weight: rr.Weight, Port: rr.Port, Target: *target})
+
+	case *dns.CAA:
+		data := set.rrset(*owner, "CAA", &ttl)
+		data.CAA = append(data.CAA, v1alpha1.CAARData{Flags: rr.Flag, Tag: v1alpha1.CAATag(rr.Tag), Value: rr.Value})
+	}
+
+	return nil
+}
+
 func a(source *v1alpha1.Ipv4String, target *net.IP) error {
 	ip := net.ParseIP(string(*source))
 	if ip == nil {
@@ -281,7 +586,7 @@ func txt(source *string, target *[]string) error {
 	return nil
 }
 
-func extractTSIGKey(resource *v1alpha1.DNSProvider, k8sClient client.Client) (string, string, string, error) {
+func extractTSIGKey(ctx context.Context, resource *v1alpha1.DNSProvider, k8sClient client.Client) (string, string, string, error) {
 
 	// Extract the required parameters
 	secretRef := resource.Spec.RFC2136.TSIGSecretRef
@@ -308,7 +613,7 @@ func extractTSIGKey(resource *v1alpha1.DNSProvider, k8sClient client.Client) (st
 		secretNamespace = &resource.Namespace
 	}
 	var secret corev1.Secret
-	if err := k8sClient.Get(context.Background(), k8stypes.NamespacedName{Name: secretName, Namespace: *secretNamespace}, &secret); err != nil {
+	if err := k8sClient.Get(ctx, k8stypes.NamespacedName{Name: secretName, Namespace: *secretNamespace}, &secret); err != nil {
 		return "", "", "", err
 	}
 
@@ -323,10 +628,10 @@ func extractTSIGKey(resource *v1alpha1.DNSProvider, k8sClient client.Client) (st
 }
 
 func init() {
-	RegisterProviderConstructor("rfc2136", func(ctx *types.ControllerContext, resource *v1alpha1.DNSProvider) (types.Provider, error) {
-		provider := NewRFC2136(ctx.Log, resource.Spec.Zones, resource.Spec.RFC2136.Nameserver)
+	RegisterProviderConstructor("rfc2136", func(ctx context.Context, controllerCtx *types.ControllerContext, resource *v1alpha1.DNSProvider) (types.Provider, error) {
+		provider := NewRFC2136(controllerCtx.Log, resource.Spec.Zones, resource.Spec.RFC2136.Nameserver)
 		if resource.Spec.RFC2136.TSIGSecretRef != nil {
-			keyName, secret, algorithm, err := extractTSIGKey(resource, ctx.Client)
+			keyName, secret, algorithm, err := extractTSIGKey(ctx, resource, controllerCtx.Client)
 			if err != nil {
 				return nil, err
 			}
@@ -0,0 +1,252 @@
+package providers
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+)
+
+// testNameserver is an authoritative nameserver for the zone `example.com`, answering queries over UDP and TCP.
+// Names without records are answered synthesizing the records of the wildcard of their closest encloser, if any,
+// and answers are signed when `signed` is set (without actual signatures, only the number of labels).
+type testNameserver struct {
+	zone          []dns.RR
+	signed        bool
+	allowTransfer bool
+	transfers     int32
+	servers       []*dns.Server
+	addr          string
+}
+
+func startNameserver(t *testing.T, records []string, signed bool, allowTransfer bool) *testNameserver {
+	ns := &testNameserver{signed: signed, allowTransfer: allowTransfer}
+	for _, s := range append([]string{"example.com. 3600 IN SOA ns.example.com. admin.example.com. 1 3600 600 86400 3600"}, records...) {
+		rr, err := dns.NewRR(s)
+		require.Nil(t, err)
+		ns.zone = append(ns.zone, rr)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	packetConn, err := net.ListenPacket("udp", listener.Addr().String())
+	require.Nil(t, err)
+	ns.addr = listener.Addr().String()
+
+	for _, server := range []*dns.Server{{Listener: listener, Handler: ns}, {PacketConn: packetConn, Handler: ns}} {
+		started := make(chan struct{})
+		server.NotifyStartedFunc = func() { close(started) }
+		go func(server *dns.Server) { _ = server.ActivateAndServe() }(server)
+		<-started
+		ns.servers = append(ns.servers, server)
+	}
+	return ns
+}
+
+func (ns *testNameserver) shutdown() {
+	for _, server := range ns.servers {
+		_ = server.Shutdown()
+	}
+}
+
+// lookup returns the records with the given name and type, or nil if the name does not exist.
+// Empty non-terminals exist, but have no records.
+func (ns *testNameserver) lookup(name string, rtype uint16) []dns.RR {
+	var res []dns.RR
+	exists := false
+	for _, rr := range ns.zone {
+		exists = exists || dns.IsSubDomain(name, rr.Header().Name)
+		if strings.EqualFold(rr.Header().Name, name) && rr.Header().Rrtype == rtype {
+			res = append(res, dns.Copy(rr))
+		}
+	}
+	if !exists {
+		return nil
+	}
+	if res == nil {
+		res = []dns.RR{}
+	}
+	return res
+}
+
+func (ns *testNameserver) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	res := new(dns.Msg)
+	res.SetReply(req)
+	res.Authoritative = true
+	q := req.Question[0]
+
+	if q.Qtype == dns.TypeAXFR {
+		atomic.AddInt32(&ns.transfers, 1)
+		if ns.allowTransfer {
+			res.Answer = append(append(res.Answer, ns.zone...), ns.zone[0])
+		} else {
+			res.Rcode = dns.RcodeRefused
+		}
+		_ = w.WriteMsg(res)
+		return
+	}
+
+	// Look for the name, or for the wildcard of its closest encloser
+	answer := ns.lookup(q.Name, q.Qtype)
+	labels := dns.CountLabel(q.Name)
+	for i, end := dns.NextLabel(q.Name, 0); answer == nil && !end; i, end = dns.NextLabel(q.Name, i) {
+		if ns.lookup(q.Name[i:], dns.TypeANY) == nil {
+			continue
+		}
+		if answer = ns.lookup("*."+q.Name[i:], q.Qtype); answer != nil {
+			labels = dns.CountLabel(q.Name[i:])
+			for _, rr := range answer {
+				rr.Header().Name = q.Name
+			}
+		}
+		break
+	}
+	if answer == nil {
+		res.Rcode = dns.RcodeNameError
+	}
+	res.Answer = answer
+	if ns.signed && len(answer) > 0 {
+		res.Answer = append(res.Answer, &dns.RRSIG{
+			Hdr:         dns.RR_Header{Name: q.Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: answer[0].Header().Ttl},
+			TypeCovered: q.Qtype,
+			Algorithm:   dns.ECDSAP256SHA256,
+			Labels:      uint8(labels),
+			SignerName:  "example.com.",
+		})
+	}
+	_ = w.WriteMsg(res)
+}
+
+func TestRFC2136GetRecordIgnoresWildcardSynthesis(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	ns := startNameserver(t, []string{"*.example.com. 3600 IN A 1.1.1.1"}, false, true)
+	defer ns.shutdown()
+
+	zone := mustName("example.com")
+	provider := NewRFC2136(ctrl.Log, nil, ns.addr)
+
+	// A name covered by the wildcard has no RRset of its own
+	api := v1alpha1.DNSRecord{Spec: v1alpha1.DNSRecordSpec{Name: mustName("api.example.com"), RRSet: v1alpha1.DNSRecordSetData{
+		A: []v1alpha1.Ipv4String{"2.2.2.2"},
+	}}}
+	published, err := provider.GetRecord(ctx, zone, api)
+	require.Nil(err)
+	require.Nil(published)
+
+	// The wildcard itself is read back
+	wildcard := v1alpha1.DNSRecord{Spec: v1alpha1.DNSRecordSpec{Name: mustName("*.example.com"), RRSet: v1alpha1.DNSRecordSetData{
+		A: []v1alpha1.Ipv4String{"1.1.1.1"},
+	}}}
+	published, err = provider.GetRecord(ctx, zone, wildcard)
+	require.Nil(err)
+	require.NotNil(published)
+	require.Equal([]v1alpha1.Ipv4String{"1.1.1.1"}, published.Spec.RRSet.A)
+}
+
+func TestRFC2136GetRecordWithoutTransfers(t *testing.T) {
+	zone := mustName("example.com")
+	record := func(name string) v1alpha1.DNSRecord {
+		return v1alpha1.DNSRecord{Spec: v1alpha1.DNSRecordSpec{Name: mustName(name), RRSet: v1alpha1.DNSRecordSetData{
+			A: []v1alpha1.Ipv4String{"2.2.2.2"},
+		}}}
+	}
+	records := []string{
+		"*.example.com. 3600 IN A 1.1.1.1",
+		"www.example.com. 3600 IN A 2.2.2.2",
+		"same.example.com. 3600 IN A 1.1.1.1",
+		"*.sub.example.com. 3600 IN TXT \"text\"",
+	}
+
+	table := []struct {
+		name      string
+		signed    bool
+		expected  []v1alpha1.Ipv4String
+		transfers int32
+	}{
+		// Records different from the wildcards, and missing names, need no transfer
+		{"www.example.com", false, []v1alpha1.Ipv4String{"2.2.2.2"}, 0},
+		{"missing.sub.example.com", false, nil, 0},
+		{"missing.example.org", false, nil, 0},
+
+		// Records equal to a wildcard need a transfer, and are considered synthesized if it is refused
+		{"api.example.com", false, nil, 1},
+		{"same.example.com", false, nil, 1},
+
+		// Signatures tell synthesized answers apart
+		{"api.example.com", true, nil, 0},
+		{"same.example.com", true, []v1alpha1.Ipv4String{"1.1.1.1"}, 0},
+		{"*.example.com", true, []v1alpha1.Ipv4String{"1.1.1.1"}, 0},
+	}
+
+	for _, entry := range table {
+		ns := startNameserver(t, records, entry.signed, false)
+		provider := NewRFC2136(ctrl.Log, nil, ns.addr)
+		published, err := provider.GetRecord(context.Background(), zone, record(entry.name))
+		ns.shutdown()
+
+		require.Nil(t, err, "Name: %s, Signed: %v", entry.name, entry.signed)
+		if entry.expected == nil {
+			require.Nil(t, published, "Name: %s, Signed: %v", entry.name, entry.signed)
+		} else {
+			require.NotNil(t, published, "Name: %s, Signed: %v", entry.name, entry.signed)
+			require.Equal(t, entry.expected, published.Spec.RRSet.A, "Name: %s, Signed: %v", entry.name, entry.signed)
+		}
+		require.Equal(t, entry.transfers, ns.transfers, "Name: %s, Signed: %v", entry.name, entry.signed)
+	}
+}
+
+func TestRFC2136Cancellation(t *testing.T) {
+	require := require.New(t)
+
+	// A nameserver which never answers, neither to updates nor to transfers
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(err)
+	defer udp.Close()
+	tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+	require.Nil(err)
+	defer tcp.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := tcp.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	zone := mustName("example.com")
+	provider := NewRFC2136(ctrl.Log, nil, udp.LocalAddr().String())
+	record := v1alpha1.DNSRecord{Spec: v1alpha1.DNSRecordSpec{Name: mustName("www.example.com"), RRSet: v1alpha1.DNSRecordSetData{
+		A: []v1alpha1.Ipv4String{"1.1.1.1"},
+	}}}
+
+	// Operations return as soon as the context is canceled, without waiting for the timeouts of the DNS client
+	for _, op := range []func(context.Context) error{
+		func(ctx context.Context) error { return provider.UpdateRecord(ctx, zone, record) },
+		func(ctx context.Context) error { _, err := provider.ListRecords(ctx, zone); return err },
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+		start := time.Now()
+		err := op(ctx)
+		require.NotNil(err)
+		require.True(time.Since(start) < time.Second, "Operation took %s", time.Since(start))
+		cancel()
+	}
+
+	// The connection of the zone transfer is closed, instead of being left to the timeouts of the DNS client
+	conn := <-accepted
+	defer conn.Close()
+	require.Nil(conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = ioutil.ReadAll(conn)
+	require.Nil(err)
+}
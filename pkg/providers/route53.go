@@ -0,0 +1,527 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	"github.com/95ulisse/dns-operator/pkg/metrics"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+const (
+	route53DefaultEndpoint = "https://route53.amazonaws.com"
+	route53DefaultRegion   = "us-east-1"
+	route53APIVersion      = "2013-04-01"
+	route53XMLNamespace    = "https://route53.amazonaws.com/doc/2013-04-01/"
+)
+
+// Route53 DNS provider.
+// It talks directly to the REST API of Route53, so it can be pointed to any service compatible with it.
+type Route53 struct {
+	log              logr.Logger
+	zones            []dnsname.Name
+	client           *http.Client
+	endpoint         string
+	region           string
+	accessKeyID      string
+	secretAccessKey  string
+	privateZone      *bool
+	zonesIDCache     map[string]string
+	zonesIDCacheLock sync.RWMutex
+}
+
+// NewRoute53 creates a new instance of the Route53 provider.
+// When `privateZone` is set, only the private or the public hosted zones are used.
+func NewRoute53(log logr.Logger, zones []dnsname.Name, client *http.Client, endpoint, region, accessKeyID, secretAccessKey string, privateZone *bool) *Route53 {
+	return &Route53{
+		log:             log.WithName("providers").WithName("Route53"),
+		zones:           zones,
+		client:          client,
+		endpoint:        strings.TrimSuffix(endpoint, "/"),
+		region:          region,
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		privateZone:     privateZone,
+		zonesIDCache:    make(map[string]string),
+	}
+}
+
+// Zones returns a slice containing the DNS zones managed by this provider.
+func (r53 *Route53) Zones() []dnsname.Name {
+	return r53.zones
+}
+
+// UpdateRecord replaces the whole RRset on Route53 with the given one.
+func (r53 *Route53) UpdateRecord(ctx context.Context, zone dnsname.Name, resource v1alpha1.DNSRecord) error {
+	zoneID, err := r53.zoneIDFromName(ctx, zone)
+	if err != nil {
+		return err
+	}
+	rrset, err := toR53RecordSet(&resource)
+	if err != nil {
+		return err
+	}
+
+	r53.log.V(1).Info("Upserting DNS record", "name", rrset.Name, "type", rrset.Type)
+	return r53.changeRRSet(ctx, zoneID, "UPSERT", rrset)
+}
+
+// DeleteRecord deletes the given RRset from Route53.
+func (r53 *Route53) DeleteRecord(ctx context.Context, zone dnsname.Name, resource v1alpha1.DNSRecord) error {
+	zoneID, err := r53.zoneIDFromName(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	// Route53 deletes an RRset only if its contents match exactly the published ones,
+	// so we delete the RRset as it is currently published, which might differ from the resource.
+	rrset, err := r53.getRRSet(ctx, zoneID, &resource.Spec.Name, resource.RType())
+	if err != nil || rrset == nil {
+		return err
+	}
+
+	r53.log.V(1).Info("Deleting DNS record", "name", rrset.Name, "type", rrset.Type)
+	return r53.changeRRSet(ctx, zoneID, "DELETE", rrset)
+}
+
+// GetRecord returns the RRset registered on Route53 with the same name and type of the given resource.
+func (r53 *Route53) GetRecord(ctx context.Context, zone dnsname.Name, resource v1alpha1.DNSRecord) (*v1alpha1.DNSRecord, error) {
+	zoneID, err := r53.zoneIDFromName(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	rrset, err := r53.getRRSet(ctx, zoneID, &resource.Spec.Name, resource.RType())
+	if err != nil || rrset == nil {
+		return nil, err
+	}
+
+	set := newRecordSet()
+	if err := addR53RecordSet(set, rrset); err != nil {
+		return nil, err
+	}
+	return set.first(), nil
+}
+
+// ListRecords returns all the RRsets registered on Route53 for the given zone.
+func (r53 *Route53) ListRecords(ctx context.Context, zone dnsname.Name) ([]v1alpha1.DNSRecord, error) {
+	zoneID, err := r53.zoneIDFromName(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	set := newRecordSet()
+	query := url.Values{}
+	for {
+		var res r53ListResourceRecordSetsResponse
+		if err := r53.do(ctx, http.MethodGet, "/hostedzone/"+zoneID+"/rrset", query, nil, &res); err != nil {
+			return nil, err
+		}
+		for i := range res.ResourceRecordSets {
+			if err := addR53RecordSet(set, &res.ResourceRecordSets[i]); err != nil {
+				return nil, err
+			}
+		}
+
+		// Follow the pagination
+		if !res.IsTruncated {
+			break
+		}
+		query = url.Values{}
+		query.Set("name", res.NextRecordName)
+		query.Set("type", res.NextRecordType)
+		if res.NextRecordIdentifier != "" {
+			query.Set("identifier", res.NextRecordIdentifier)
+		}
+	}
+
+	return set.list(), nil
+}
+
+// getRRSet returns the RRset with the given name and type published on Route53, or nil if it does not exist.
+// RRsets with routing policies or aliases are not managed by the operator, so they are reported as owned by someone else.
+func (r53 *Route53) getRRSet(ctx context.Context, zoneID string, name *dnsname.Name, rtype string) (*r53ResourceRecordSet, error) {
+
+	// Route53 lists the RRsets in order starting from the given name and type.
+	// More than one RRset can have the same name and type when they use a routing policy,
+	// so keep reading until a different name or type is found.
+	query := url.Values{}
+	query.Set("name", name.ToFQDN().String())
+	query.Set("type", rtype)
+	query.Set("maxitems", "100")
+	var found *r53ResourceRecordSet
+	for {
+		var res r53ListResourceRecordSetsResponse
+		if err := r53.do(ctx, http.MethodGet, "/hostedzone/"+zoneID+"/rrset", query, nil, &res); err != nil {
+			return nil, err
+		}
+		for i := range res.ResourceRecordSets {
+			rrset := &res.ResourceRecordSets[i]
+			owner, err := dnsname.NewName(r53Unescape(rrset.Name))
+			if err != nil {
+				return nil, err
+			}
+			if !owner.Equal(name.ToFQDN()) || rrset.Type != rtype {
+				return found, nil
+			}
+			if !rrset.isSimple() {
+				return nil, fmt.Errorf("%w: %s %s uses a routing policy or an alias, which are not managed by dns-operator", types.ErrNotOwned, rtype, name.ToFQDN().String())
+			}
+			found = rrset
+		}
+
+		// Follow the pagination
+		if !res.IsTruncated {
+			return found, nil
+		}
+		query.Set("name", res.NextRecordName)
+		query.Set("type", res.NextRecordType)
+		query.Del("identifier")
+		if res.NextRecordIdentifier != "" {
+			query.Set("identifier", res.NextRecordIdentifier)
+		}
+	}
+}
+
+// changeRRSet submits a change batch containing a single change of the whole RRset.
+func (r53 *Route53) changeRRSet(ctx context.Context, zoneID string, action string, rrset *r53ResourceRecordSet) error {
+	req := r53ChangeResourceRecordSetsRequest{
+		XMLNS: route53XMLNamespace,
+		Changes: []r53Change{
+			{Action: action, ResourceRecordSet: *rrset},
+		},
+	}
+	return r53.do(ctx, http.MethodPost, "/hostedzone/"+zoneID+"/rrset", nil, &req, nil)
+}
+
+func (r53 *Route53) zoneIDFromName(ctx context.Context, zone dnsname.Name) (string, error) {
+	name := strings.ToLower(zone.ToFQDN().String())
+
+	// First check if the zone is in the cache
+	id := func() string {
+		r53.zonesIDCacheLock.RLock()
+		defer r53.zonesIDCacheLock.RUnlock()
+		return r53.zonesIDCache[name]
+	}()
+	if id != "" {
+		return id, nil
+	}
+
+	// Hosted zones are listed in order starting from the given name,
+	// so all the zones with the same name come first
+	query := url.Values{}
+	query.Set("dnsname", name)
+	var res r53ListHostedZonesByNameResponse
+	if err := r53.do(ctx, http.MethodGet, "/hostedzonesbyname", query, nil, &res); err != nil {
+		r53.log.Error(err, "Could not resolve zone name", "zone", zone.String())
+		return "", err
+	}
+	for _, hostedZone := range res.HostedZones {
+		if strings.ToLower(hostedZone.Name) != name {
+			continue
+		}
+		if r53.privateZone != nil && *r53.privateZone != hostedZone.Config.PrivateZone {
+			continue
+		}
+		if id != "" {
+			return "", fmt.Errorf("Found multiple hosted zones named %s, use privateZone to choose between a public and a private one", name)
+		}
+		id = strings.TrimPrefix(hostedZone.ID, "/hostedzone/")
+	}
+	if id == "" {
+		return "", fmt.Errorf("Cannot find hosted zone %s", name)
+	}
+
+	// Store the id in the cache
+	r53.zonesIDCacheLock.Lock()
+	defer r53.zonesIDCacheLock.Unlock()
+	r53.zonesIDCache[name] = id
+
+	return id, nil
+}
+
+// do performs a signed request against the Route53 API, encoding `body` and decoding the response in `out` as XML.
+func (r53 *Route53) do(ctx context.Context, method, path string, query url.Values, body interface{}, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = xml.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = append([]byte(xml.Header), payload...)
+	}
+
+	u := r53.endpoint + "/" + route53APIVersion + path
+	if len(query) > 0 {
+		u += "?" + r53EncodeQuery(query)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	r53.sign(req, payload, time.Now())
+
+	res, err := r53.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	raw, err := ioutil.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return r53Error(res.StatusCode, raw)
+	}
+	if out != nil {
+		if err := xml.Unmarshal(raw, out); err != nil {
+			return fmt.Errorf("Cannot decode Route53 response: %s", err)
+		}
+	}
+	return nil
+}
+
+// sign adds to the request the headers needed to authenticate it with AWS Signature Version 4.
+func (r53 *Route53) sign(req *http.Request, payload []byte, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	payloadHash := sha256.Sum256(payload)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\n" + "x-amz-date:" + amzDate + "\n",
+		"host;x-amz-date",
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + r53.region + "/route53/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + r53.secretAccessKey)
+	for _, part := range []string{date, r53.region, "route53", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=host;x-amz-date, Signature=%s", r53.accessKeyID, scope, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// r53EncodeQuery encodes the query string in the canonical form required by the signature:
+// sorted by key, and with spaces encoded as `%20`.
+func r53EncodeQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, r53Escape(key)+"="+r53Escape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func r53Escape(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+// r53Error builds an error out of an error response of the Route53 API.
+func r53Error(status int, raw []byte) error {
+	var res struct {
+		Error struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		} `xml:"Error"`
+		Messages []string `xml:"Messages>Message"`
+	}
+	if err := xml.Unmarshal(raw, &res); err != nil {
+		return fmt.Errorf("Route53 request failed with status %d", status)
+	}
+
+	// Route53 signals rate limiting with a `400 Bad Request`, so it is not caught by metrics.RateLimitTransport
+	if res.Error.Code == "Throttling" || res.Error.Code == "PriorRequestNotComplete" {
+		metrics.ProviderRateLimits.WithLabelValues("route53").Inc()
+	}
+
+	switch {
+	case res.Error.Code != "":
+		return fmt.Errorf("Route53 request failed: %s: %s", res.Error.Code, res.Error.Message)
+	case len(res.Messages) > 0:
+		return fmt.Errorf("Route53 request failed: %s", strings.Join(res.Messages, "; "))
+	default:
+		return fmt.Errorf("Route53 request failed with status %d", status)
+	}
+}
+
+// Route53 API payloads
+
+type r53HostedZone struct {
+	ID     string `xml:"Id"`
+	Name   string `xml:"Name"`
+	Config struct {
+		PrivateZone bool `xml:"PrivateZone"`
+	} `xml:"Config"`
+}
+
+type r53ListHostedZonesByNameResponse struct {
+	HostedZones []r53HostedZone `xml:"HostedZones>HostedZone"`
+}
+
+type r53ResourceRecord struct {
+	Value string `xml:"Value"`
+}
+
+type r53AliasTarget struct {
+	HostedZoneID string `xml:"HostedZoneId"`
+	DNSName      string `xml:"DNSName"`
+}
+
+type r53ResourceRecordSet struct {
+	Name            string              `xml:"Name"`
+	Type            string              `xml:"Type"`
+	SetIdentifier   string              `xml:"SetIdentifier,omitempty"`
+	AliasTarget     *r53AliasTarget     `xml:"AliasTarget,omitempty"`
+	TTL             *uint32             `xml:"TTL,omitempty"`
+	ResourceRecords []r53ResourceRecord `xml:"ResourceRecords>ResourceRecord,omitempty"`
+}
+
+// isSimple tells whether the RRset uses simple routing, i.e., it is not an alias, nor it has a routing policy.
+func (rrset *r53ResourceRecordSet) isSimple() bool {
+	return rrset.SetIdentifier == "" && rrset.AliasTarget == nil
+}
+
+type r53ListResourceRecordSetsResponse struct {
+	ResourceRecordSets   []r53ResourceRecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+	IsTruncated          bool                   `xml:"IsTruncated"`
+	NextRecordName       string                 `xml:"NextRecordName"`
+	NextRecordType       string                 `xml:"NextRecordType"`
+	NextRecordIdentifier string                 `xml:"NextRecordIdentifier"`
+}
+
+type r53Change struct {
+	Action            string               `xml:"Action"`
+	ResourceRecordSet r53ResourceRecordSet `xml:"ResourceRecordSet"`
+}
+
+type r53ChangeResourceRecordSetsRequest struct {
+	XMLName xml.Name    `xml:"ChangeResourceRecordSetsRequest"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Changes []r53Change `xml:"ChangeBatch>Changes>Change"`
+}
+
+// toR53RecordSet converts a DNSRecord resource to a Route53 RRset.
+func toR53RecordSet(resource *v1alpha1.DNSRecord) (*r53ResourceRecordSet, error) {
+	rdata, err := toRData(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := v1alpha1.DefaultTTLSeconds
+	if resource.Spec.TTLSeconds != nil {
+		ttl = *resource.Spec.TTLSeconds
+	}
+	rrset := &r53ResourceRecordSet{
+		Name: strings.ToLower(resource.Spec.Name.ToFQDN().String()),
+		Type: resource.RType(),
+		TTL:  &ttl,
+	}
+	for _, value := range rdata {
+		rrset.ResourceRecords = append(rrset.ResourceRecords, r53ResourceRecord{Value: value})
+	}
+	return rrset, nil
+}
+
+// addR53RecordSet adds an RRset read from Route53 to the given set, performing the opposite conversion of toR53RecordSet.
+// RRsets of unsupported types, aliases and RRsets with routing policies are ignored.
+func addR53RecordSet(set *recordSet, rrset *r53ResourceRecordSet) error {
+	if !rrset.isSimple() {
+		return nil
+	}
+	var ttl uint32
+	if rrset.TTL != nil {
+		ttl = *rrset.TTL
+	}
+	for _, record := range rrset.ResourceRecords {
+		if err := addRData(set, r53Unescape(rrset.Name), rrset.Type, ttl, record.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// r53Unescape undoes the escaping applied by Route53 to the names of the records.
+// Route53 returns the asterisk of wildcard names as the octal escape `\052`.
+func r53Unescape(name string) string {
+	return strings.Replace(name, `\052`, "*", -1)
+}
+
+func init() {
+	RegisterProviderConstructor("route53", func(ctx context.Context, controllerCtx *types.ControllerContext, resource *dnsv1alpha1.DNSProvider) (types.Provider, error) {
+
+		// Read the credentials from the secrets
+		accessKeyID, err := readSecretKey(ctx, controllerCtx, resource, &resource.Spec.Route53.AccessKeyIDSecretRef)
+		if err != nil {
+			return nil, err
+		}
+		secretAccessKey, err := readSecretKey(ctx, controllerCtx, resource, &resource.Spec.Route53.SecretAccessKeySecretRef)
+		if err != nil {
+			return nil, err
+		}
+
+		endpoint := route53DefaultEndpoint
+		if resource.Spec.Route53.Endpoint != nil && *resource.Spec.Route53.Endpoint != "" {
+			endpoint = *resource.Spec.Route53.Endpoint
+		}
+		region := route53DefaultRegion
+		if resource.Spec.Route53.Region != nil && *resource.Spec.Route53.Region != "" {
+			region = *resource.Spec.Route53.Region
+		}
+
+		client := &http.Client{
+			Timeout:   resource.GetTimeout(),
+			Transport: metrics.RateLimitTransport("route53", nil),
+		}
+		return NewRoute53(controllerCtx.Log, resource.Spec.Zones, client, endpoint, region, string(accessKeyID), string(secretAccessKey), resource.Spec.Route53.PrivateZone), nil
+	})
+}
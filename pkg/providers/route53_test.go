@@ -0,0 +1,223 @@
+package providers
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+// fakeRoute53 is a minimal in-memory implementation of the Route53 API, serving a single hosted zone.
+type fakeRoute53 struct {
+	sync.Mutex
+	zoneLookups int
+	rrsets      map[string]r53ResourceRecordSet
+}
+
+func (f *fakeRoute53) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/2013-04-01/hostedzonesbyname":
+		f.zoneLookups++
+		res := r53ListHostedZonesByNameResponse{HostedZones: []r53HostedZone{
+			{ID: "/hostedzone/Z1", Name: "example.com."},
+			{ID: "/hostedzone/Z2", Name: "example.net."},
+			{ID: "/hostedzone/Z3", Name: "example.org."},
+			{ID: "/hostedzone/Z4", Name: "example.org."},
+		}}
+		res.HostedZones[3].Config.PrivateZone = true
+		writeXML(w, http.StatusOK, &res)
+
+	case r.Method == http.MethodGet && r.URL.Path == "/2013-04-01/hostedzone/Z1/rrset":
+		var keys []string
+		for key := range f.rrsets {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		// Start from the given name and type, if any
+		var res r53ListResourceRecordSetsResponse
+		start := r.URL.Query().Get("name") + " " + r.URL.Query().Get("type")
+		for _, key := range keys {
+			if key >= start {
+				rrset := f.rrsets[key]
+				rrset.Name = strings.Replace(rrset.Name, "*", `\052`, -1)
+				res.ResourceRecordSets = append(res.ResourceRecordSets, rrset)
+			}
+		}
+		if maxItems, err := strconv.Atoi(r.URL.Query().Get("maxitems")); err == nil && len(res.ResourceRecordSets) > maxItems {
+			next := res.ResourceRecordSets[maxItems]
+			res.ResourceRecordSets = res.ResourceRecordSets[:maxItems]
+			res.IsTruncated = true
+			res.NextRecordName = next.Name
+			res.NextRecordType = next.Type
+			res.NextRecordIdentifier = next.SetIdentifier
+		}
+		writeXML(w, http.StatusOK, &res)
+
+	case r.Method == http.MethodPost && r.URL.Path == "/2013-04-01/hostedzone/Z1/rrset":
+		raw, _ := ioutil.ReadAll(r.Body)
+		var req r53ChangeResourceRecordSetsRequest
+		if err := xml.Unmarshal(raw, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, change := range req.Changes {
+			key := change.ResourceRecordSet.Name + " " + change.ResourceRecordSet.Type + " " + change.ResourceRecordSet.SetIdentifier
+			switch change.Action {
+			case "UPSERT":
+				f.rrsets[key] = change.ResourceRecordSet
+			case "DELETE":
+				if existing, ok := f.rrsets[key]; !ok || !reflect.DeepEqual(existing, change.ResourceRecordSet) {
+					writeXML(w, http.StatusBadRequest, &struct {
+						XMLName  xml.Name `xml:"InvalidChangeBatch"`
+						Messages []string `xml:"Messages>Message"`
+					}{Messages: []string{"RRset not found or not matching"}})
+					return
+				}
+				delete(f.rrsets, key)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		writeXML(w, http.StatusBadRequest, &struct {
+			XMLName xml.Name `xml:"ErrorResponse"`
+			Code    string   `xml:"Error>Code"`
+			Message string   `xml:"Error>Message"`
+		}{Code: "InvalidInput", Message: "Unexpected request " + r.Method + " " + r.URL.Path})
+	}
+}
+
+func writeXML(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(body)
+}
+
+func newFakeRoute53() (*Route53, *fakeRoute53, *httptest.Server) {
+	fake := &fakeRoute53{rrsets: make(map[string]r53ResourceRecordSet)}
+	server := httptest.NewServer(fake)
+	zones := []dnsname.Name{mustName("example.com")}
+	return NewRoute53(ctrl.Log, zones, server.Client(), server.URL, route53DefaultRegion, "AKID", "secret", nil), fake, server
+}
+
+func TestRoute53(t *testing.T) {
+	r53, fake, server := newFakeRoute53()
+	defer server.Close()
+
+	testProviderRoundTrip(t, r53, append(testRecords(), testWildcardRecord()))
+
+	// The ID of the hosted zone is looked up only once
+	require.Equal(t, 1, fake.zoneLookups)
+}
+
+func TestRoute53Fixtures(t *testing.T) {
+	require := require.New(t)
+	server := serveFixtures(t, map[string]string{
+		"GET /2013-04-01/hostedzonesbyname":               "route53/list-hosted-zones-by-name.xml",
+		"GET /2013-04-01/hostedzone/Z1D633PJN98FT9/rrset": "route53/list-resource-record-sets.xml",
+	})
+	defer server.Close()
+
+	// Aliases, RRsets with routing policies, NS and SOA are ignored
+	r53 := NewRoute53(ctrl.Log, nil, server.Client(), server.URL, route53DefaultRegion, "AKID", "secret", nil)
+	list, err := r53.ListRecords(context.Background(), mustName("example.com"))
+	require.Nil(err)
+	requireSameRecords(t, append(testRecords(), testWildcardRecord()), list)
+}
+
+func TestRoute53Errors(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	r53, _, server := newFakeRoute53()
+	defer server.Close()
+
+	_, err := r53.ListRecords(ctx, mustName("example.info"))
+	require.EqualError(err, "Cannot find hosted zone example.info.")
+
+	r53.zonesIDCache["example.net."] = "Z2"
+	_, err = r53.ListRecords(ctx, mustName("example.net"))
+	require.EqualError(err, "Route53 request failed: InvalidInput: Unexpected request GET /2013-04-01/hostedzone/Z2/rrset")
+}
+
+func TestRoute53RoutingPolicies(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	zone := mustName("example.com")
+	r53, fake, server := newFakeRoute53()
+	defer server.Close()
+
+	// Weighted RRsets share the name and the type, and are told apart by their identifier
+	ttl := uint32(60)
+	for i := 0; i < 150; i++ {
+		id := fmt.Sprintf("weight-%03d", i)
+		fake.rrsets["weighted.example.com. A "+id] = r53ResourceRecordSet{
+			Name: "weighted.example.com.", Type: "A", SetIdentifier: id, TTL: &ttl,
+			ResourceRecords: []r53ResourceRecord{{Value: "1.1.1.1"}},
+		}
+	}
+	fake.rrsets["alias.example.com. A "] = r53ResourceRecordSet{
+		Name: "alias.example.com.", Type: "A", AliasTarget: &r53AliasTarget{HostedZoneID: "Z2", DNSName: "lb.example.net."},
+	}
+
+	for _, name := range []string{"weighted.example.com", "alias.example.com"} {
+		record := v1alpha1.DNSRecord{Spec: v1alpha1.DNSRecordSpec{Name: mustName(name), RRSet: v1alpha1.DNSRecordSetData{
+			A: []v1alpha1.Ipv4String{"2.2.2.2"},
+		}}}
+		_, err := r53.GetRecord(ctx, zone, record)
+		require.True(errors.Is(err, types.ErrNotOwned), "Name: %s, Error: %v", name, err)
+		require.True(errors.Is(r53.DeleteRecord(ctx, zone, record), types.ErrNotOwned), "Name: %s", name)
+	}
+
+	// They are not listed, and they are left untouched
+	list, err := r53.ListRecords(ctx, zone)
+	require.Nil(err)
+	require.Empty(list)
+	require.Len(fake.rrsets, 151)
+}
+
+func TestRoute53PrivateZones(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	r53, _, server := newFakeRoute53()
+	defer server.Close()
+
+	// A public and a private hosted zone share the same name
+	_, err := r53.zoneIDFromName(ctx, mustName("example.org"))
+	require.EqualError(err, "Found multiple hosted zones named example.org., use privateZone to choose between a public and a private one")
+
+	for _, private := range []bool{false, true} {
+		r53 := NewRoute53(ctrl.Log, nil, server.Client(), server.URL, route53DefaultRegion, "AKID", "secret", &private)
+		id, err := r53.zoneIDFromName(ctx, mustName("example.org"))
+		require.Nil(err)
+		if private {
+			require.Equal("Z4", id)
+		} else {
+			require.Equal("Z3", id)
+		}
+	}
+}
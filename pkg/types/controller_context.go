@@ -16,6 +16,9 @@ type ControllerContext struct {
 	Log           logr.Logger
 	EventRecorder record.EventRecorder
 
+	// ClusterID identifies this cluster in the ownership records published alongside the DNS records.
+	ClusterID string
+
 	providers     map[string]Provider
 	providersLock sync.RWMutex
 }
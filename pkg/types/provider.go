@@ -1,13 +1,32 @@
 package types
 
 import (
+	"context"
+	"errors"
+
 	"github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
 	"github.com/95ulisse/dns-operator/pkg/dnsname"
 )
 
+// ErrNotSupported is returned by providers which do not support a specific operation.
+var ErrNotSupported = errors.New("Operation not supported by the provider")
+
+// ErrNotOwned is returned by providers when asked to modify or delete an RRset owned by someone else.
+var ErrNotOwned = errors.New("DNS record is not owned by this resource")
+
 // Provider is a generic DNS provider which knows how to talk to a backend to reconcile DNS records.
+// Operations which reach the backend must honor the cancellation and the deadline of the given context.
 type Provider interface {
 	Zones() []dnsname.Name
-	UpdateRecord(zone dnsname.Name, rrset v1alpha1.DNSRecord) error
-	DeleteRecord(zone dnsname.Name, rrset v1alpha1.DNSRecord) error
+	UpdateRecord(ctx context.Context, zone dnsname.Name, rrset v1alpha1.DNSRecord) error
+	DeleteRecord(ctx context.Context, zone dnsname.Name, rrset v1alpha1.DNSRecord) error
+
+	// ListRecords returns all the RRsets published in the given zone.
+	// Each RRset is returned as a DNSRecord with only the name, the TTL and the rrset fields set.
+	// Providers which cannot read back records return ErrNotSupported.
+	ListRecords(ctx context.Context, zone dnsname.Name) ([]v1alpha1.DNSRecord, error)
+
+	// GetRecord returns the RRset published with the same name and type of the given one, or nil if it does not exist.
+	// Providers which cannot read back records return ErrNotSupported.
+	GetRecord(ctx context.Context, zone dnsname.Name, rrset v1alpha1.DNSRecord) (*v1alpha1.DNSRecord, error)
 }
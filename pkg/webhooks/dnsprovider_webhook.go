@@ -0,0 +1,57 @@
+package webhooks
+
+import (
+	"context"
+	"net/http"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+)
+
+// +kubebuilder:webhook:path=/validate-dns-k8s-marcocameriero-net-v1alpha1-dnsprovider,mutating=false,failurePolicy=fail,groups=dns.k8s.marcocameriero.net,resources=dnsproviders;clusterdnsproviders,verbs=create;update,versions=v1alpha1,name=vdnsprovider.dns.k8s.marcocameriero.net
+
+const validateDNSProviderPath = "/validate-dns-k8s-marcocameriero-net-v1alpha1-dnsprovider"
+
+// DNSProviderValidator is a validating webhook for DNSProvider and ClusterDNSProvider resources.
+type DNSProviderValidator struct {
+	decoder *admission.Decoder
+}
+
+// Handle validates the DNSProvider or ClusterDNSProvider contained in the admission request.
+func (v *DNSProviderValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var spec *dnsv1alpha1.DNSProviderSpec
+	var name string
+	if req.Kind.Kind == dnsv1alpha1.ClusterDNSProviderKind {
+		var provider dnsv1alpha1.ClusterDNSProvider
+		if err := v.decoder.Decode(req, &provider); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		spec, name = &provider.Spec, provider.Name
+	} else {
+		var provider dnsv1alpha1.DNSProvider
+		if err := v.decoder.Decode(req, &provider); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		spec, name = &provider.Spec, provider.Name
+	}
+
+	if errs := validateDNSProviderSpec(spec); len(errs) > 0 {
+		return invalid(req.Kind.Kind, name, errs)
+	}
+	return admission.Allowed("")
+}
+
+// InjectDecoder injects the decoder used to decode the admission requests.
+func (v *DNSProviderValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// SetupWithManager registers the DNSProvider validating webhook with the webhook server of the given Manager.
+func (v *DNSProviderValidator) SetupWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(validateDNSProviderPath, &webhook.Admission{Handler: v})
+	return nil
+}
@@ -0,0 +1,56 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+)
+
+// +kubebuilder:webhook:path=/mutate-dns-k8s-marcocameriero-net-v1alpha1-dnsrecord,mutating=true,failurePolicy=fail,groups=dns.k8s.marcocameriero.net,resources=dnsrecords,verbs=create;update,versions=v1alpha1,name=mdnsrecord.dns.k8s.marcocameriero.net
+
+const mutateDNSRecordPath = "/mutate-dns-k8s-marcocameriero-net-v1alpha1-dnsrecord"
+
+// DNSRecordDefaulter is a mutating webhook filling in the defaults of DNSRecord resources.
+type DNSRecordDefaulter struct {
+	decoder *admission.Decoder
+}
+
+// Handle fills in the defaults of the DNSRecord contained in the admission request.
+func (d *DNSRecordDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var record dnsv1alpha1.DNSRecord
+	if err := d.decoder.Decode(req, &record); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// The namespace may be missing from the object when it is being created
+	namespace := record.Namespace
+	if namespace == "" {
+		record.Namespace = req.Namespace
+	}
+	record.Default()
+	record.Namespace = namespace
+
+	marshaled, err := json.Marshal(&record)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// InjectDecoder injects the decoder used to decode the admission requests.
+func (d *DNSRecordDefaulter) InjectDecoder(decoder *admission.Decoder) error {
+	d.decoder = decoder
+	return nil
+}
+
+// SetupWithManager registers the DNSRecord mutating webhook with the webhook server of the given Manager.
+func (d *DNSRecordDefaulter) SetupWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(mutateDNSRecordPath, &webhook.Admission{Handler: d})
+	return nil
+}
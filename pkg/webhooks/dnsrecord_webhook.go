@@ -0,0 +1,123 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/controllers"
+	"github.com/95ulisse/dns-operator/pkg/types"
+)
+
+// +kubebuilder:webhook:path=/validate-dns-k8s-marcocameriero-net-v1alpha1-dnsrecord,mutating=false,failurePolicy=fail,groups=dns.k8s.marcocameriero.net,resources=dnsrecords,verbs=create;update,versions=v1alpha1,name=vdnsrecord.dns.k8s.marcocameriero.net
+
+const validateDNSRecordPath = "/validate-dns-k8s-marcocameriero-net-v1alpha1-dnsrecord"
+
+// DNSRecordValidator is a validating webhook for DNSRecord resources.
+type DNSRecordValidator struct {
+	Client  client.Client
+	Context *types.ControllerContext
+
+	// Kinds which can be referenced by `valueFrom.fieldRef`, as in the DNSRecord controller.
+	// Defaults to controllers.DefaultFieldRefKinds.
+	FieldRefKinds []schema.GroupKind
+
+	decoder        *admission.Decoder
+	fieldRefPolicy *controllers.FieldRefPolicy
+}
+
+// Handle validates the DNSRecord contained in the admission request.
+func (v *DNSRecordValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var record dnsv1alpha1.DNSRecord
+	if err := v.decoder.Decode(req, &record); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if record.Namespace == "" {
+		record.Namespace = req.Namespace
+	}
+
+	// Records being deleted are releasing their RRset, there is no point in checking them
+	if !record.DeletionTimestamp.IsZero() {
+		return admission.Allowed("")
+	}
+
+	// Reject invalid specs, checking the name against the zones of the provider if it exists
+	zones, err := providerZones(ctx, v.Client, &record)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if errs := validateDNSRecord(&record, zones, v.policy()); len(errs) > 0 {
+		return invalid("DNSRecord", record.Name, errs)
+	}
+
+	// Reject records claiming an RRset already claimed by an older record.
+	// This is the same rule used by the DNSRecord controller, which reports the conflict on the newer record.
+	// Updates are checked only when they change the claimed RRset, so that records which are already conflicting
+	// (e.g., created while the webhook was not running) can still be updated by the controller and by their owners.
+	if req.Operation == admissionv1beta1.Update {
+		var old dnsv1alpha1.DNSRecord
+		if err := v.decoder.DecodeRaw(req.OldObject, &old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if old.Namespace == "" {
+			old.Namespace = req.Namespace
+		}
+		oldKey, oldOk := controllers.RRSetKey(v.Context, &old)
+		key, ok := controllers.RRSetKey(v.Context, &record)
+		if oldOk == ok && oldKey == key {
+			return admission.Allowed("")
+		}
+	}
+	winner, err := controllers.FindConflict(ctx, v.Client, v.Context, &record)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if winner != nil {
+		name := &record.Spec.Name
+		if fqdn, ok := controllers.RRSetName(v.Context, &record); ok {
+			name = fqdn
+		}
+		return admission.Denied(fmt.Sprintf(
+			"RRset %s %s is already claimed by DNSRecord %s/%s",
+			record.RType(), name.ToFQDN().String(), winner.Namespace, winner.Name,
+		))
+	}
+
+	return admission.Allowed("")
+}
+
+// policy returns the policy restricting the resources which can be referenced by `valueFrom.fieldRef`.
+// Without a RESTMapper, the scope of the kinds is not checked.
+func (v *DNSRecordValidator) policy() *controllers.FieldRefPolicy {
+	if v.fieldRefPolicy != nil {
+		return v.fieldRefPolicy
+	}
+	kinds := v.FieldRefKinds
+	if kinds == nil {
+		kinds = controllers.DefaultFieldRefKinds
+	}
+	return &controllers.FieldRefPolicy{Kinds: kinds}
+}
+
+// InjectDecoder injects the decoder used to decode the admission requests.
+func (v *DNSRecordValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// SetupWithManager registers the DNSRecord validating webhook with the webhook server of the given Manager.
+// The DNSRecord controller must be registered as well, since it provides the index used to look for conflicts.
+func (v *DNSRecordValidator) SetupWithManager(mgr ctrl.Manager) error {
+	v.fieldRefPolicy = v.policy()
+	v.fieldRefPolicy.Mapper = mgr.GetRESTMapper()
+	mgr.GetWebhookServer().Register(validateDNSRecordPath, &webhook.Admission{Handler: v})
+	return nil
+}
@@ -0,0 +1,290 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/controllers"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+)
+
+// validateRRSet checks that exactly one record type is set in an RRset, and that its contents are valid.
+// References to other resources are checked against the given policy.
+func validateRRSet(rrset *dnsv1alpha1.DNSRecordSetData, path *field.Path, fieldRefs *controllers.FieldRefPolicy) field.ErrorList {
+	var errs field.ErrorList
+
+	var set []string
+	for _, f := range []struct {
+		name    string
+		present bool
+	}{
+		{"a", rrset.A != nil},
+		{"aaaa", rrset.AAAA != nil},
+		{"mx", rrset.MX != nil},
+		{"cname", rrset.CNAME != nil},
+		{"txt", rrset.TXT != nil},
+		{"srv", rrset.SRV != nil},
+		{"caa", rrset.CAA != nil},
+		{"valueFrom", rrset.ValueFrom != nil},
+	} {
+		if f.present {
+			set = append(set, f.name)
+		}
+	}
+	switch {
+	case len(set) == 0:
+		errs = append(errs, field.Required(path, "One of a, aaaa, mx, cname, txt, srv, caa or valueFrom must be set"))
+	case len(set) > 1:
+		for _, name := range set[1:] {
+			errs = append(errs, field.Forbidden(path.Child(name), fmt.Sprintf("Only one record type can be set, found %s", strings.Join(set, ", "))))
+		}
+	}
+
+	if len(rrset.CNAME) > 1 {
+		errs = append(errs, field.TooMany(path.Child("cname"), len(rrset.CNAME), 1))
+	}
+	for i, caa := range rrset.CAA {
+		if err := caa.Validate(); err != nil {
+			errs = append(errs, field.Invalid(path.Child("caa").Index(i).Child("value"), caa.Value, err.Error()))
+		}
+	}
+
+	if valueFrom := rrset.ValueFrom; valueFrom != nil {
+		var refs []string
+		for _, f := range []struct {
+			name    string
+			present bool
+		}{
+			{"serviceRef", valueFrom.ServiceRef != nil},
+			{"nodeRef", valueFrom.NodeRef != nil},
+			{"nodeSelector", valueFrom.NodeSelector != nil},
+			{"configMapKeyRef", valueFrom.ConfigMapKeyRef != nil},
+			{"fieldRef", valueFrom.FieldRef != nil},
+		} {
+			if f.present {
+				refs = append(refs, f.name)
+			}
+		}
+		switch {
+		case len(refs) == 0:
+			errs = append(errs, field.Required(path.Child("valueFrom"), "One of serviceRef, nodeRef, nodeSelector, configMapKeyRef or fieldRef must be set"))
+		case len(refs) > 1:
+			for _, name := range refs[1:] {
+				errs = append(errs, field.Forbidden(path.Child("valueFrom", name), fmt.Sprintf("Only one source can be set, found %s", strings.Join(refs, ", "))))
+			}
+		}
+		if valueFrom.NodeSelector != nil && valueFrom.NodeSelector.Selector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(valueFrom.NodeSelector.Selector); err != nil {
+				errs = append(errs, field.Invalid(path.Child("valueFrom", "nodeSelector", "selector"), valueFrom.NodeSelector.Selector, err.Error()))
+			}
+		}
+		if valueFrom.FieldRef != nil {
+			if _, err := fieldRefs.Resolve(valueFrom.FieldRef); err != nil {
+				errs = append(errs, field.Forbidden(path.Child("valueFrom", "fieldRef", "kind"), err.Error()))
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateDNSRecord checks the spec of a DNSRecord.
+// When the zones of its provider are known, it also checks that the record belongs to one of them,
+// and that it is not a CNAME at the zone apex.
+func validateDNSRecord(record *dnsv1alpha1.DNSRecord, zones []dnsname.Name, fieldRefs *controllers.FieldRefPolicy) field.ErrorList {
+	specPath := field.NewPath("spec")
+	errs := validateRRSet(&record.Spec.RRSet, specPath.Child("rrset"), fieldRefs)
+
+	if zones == nil {
+		return errs
+	}
+
+	if zone := record.Spec.Zone; zone != nil {
+		found := false
+		for _, z := range zones {
+			found = found || z.Equal(zone)
+		}
+		if !found {
+			return append(errs, field.NotSupported(specPath.Child("zone"), zone.String(), zoneNames(zones)))
+		}
+	}
+
+	var zone, fqdn dnsname.Name
+	if err := controllers.ResolveRecordName(zones, record, &zone, &fqdn); err != nil {
+		return append(errs, field.Invalid(specPath.Child("name"), record.Spec.Name.String(), err.Error()))
+	}
+	if len(errs) == 0 && record.RType() == "CNAME" && fqdn.Equal(&zone) {
+		errs = append(errs, field.Invalid(specPath.Child("name"), record.Spec.Name.String(), "A CNAME record cannot be at the zone apex"))
+	}
+
+	return errs
+}
+
+// validateEndpoint checks that the optional endpoint of a provider is an absolute URL.
+func validateEndpoint(path *field.Path, endpoint *string) field.ErrorList {
+	if endpoint == nil {
+		return nil
+	}
+	if u, err := url.Parse(*endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+		return field.ErrorList{field.Invalid(path, *endpoint, "Must be an absolute URL")}
+	}
+	return nil
+}
+
+// validateDNSProviderSpec checks the spec shared by DNSProviders and ClusterDNSProviders.
+func validateDNSProviderSpec(spec *dnsv1alpha1.DNSProviderSpec) field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	types := spec.ProviderTypes()
+	switch {
+	case len(types) == 0:
+		errs = append(errs, field.Required(specPath, "A provider must be configured"))
+	case len(types) > 1:
+		for _, name := range types[1:] {
+			errs = append(errs, field.Forbidden(specPath.Child(name), fmt.Sprintf("Only one provider can be configured, found %s", strings.Join(types, ", "))))
+		}
+	}
+
+	if rfc2136 := spec.RFC2136; rfc2136 != nil {
+		path := specPath.Child("rfc2136")
+		tsig := []bool{rfc2136.TSIGSecretRef != nil, rfc2136.TSIGKeyName != nil, rfc2136.TSIGAlgorithm != nil}
+		if (tsig[0] || tsig[1] || tsig[2]) && !(tsig[0] && tsig[1] && tsig[2]) {
+			for i, name := range []string{"tsigSecretRef", "tsigKeyName", "tsigAlgorithm"} {
+				if !tsig[i] {
+					errs = append(errs, field.Required(path.Child(name), "All fields tsigSecretRef, tsigKeyName and tsigAlgorithm are required when specifying a TSIG key"))
+				}
+			}
+		}
+	}
+
+	if cf := spec.Cloudflare; cf != nil {
+		path := specPath.Child("cloudflare")
+		switch {
+		case cf.APITokenSecretRef == nil && cf.APIKeySecretRef == nil:
+			errs = append(errs, field.Required(path, "One between apiTokenSecretRef and apiKeySecretRef must be set"))
+		case cf.APITokenSecretRef != nil && cf.APIKeySecretRef != nil:
+			errs = append(errs, field.Forbidden(path.Child("apiKeySecretRef"), "Only one between apiTokenSecretRef and apiKeySecretRef can be set"))
+		case cf.APIKeySecretRef != nil && cf.Email == nil:
+			errs = append(errs, field.Required(path.Child("email"), "The email is required when using an API Key"))
+		}
+	}
+
+	if r53 := spec.Route53; r53 != nil {
+		errs = append(errs, validateEndpoint(specPath.Child("route53", "endpoint"), r53.Endpoint)...)
+	}
+
+	if gcd := spec.GoogleCloudDNS; gcd != nil {
+		path := specPath.Child("googleCloudDNS")
+		errs = append(errs, validateEndpoint(path.Child("endpoint"), gcd.Endpoint)...)
+		for zone := range gcd.ManagedZones {
+			if _, err := dnsname.NewName(zone); err != nil {
+				errs = append(errs, field.Invalid(path.Child("managedZones").Key(zone), zone, err.Error()))
+			}
+		}
+	}
+
+	if az := spec.AzureDNS; az != nil {
+		path := specPath.Child("azureDNS")
+		errs = append(errs, validateEndpoint(path.Child("endpoint"), az.Endpoint)...)
+		errs = append(errs, validateEndpoint(path.Child("authorityHost"), az.AuthorityHost)...)
+		groups := make(map[string]bool)
+		for zone := range az.ZoneResourceGroups {
+			name, err := dnsname.NewName(zone)
+			if err != nil {
+				errs = append(errs, field.Invalid(path.Child("zoneResourceGroups").Key(zone), zone, err.Error()))
+				continue
+			}
+			groups[strings.ToLower(name.ToFQDN().String())] = true
+		}
+		if az.ResourceGroup == nil {
+			for _, zone := range spec.Zones {
+				if !groups[strings.ToLower(zone.ToFQDN().String())] {
+					errs = append(errs, field.Required(path.Child("zoneResourceGroups").Key(zone.String()), "Zones must have a resource group when resourceGroup is not set"))
+				}
+			}
+		}
+	}
+
+	if pdns := spec.PowerDNS; pdns != nil {
+		errs = append(errs, validateEndpoint(specPath.Child("powerdns", "url"), &pdns.URL)...)
+	}
+
+	if policy := spec.AccessPolicy; policy != nil {
+		path := specPath.Child("accessPolicy")
+		if policy.NamespaceSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(policy.NamespaceSelector); err != nil {
+				errs = append(errs, field.Invalid(path.Child("namespaceSelector"), policy.NamespaceSelector, err.Error()))
+			}
+		}
+		for i, rule := range policy.Rules {
+			if rule.NamespaceSelector != nil {
+				if _, err := metav1.LabelSelectorAsSelector(rule.NamespaceSelector); err != nil {
+					errs = append(errs, field.Invalid(path.Child("rules").Index(i).Child("namespaceSelector"), rule.NamespaceSelector, err.Error()))
+				}
+			}
+			for j, name := range rule.Names {
+				if _, err := dnsname.NewName(strings.TrimPrefix(name, "*.")); err != nil {
+					errs = append(errs, field.Invalid(path.Child("rules").Index(i).Child("names").Index(j), name, err.Error()))
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+// providerZones returns the zones of the provider referenced by a record.
+// Returns nil if the provider does not exist (yet).
+func providerZones(ctx context.Context, c client.Client, record *dnsv1alpha1.DNSRecord) ([]dnsname.Name, error) {
+	ref := record.Spec.ProviderRef
+	var spec *dnsv1alpha1.DNSProviderSpec
+	var err error
+	if ref.IsClusterScoped() {
+		var provider dnsv1alpha1.ClusterDNSProvider
+		err = c.Get(ctx, k8stypes.NamespacedName{Name: ref.Name}, &provider)
+		spec = &provider.Spec
+	} else {
+		namespace := record.Namespace
+		if ref.Namespace != nil {
+			namespace = *ref.Namespace
+		}
+		var provider dnsv1alpha1.DNSProvider
+		err = c.Get(ctx, k8stypes.NamespacedName{Namespace: namespace, Name: ref.Name}, &provider)
+		spec = &provider.Spec
+	}
+	if err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	return spec.Zones, nil
+}
+
+func zoneNames(zones []dnsname.Name) []string {
+	res := make([]string, 0, len(zones))
+	for _, z := range zones {
+		res = append(res, z.String())
+	}
+	return res
+}
+
+// invalid builds an admission response rejecting a resource with the given field errors.
+func invalid(kind, name string, errs field.ErrorList) admission.Response {
+	status := apierrors.NewInvalid(dnsv1alpha1.GroupVersion.WithKind(kind).GroupKind(), name, errs).ErrStatus
+	return admission.Response{
+		AdmissionResponse: admissionv1beta1.AdmissionResponse{
+			Allowed: false,
+			Result:  &status,
+		},
+	}
+}
@@ -0,0 +1,173 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/controllers"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+)
+
+func mustName(name string) dnsname.Name {
+	n, err := dnsname.NewName(name)
+	if err != nil {
+		panic(err)
+	}
+	return *n
+}
+
+func mustRecordName(name string) dnsname.Name {
+	n, err := dnsname.NewRecordName(name)
+	if err != nil {
+		panic(err)
+	}
+	return *n
+}
+
+func TestValidateDNSRecord(t *testing.T) {
+	require := require.New(t)
+
+	zones := []dnsname.Name{mustName("example.com"), mustName("example.org")}
+	org := mustName("example.org")
+	other := mustName("example.net")
+
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{{Version: "v1"}})
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Node"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, meta.RESTScopeNamespace)
+	fieldRefs := &controllers.FieldRefPolicy{
+		Kinds:  []schema.GroupKind{{Kind: "Pod"}, {Kind: "Node"}, {Kind: "Secret"}},
+		Mapper: mapper,
+	}
+	fieldRef := func(kind string) dnsv1alpha1.DNSRecordSetData {
+		return dnsv1alpha1.DNSRecordSetData{ValueFrom: &dnsv1alpha1.RecordValueSource{
+			Type:     "A",
+			FieldRef: &dnsv1alpha1.ObjectFieldSelector{APIVersion: "v1", Kind: kind, Name: "name", JSONPath: "{.status.podIP}"},
+		}}
+	}
+
+	table := []struct {
+		description string
+		name        string
+		zone        *dnsname.Name
+		rrset       dnsv1alpha1.DNSRecordSetData
+		zones       []dnsname.Name
+		fields      []string
+	}{
+		{"valid record", "www.example.com", nil, dnsv1alpha1.DNSRecordSetData{A: []dnsv1alpha1.Ipv4String{"1.1.1.1"}}, zones, nil},
+		{"relative name", "www", &org, dnsv1alpha1.DNSRecordSetData{CNAME: []dnsname.Name{mustName("example.com.")}}, zones, nil},
+		{"unknown provider", "www.example.net", nil, dnsv1alpha1.DNSRecordSetData{A: []dnsv1alpha1.Ipv4String{"1.1.1.1"}}, nil, nil},
+		{"no rrset", "www.example.com", nil, dnsv1alpha1.DNSRecordSetData{}, zones, []string{"spec.rrset"}},
+		{
+			"multiple rrsets", "www.example.com", nil,
+			dnsv1alpha1.DNSRecordSetData{A: []dnsv1alpha1.Ipv4String{"1.1.1.1"}, TXT: []string{"text"}, ValueFrom: &dnsv1alpha1.RecordValueSource{}},
+			zones, []string{"spec.rrset.txt", "spec.rrset.valueFrom", "spec.rrset.valueFrom"},
+		},
+		{"multiple CNAMEs", "www.example.com", nil, dnsv1alpha1.DNSRecordSetData{CNAME: []dnsname.Name{mustName("a.com"), mustName("b.com")}}, zones, []string{"spec.rrset.cname"}},
+		{"empty valueFrom", "www.example.com", nil, dnsv1alpha1.DNSRecordSetData{ValueFrom: &dnsv1alpha1.RecordValueSource{Type: "A"}}, zones, []string{"spec.rrset.valueFrom"}},
+		{"invalid CAA", "example.com", nil, dnsv1alpha1.DNSRecordSetData{CAA: []dnsv1alpha1.CAARData{{Tag: dnsv1alpha1.CAAIodefTag, Value: "example.com"}}}, zones, []string{"spec.rrset.caa[0].value"}},
+		{"CNAME at the apex", "example.com.", nil, dnsv1alpha1.DNSRecordSetData{CNAME: []dnsname.Name{mustName("example.org.")}}, zones, []string{"spec.name"}},
+		{"relative CNAME at the apex", "@", &org, dnsv1alpha1.DNSRecordSetData{CNAME: []dnsname.Name{mustName("example.com.")}}, zones, []string{"spec.name"}},
+		{"name outside the zones", "www.example.net.", nil, dnsv1alpha1.DNSRecordSetData{A: []dnsv1alpha1.Ipv4String{"1.1.1.1"}}, zones, []string{"spec.name"}},
+		{"ambiguous relative name", "www", nil, dnsv1alpha1.DNSRecordSetData{A: []dnsv1alpha1.Ipv4String{"1.1.1.1"}}, zones, []string{"spec.name"}},
+		{"unknown zone", "www", &other, dnsv1alpha1.DNSRecordSetData{A: []dnsv1alpha1.Ipv4String{"1.1.1.1"}}, zones, []string{"spec.zone"}},
+		{"fieldRef", "www.example.com", nil, fieldRef("Pod"), zones, nil},
+		{"fieldRef to a Secret", "www.example.com", nil, fieldRef("Secret"), zones, []string{"spec.rrset.valueFrom.fieldRef.kind"}},
+		{"fieldRef to a cluster scoped kind", "www.example.com", nil, fieldRef("Node"), zones, []string{"spec.rrset.valueFrom.fieldRef.kind"}},
+		{"fieldRef to a kind not allowed", "www.example.com", nil, fieldRef("ConfigMap"), zones, []string{"spec.rrset.valueFrom.fieldRef.kind"}},
+	}
+
+	for _, entry := range table {
+		var record dnsv1alpha1.DNSRecord
+		record.Spec.Name = mustRecordName(entry.name)
+		record.Spec.Zone = entry.zone
+		record.Spec.RRSet = entry.rrset
+
+		var fields []string
+		for _, err := range validateDNSRecord(&record, entry.zones, fieldRefs) {
+			fields = append(fields, err.Field)
+		}
+		require.Equal(entry.fields, fields, entry.description)
+	}
+}
+
+func TestValidateDNSProviderSpec(t *testing.T) {
+	require := require.New(t)
+
+	enabled := true
+	email := "admin@example.com"
+	secret := &dnsv1alpha1.SecretReference{ObjectReference: dnsv1alpha1.ObjectReference{Name: "secret"}, Key: "key"}
+	keyName := "key"
+
+	table := []struct {
+		description string
+		spec        dnsv1alpha1.DNSProviderSpec
+		fields      []string
+	}{
+		{"valid provider", dnsv1alpha1.DNSProviderSpec{Dummy: &enabled}, nil},
+		{"no provider", dnsv1alpha1.DNSProviderSpec{}, []string{"spec"}},
+		{
+			"multiple providers",
+			dnsv1alpha1.DNSProviderSpec{
+				RFC2136:    &dnsv1alpha1.DNSProviderRFC2136{Nameserver: "127.0.0.1"},
+				Cloudflare: &dnsv1alpha1.DNSProviderCloudflare{APITokenSecretRef: secret},
+			},
+			[]string{"spec.cloudflare"},
+		},
+		{
+			"partial TSIG configuration",
+			dnsv1alpha1.DNSProviderSpec{RFC2136: &dnsv1alpha1.DNSProviderRFC2136{Nameserver: "127.0.0.1", TSIGKeyName: &keyName}},
+			[]string{"spec.rfc2136.tsigSecretRef", "spec.rfc2136.tsigAlgorithm"},
+		},
+		{"Cloudflare without credentials", dnsv1alpha1.DNSProviderSpec{Cloudflare: &dnsv1alpha1.DNSProviderCloudflare{}}, []string{"spec.cloudflare"}},
+		{"Cloudflare API key without email", dnsv1alpha1.DNSProviderSpec{Cloudflare: &dnsv1alpha1.DNSProviderCloudflare{APIKeySecretRef: secret}}, []string{"spec.cloudflare.email"}},
+		{"Cloudflare API key with email", dnsv1alpha1.DNSProviderSpec{Cloudflare: &dnsv1alpha1.DNSProviderCloudflare{APIKeySecretRef: secret, Email: &email}}, nil},
+		{
+			"Route53 with invalid endpoint",
+			dnsv1alpha1.DNSProviderSpec{Route53: &dnsv1alpha1.DNSProviderRoute53{Endpoint: &keyName}},
+			[]string{"spec.route53.endpoint"},
+		},
+		{
+			"Google Cloud DNS with invalid zone mapping",
+			dnsv1alpha1.DNSProviderSpec{GoogleCloudDNS: &dnsv1alpha1.DNSProviderGoogleCloudDNS{ManagedZones: map[string]string{"invalid..name": "zone"}}},
+			[]string{"spec.googleCloudDNS.managedZones[invalid..name]"},
+		},
+		{
+			"Azure DNS without resource group",
+			dnsv1alpha1.DNSProviderSpec{
+				Zones: []dnsname.Name{mustName("example.com"), mustName("example.net")},
+				AzureDNS: &dnsv1alpha1.DNSProviderAzureDNS{
+					ZoneResourceGroups: map[string]string{"example.com.": "my-group"},
+				},
+			},
+			[]string{"spec.azureDNS.zoneResourceGroups[example.net]"},
+		},
+		{
+			"PowerDNS with relative URL",
+			dnsv1alpha1.DNSProviderSpec{PowerDNS: &dnsv1alpha1.DNSProviderPowerDNS{URL: "powerdns:8081", APIKeySecretRef: *secret}},
+			[]string{"spec.powerdns.url"},
+		},
+		{
+			"invalid access policy",
+			dnsv1alpha1.DNSProviderSpec{
+				Dummy: &enabled,
+				AccessPolicy: &dnsv1alpha1.DNSProviderAccessPolicy{
+					Rules: []dnsv1alpha1.DNSProviderAccessRule{{Names: []string{"*.example.com", "invalid..name"}}},
+				},
+			},
+			[]string{"spec.accessPolicy.rules[0].names[1]"},
+		},
+	}
+
+	for _, entry := range table {
+		var fields []string
+		for _, err := range validateDNSProviderSpec(&entry.spec) {
+			fields = append(fields, err.Field)
+		}
+		require.Equal(entry.fields, fields, entry.description)
+	}
+}
@@ -0,0 +1,206 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	dnsv1alpha1 "github.com/95ulisse/dns-operator/pkg/api/v1alpha1"
+	"github.com/95ulisse/dns-operator/pkg/dnsname"
+)
+
+// admissionRequest builds an admission request creating the given object.
+func admissionRequest(kind string, obj runtime.Object) admission.Request {
+	raw, err := json.Marshal(obj)
+	Expect(err).ToNot(HaveOccurred())
+	meta := obj.(metav1.Object)
+	return admission.Request{
+		AdmissionRequest: admissionv1beta1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Group: dnsv1alpha1.GroupVersion.Group, Version: dnsv1alpha1.GroupVersion.Version, Kind: kind},
+			Name:      meta.GetName(),
+			Namespace: meta.GetNamespace(),
+			Operation: admissionv1beta1.Create,
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	}
+}
+
+// admissionUpdateRequest builds an admission request updating `old` to `obj`.
+func admissionUpdateRequest(kind string, old, obj runtime.Object) admission.Request {
+	req := admissionRequest(kind, obj)
+	raw, err := json.Marshal(old)
+	Expect(err).ToNot(HaveOccurred())
+	req.Operation = admissionv1beta1.Update
+	req.OldObject = runtime.RawExtension{Raw: raw}
+	return req
+}
+
+// deniedFields returns the fields rejected by an admission response.
+func deniedFields(res admission.Response) []string {
+	Expect(res.Allowed).To(BeFalse())
+	Expect(res.Result).ToNot(BeNil())
+	Expect(res.Result.Details).ToNot(BeNil())
+	var fields []string
+	for _, cause := range res.Result.Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	return fields
+}
+
+var _ = Describe("DNSRecord webhook", func() {
+	ctx := context.Background()
+	var validator *DNSRecordValidator
+
+	newRecord := func(name string, rrset dnsv1alpha1.DNSRecordSetData) *dnsv1alpha1.DNSRecord {
+		n, err := dnsname.NewRecordName(name)
+		Expect(err).ToNot(HaveOccurred())
+		return &dnsv1alpha1.DNSRecord{
+			ObjectMeta: metav1.ObjectMeta{Name: "record", Namespace: "default"},
+			Spec: dnsv1alpha1.DNSRecordSpec{
+				ProviderRef: dnsv1alpha1.ProviderReference{Name: "webhook-provider"},
+				Name:        *n,
+				RRSet:       rrset,
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		validator = &DNSRecordValidator{Client: k8sClient, Context: controllerCtx}
+		Expect(validator.InjectDecoder(decoder)).To(Succeed())
+
+		// Zones are checked against the provider referenced by the records
+		enabled := true
+		provider := &dnsv1alpha1.DNSProvider{
+			ObjectMeta: metav1.ObjectMeta{Name: "webhook-provider", Namespace: "default"},
+			Spec: dnsv1alpha1.DNSProviderSpec{
+				Zones: []dnsname.Name{mustName("example.com")},
+				Dummy: &enabled,
+			},
+		}
+		if err := k8sClient.Create(ctx, provider); !apierrors.IsAlreadyExists(err) {
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+
+	It("accepts valid records", func() {
+		record := newRecord("www", dnsv1alpha1.DNSRecordSetData{A: []dnsv1alpha1.Ipv4String{"1.1.1.1"}})
+		Eventually(func() bool {
+			return validator.Handle(ctx, admissionRequest("DNSRecord", record)).Allowed
+		}).Should(BeTrue())
+	})
+
+	It("rejects records with multiple rrset types", func() {
+		record := newRecord("www", dnsv1alpha1.DNSRecordSetData{
+			A:   []dnsv1alpha1.Ipv4String{"1.1.1.1"},
+			TXT: []string{"text"},
+		})
+		res := validator.Handle(ctx, admissionRequest("DNSRecord", record))
+		Expect(deniedFields(res)).To(Equal([]string{"spec.rrset.txt"}))
+	})
+
+	It("rejects records without rrset", func() {
+		record := newRecord("www", dnsv1alpha1.DNSRecordSetData{})
+		res := validator.Handle(ctx, admissionRequest("DNSRecord", record))
+		Expect(deniedFields(res)).To(Equal([]string{"spec.rrset"}))
+	})
+
+	It("rejects CNAME records at the zone apex", func() {
+		record := newRecord("@", dnsv1alpha1.DNSRecordSetData{CNAME: []dnsname.Name{mustName("example.org.")}})
+		Eventually(func() []string {
+			return deniedFields(validator.Handle(ctx, admissionRequest("DNSRecord", record)))
+		}).Should(Equal([]string{"spec.name"}))
+	})
+
+	It("rejects records outside the zones of the provider", func() {
+		record := newRecord("www.example.org.", dnsv1alpha1.DNSRecordSetData{A: []dnsv1alpha1.Ipv4String{"1.1.1.1"}})
+		Eventually(func() []string {
+			return deniedFields(validator.Handle(ctx, admissionRequest("DNSRecord", record)))
+		}).Should(Equal([]string{"spec.name"}))
+	})
+
+	It("rejects records claiming an RRset claimed by an older record, but not updates to them", func() {
+		older := newRecord("conflict.example.com", dnsv1alpha1.DNSRecordSetData{A: []dnsv1alpha1.Ipv4String{"1.1.1.1"}})
+		older.Name = "older"
+		Expect(k8sClient.Create(ctx, older)).To(Succeed())
+
+		record := newRecord("conflict", dnsv1alpha1.DNSRecordSetData{A: []dnsv1alpha1.Ipv4String{"2.2.2.2"}})
+		Eventually(func() string {
+			res := validator.Handle(ctx, admissionRequest("DNSRecord", record))
+			if res.Allowed {
+				return ""
+			}
+			return string(res.Result.Reason)
+		}).Should(ContainSubstring("conflict.example.com."))
+		Expect(string(validator.Handle(ctx, admissionRequest("DNSRecord", record)).Result.Reason)).To(ContainSubstring("default/older"))
+
+		// Updates keeping the same RRset are allowed, so that the record can still be reconciled
+		updated := record.DeepCopy()
+		updated.Finalizers = []string{"dns.k8s.marcocameriero.net/finalizer"}
+		updated.Spec.RRSet.A = []dnsv1alpha1.Ipv4String{"3.3.3.3"}
+		Expect(validator.Handle(ctx, admissionUpdateRequest("DNSRecord", record, updated)).Allowed).To(BeTrue())
+
+		// Updates moving to a claimed RRset are rejected
+		moved := newRecord("other", dnsv1alpha1.DNSRecordSetData{A: []dnsv1alpha1.Ipv4String{"2.2.2.2"}})
+		Expect(validator.Handle(ctx, admissionUpdateRequest("DNSRecord", moved, record)).Allowed).To(BeFalse())
+	})
+})
+
+var _ = Describe("DNSRecord defaulting webhook", func() {
+	ctx := context.Background()
+
+	It("fills in the defaults", func() {
+		defaulter := &DNSRecordDefaulter{}
+		Expect(defaulter.InjectDecoder(decoder)).To(Succeed())
+
+		record := &dnsv1alpha1.DNSRecord{
+			ObjectMeta: metav1.ObjectMeta{Name: "record", Namespace: "default"},
+			Spec: dnsv1alpha1.DNSRecordSpec{
+				ProviderRef: dnsv1alpha1.ProviderReference{Name: "provider"},
+				Name:        mustName("www"),
+				RRSet:       dnsv1alpha1.DNSRecordSetData{A: []dnsv1alpha1.Ipv4String{"1.1.1.1"}},
+			},
+		}
+		res := defaulter.Handle(ctx, admissionRequest("DNSRecord", record))
+		Expect(res.Allowed).To(BeTrue())
+
+		var paths []string
+		for _, patch := range res.Patches {
+			paths = append(paths, patch.Path)
+		}
+		Expect(paths).To(ConsistOf("/spec/providerRef/namespace", "/spec/ttlSeconds", "/spec/deletionPolicy"))
+	})
+})
+
+var _ = Describe("DNSProvider webhook", func() {
+	ctx := context.Background()
+	var validator *DNSProviderValidator
+
+	BeforeEach(func() {
+		validator = &DNSProviderValidator{}
+		Expect(validator.InjectDecoder(decoder)).To(Succeed())
+	})
+
+	It("rejects providers with multiple provider types", func() {
+		secret := &dnsv1alpha1.SecretReference{ObjectReference: dnsv1alpha1.ObjectReference{Name: "secret"}, Key: "key"}
+		spec := dnsv1alpha1.DNSProviderSpec{
+			Zones:      []dnsname.Name{mustName("example.com")},
+			RFC2136:    &dnsv1alpha1.DNSProviderRFC2136{Nameserver: "127.0.0.1"},
+			Cloudflare: &dnsv1alpha1.DNSProviderCloudflare{APITokenSecretRef: secret},
+		}
+
+		provider := &dnsv1alpha1.DNSProvider{ObjectMeta: metav1.ObjectMeta{Name: "provider", Namespace: "default"}, Spec: spec}
+		res := validator.Handle(ctx, admissionRequest(dnsv1alpha1.DNSProviderKind, provider))
+		Expect(deniedFields(res)).To(Equal([]string{"spec.cloudflare"}))
+
+		clusterProvider := &dnsv1alpha1.ClusterDNSProvider{ObjectMeta: metav1.ObjectMeta{Name: "provider"}, Spec: spec}
+		res = validator.Handle(ctx, admissionRequest(dnsv1alpha1.ClusterDNSProviderKind, clusterProvider))
+		Expect(deniedFields(res)).To(Equal([]string{"spec.cloudflare"}))
+	})
+})